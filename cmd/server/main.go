@@ -17,18 +17,23 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responses"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -64,6 +69,7 @@ func main() {
 	var antigravityLogin bool
 	var projectID string
 	var vertexImport string
+	var encryptAuth bool
 	var configPath string
 	var password string
 
@@ -79,6 +85,7 @@ func main() {
 	flag.StringVar(&projectID, "project_id", "", "Project ID (Gemini only, not required)")
 	flag.StringVar(&configPath, "config", DefaultConfigPath, "Configure File Path")
 	flag.StringVar(&vertexImport, "vertex-import", "", "Import Vertex service account key JSON file")
+	flag.BoolVar(&encryptAuth, "encrypt-auth", false, "Re-encrypt all stored auth files with the primary token encryption key")
 	flag.StringVar(&password, "password", "", "")
 
 	flag.CommandLine.Usage = func() {
@@ -211,6 +218,17 @@ func main() {
 		objectStoreLocalPath = value
 	}
 
+	// Encrypt stored tokens at rest when a key is configured. The first key encrypts new writes;
+	// the others only decrypt files written before a key rotation.
+	tokenKeys, _ := lookupEnv("TOKEN_ENCRYPTION_KEY", "token_encryption_key")
+	tokenKeyFile, _ := lookupEnv("TOKEN_ENCRYPTION_KEY_FILE", "token_encryption_key_file")
+	keyring, errKeyring := tokencrypt.LoadKeyring(tokenKeys, tokenKeyFile)
+	if errKeyring != nil {
+		log.Errorf("failed to load token encryption keys: %v", errKeyring)
+		return
+	}
+	tokencrypt.SetKeyring(keyring)
+
 	// Check for cloud deploy mode only on first execution
 	// Read env var name in uppercase: DEPLOY
 	deployEnv := os.Getenv("DEPLOY")
@@ -447,6 +465,9 @@ func main() {
 	if vertexImport != "" {
 		// Handle Vertex service account import
 		cmd.DoVertexImport(cfg, vertexImport)
+	} else if encryptAuth {
+		// Re-encrypt stored credentials, e.g. after enabling encryption or rotating keys
+		cmd.DoEncryptAuthFiles(cfg)
 	} else if login {
 		// Handle Google/Gemini login
 		cmd.DoLogin(cfg, projectID, options)
@@ -472,8 +493,164 @@ func main() {
 			cmd.WaitForCloudDeploy()
 			return
 		}
+		// Stores are only connected in server mode; the one-shot modes above never use them.
+		configureSignatureCache(cfg, configFilePath, pgStoreInst)
+		configureUsageStore(cfg, configFilePath, pgStoreInst)
+		configureResponseStore(cfg, configFilePath, pgStoreInst)
 		// Start the main proxy service
 		managementasset.StartAutoUpdater(context.Background(), configFilePath)
 		cmd.StartService(cfg, configFilePath, password)
 	}
 }
+
+// configureSignatureCache installs the signature cache backend selected in the configuration.
+// Unknown or unavailable backends fall back to the in-memory store.
+func configureSignatureCache(cfg *config.Config, configFilePath string, pgStore *store.PostgresStore) {
+	cache.SetSignatureCacheLimits(time.Duration(cfg.SignatureCache.TTLSeconds)*time.Second, cfg.SignatureCache.MaxEntriesPerSession)
+	backend := strings.ToLower(strings.TrimSpace(cfg.SignatureCache.Backend))
+	switch backend {
+	case "", "memory":
+		cache.SetSignatureStore(nil)
+	case "file":
+		dir := strings.TrimSpace(cfg.SignatureCache.Dir)
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(configFilePath), "signature-cache")
+		}
+		fileStore, errStore := cache.NewFileSignatureStore(dir)
+		if errStore != nil {
+			log.Errorf("failed to initialize file signature cache, using memory: %v", errStore)
+			cache.SetSignatureStore(nil)
+			return
+		}
+		cache.SetSignatureStore(fileStore)
+		log.Infof("file-backed signature cache enabled, directory: %s", fileStore.Dir())
+	case "postgres":
+		if pgStore == nil {
+			log.Warn("signature-cache backend postgres requires PGSTORE_DSN, using memory")
+			cache.SetSignatureStore(nil)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pgSignatures, errStore := pgStore.SignatureStore(ctx)
+		if errStore != nil {
+			log.Errorf("failed to initialize postgres signature cache, using memory: %v", errStore)
+			cache.SetSignatureStore(nil)
+			return
+		}
+		cache.SetSignatureStore(pgSignatures)
+		log.Info("postgres-backed signature cache enabled")
+	default:
+		log.Warnf("unknown signature-cache backend %q, using memory", cfg.SignatureCache.Backend)
+		cache.SetSignatureStore(nil)
+	}
+}
+
+// configureResponseStore installs the Responses API store backend selected in the configuration.
+func configureResponseStore(cfg *config.Config, configFilePath string, pgStore *store.PostgresStore) {
+	ttl := time.Duration(cfg.ResponseStore.TTLSeconds) * time.Second
+	backend := strings.ToLower(strings.TrimSpace(cfg.ResponseStore.Backend))
+	switch backend {
+	case "", "memory":
+		responses.SetStore(responses.NewMemoryStore(ttl, cfg.ResponseStore.MaxBytes))
+	case "file":
+		dir := strings.TrimSpace(cfg.ResponseStore.Dir)
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(configFilePath), "responses")
+		}
+		fileStore, errStore := responses.NewFileStore(dir, ttl)
+		if errStore != nil {
+			log.Errorf("failed to initialize file response store, using memory: %v", errStore)
+			responses.SetStore(nil)
+			return
+		}
+		responses.SetStore(fileStore)
+		log.Infof("file-backed response store enabled, directory: %s", fileStore.Dir())
+	case "postgres":
+		if pgStore == nil {
+			log.Warn("response-store backend postgres requires PGSTORE_DSN, using memory")
+			responses.SetStore(nil)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pgResponses, errStore := pgStore.ResponseStore(ctx, ttl)
+		if errStore != nil {
+			log.Errorf("failed to initialize postgres response store, using memory: %v", errStore)
+			responses.SetStore(nil)
+			return
+		}
+		responses.SetStore(pgResponses)
+		log.Info("postgres-backed response store enabled")
+	default:
+		log.Warnf("unknown response-store backend %q, using memory", cfg.ResponseStore.Backend)
+		responses.SetStore(nil)
+	}
+}
+
+// configureUsageStore registers a persistent usage plugin for the configured backend.
+// The backend is selected once at startup; failures leave persistence disabled.
+func configureUsageStore(cfg *config.Config, configFilePath string, pgStore *store.PostgresStore) {
+	backend := strings.ToLower(strings.TrimSpace(cfg.UsageStore.Backend))
+	if backend == "" || backend == "none" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var (
+		usageStore *usage.SQLStore
+		errStore   error
+	)
+	switch backend {
+	case "sqlite":
+		path := strings.TrimSpace(cfg.UsageStore.Path)
+		if path == "" {
+			path = filepath.Join(filepath.Dir(configFilePath), "usage.db")
+		}
+		usageStore, errStore = usage.OpenSQLiteStore(ctx, path)
+		if errStore == nil {
+			log.Infof("sqlite-backed usage store enabled, database: %s", path)
+		}
+	case "postgres":
+		if pgStore == nil {
+			log.Warn("usage-store backend postgres requires PGSTORE_DSN, persistence disabled")
+			return
+		}
+		usageStore, errStore = pgStore.UsageStore(ctx)
+		if errStore == nil {
+			log.Info("postgres-backed usage store enabled")
+		}
+	default:
+		log.Warnf("unknown usage-store backend %q, persistence disabled", cfg.UsageStore.Backend)
+		return
+	}
+	if errStore != nil {
+		log.Errorf("failed to initialize usage store, persistence disabled: %v", errStore)
+		return
+	}
+	retention := time.Duration(cfg.UsageStore.RetentionDays) * 24 * time.Hour
+	plugin := usage.NewPersistentPlugin(usageStore, retention)
+	coreusage.RegisterPlugin(plugin)
+	usage.SetPersistentPlugin(plugin)
+	usage.SetHistoryStore(usageStore)
+	seedKeyPolicyBudgets(ctx, usageStore)
+}
+
+// seedKeyPolicyBudgets restores the per-key token totals of the current UTC day and month
+// from the usage store so API key budgets are not reset by a restart.
+func seedKeyPolicyBudgets(ctx context.Context, usageStore *usage.SQLStore) {
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthTokens, errMonth := usageStore.TokensByKey(ctx, monthStart)
+	if errMonth != nil {
+		log.Warnf("failed to seed api key budgets from usage store: %v", errMonth)
+		return
+	}
+	dayTokens, errDay := usageStore.TokensByKey(ctx, dayStart)
+	if errDay != nil {
+		log.Warnf("failed to seed api key budgets from usage store: %v", errDay)
+		return
+	}
+	policy.Default().Seed(dayTokens, monthTokens)
+}
@@ -0,0 +1,406 @@
+// Package policy enforces per-client API key restrictions: model and provider allowlists,
+// request rate limits and token budgets accumulated from usage records.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+var defaultEnforcer = NewEnforcer()
+
+func init() {
+	coreusage.RegisterPlugin(defaultEnforcer)
+}
+
+// Default returns the process-wide enforcer fed by the usage pipeline.
+func Default() *Enforcer { return defaultEnforcer }
+
+// Violation describes why a request was rejected.
+type Violation struct {
+	// StatusCode is 403 for allowlist violations and 429 for rate or budget violations.
+	StatusCode int
+	// Message is a human readable reason.
+	Message string
+	// RetryAfter is set for rate and budget violations.
+	RetryAfter time.Duration
+}
+
+func (v *Violation) Error() string { return v.Message }
+
+// Request captures the attributes of an inbound call that a policy can restrict.
+type Request struct {
+	APIKey string
+	// Model is the model name requested by the client, including any prefix.
+	Model string
+	// Providers lists the providers able to serve the model.
+	Providers []string
+	// SkipRateLimit exempts the request from the requests-per-minute limit and keeps it out of
+	// the window, for auxiliary calls such as token counting.
+	SkipRateLimit bool
+}
+
+// Usage reports the consumption tracked for one key.
+type Usage struct {
+	Name                 string `json:"name,omitempty"`
+	RequestsLastMinute   int    `json:"requests-last-minute"`
+	RequestsPerMinute    int    `json:"requests-per-minute,omitempty"`
+	TokensToday          int64  `json:"tokens-today"`
+	TokensPerDay         int64  `json:"tokens-per-day,omitempty"`
+	TokensThisMonth      int64  `json:"tokens-this-month"`
+	MonthlyTokenBudget   int64  `json:"monthly-token-budget,omitempty"`
+	HasPolicy            bool   `json:"has-policy"`
+	RejectedRequests     int64  `json:"rejected-requests"`
+	LastRejectionMessage string `json:"last-rejection,omitempty"`
+}
+
+type keyState struct {
+	requests     []time.Time
+	day          string
+	dayTokens    int64
+	month        string
+	monthTokens  int64
+	rejected     int64
+	lastRejected string
+}
+
+// Enforcer evaluates requests against the configured policies.
+type Enforcer struct {
+	mu       sync.Mutex
+	policies map[string]config.APIKeyPolicy
+	states   map[string]*keyState
+	now      func() time.Time
+	// isPrefix reports whether a model name segment is a configured credential prefix.
+	isPrefix func(string) bool
+	// seeds holds persisted token totals by key hash until the key is first seen.
+	seeds map[string]keyState
+}
+
+// NewEnforcer constructs an enforcer without policies.
+func NewEnforcer() *Enforcer {
+	return &Enforcer{
+		policies: make(map[string]config.APIKeyPolicy),
+		states:   make(map[string]*keyState),
+		now:      time.Now,
+	}
+}
+
+// SetPrefixResolver registers the lookup deciding whether the segment before the first "/" of a
+// model name is a configured credential prefix. Without it no model name carries a prefix.
+func (e *Enforcer) SetPrefixResolver(isPrefix func(prefix string) bool) {
+	e.mu.Lock()
+	e.isPrefix = isPrefix
+	e.mu.Unlock()
+}
+
+// Seed restores token consumption recorded before the process started so budgets survive a
+// restart. Totals are keyed by util.HashAPIKey of the client key and cover the current UTC
+// day and month; they are applied when the key is first seen.
+func (e *Enforcer) Seed(dayTokens, monthTokens map[string]int64) {
+	now := e.now().UTC()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seeds = make(map[string]keyState, len(monthTokens))
+	for hash, tokens := range monthTokens {
+		e.seeds[hash] = keyState{
+			day:         now.Format("2006-01-02"),
+			dayTokens:   dayTokens[hash],
+			month:       now.Format("2006-01"),
+			monthTokens: tokens,
+		}
+	}
+	for key, state := range e.states {
+		hash := util.HashAPIKey(key)
+		if seed, ok := e.seeds[hash]; ok {
+			e.rollLocked(state, now)
+			state.dayTokens += seed.dayTokens
+			state.monthTokens += seed.monthTokens
+			delete(e.seeds, hash)
+		}
+	}
+}
+
+// SetPolicies replaces the active policies. Accumulated usage is kept.
+func (e *Enforcer) SetPolicies(policies []config.APIKeyPolicy) {
+	next := make(map[string]config.APIKeyPolicy, len(policies))
+	for _, p := range policies {
+		key := strings.TrimSpace(p.APIKey)
+		if key == "" {
+			continue
+		}
+		p.APIKey = key
+		next[key] = p
+	}
+	e.mu.Lock()
+	e.policies = next
+	e.mu.Unlock()
+}
+
+// Check evaluates req and returns the providers the key may use. A nil violation admits the
+// request and counts it against the key's rate limit.
+func (e *Enforcer) Check(req Request) ([]string, *Violation) {
+	key := strings.TrimSpace(req.APIKey)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.policies[key]
+	if !ok || key == "" {
+		return req.Providers, nil
+	}
+	state := e.stateLocked(key)
+	providers, violation := e.evaluateLocked(p, state, req)
+	if violation != nil {
+		state.rejected++
+		state.lastRejected = violation.Message
+		return nil, violation
+	}
+	if !req.SkipRateLimit {
+		state.requests = append(state.requests, e.now())
+	}
+	return providers, nil
+}
+
+// Allowed returns the providers the key may use for model without counting a request.
+// It applies only the model and provider restrictions and returns nil when the model is denied.
+func (e *Enforcer) Allowed(apiKey, model string, providers []string) []string {
+	key := strings.TrimSpace(apiKey)
+	e.mu.Lock()
+	p, ok := e.policies[key]
+	isPrefix := e.isPrefix
+	e.mu.Unlock()
+	if !ok || key == "" {
+		return providers
+	}
+	allowed, violation := accessAllowed(p, Request{APIKey: key, Model: model, Providers: providers}, isPrefix)
+	if violation != nil {
+		return nil
+	}
+	return allowed
+}
+
+func (e *Enforcer) evaluateLocked(p config.APIKeyPolicy, state *keyState, req Request) ([]string, *Violation) {
+	providers, violation := accessAllowed(p, req, e.isPrefix)
+	if violation != nil {
+		return nil, violation
+	}
+
+	now := e.now().UTC()
+	e.rollLocked(state, now)
+	if p.MonthlyTokenBudget > 0 && state.monthTokens >= p.MonthlyTokenBudget {
+		next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		return nil, &Violation{StatusCode: http.StatusTooManyRequests, Message: "API key has exhausted its monthly token budget", RetryAfter: next.Sub(now)}
+	}
+	if p.TokensPerDay > 0 && state.dayTokens >= p.TokensPerDay {
+		next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return nil, &Violation{StatusCode: http.StatusTooManyRequests, Message: "API key has exceeded its daily token limit", RetryAfter: next.Sub(now)}
+	}
+	if p.RequestsPerMinute > 0 && !req.SkipRateLimit {
+		state.requests = pruneWindow(state.requests, now)
+		if len(state.requests) >= p.RequestsPerMinute {
+			retry := state.requests[0].Add(time.Minute).Sub(now)
+			if retry < time.Second {
+				retry = time.Second
+			}
+			return nil, &Violation{StatusCode: http.StatusTooManyRequests, Message: "API key has exceeded its requests per minute limit", RetryAfter: retry}
+		}
+	}
+	return providers, nil
+}
+
+// accessAllowed applies the policy's prefix, model and provider restrictions.
+func accessAllowed(p config.APIKeyPolicy, req Request, isPrefix func(string) bool) ([]string, *Violation) {
+	prefix, baseModel := splitModelPrefix(req.Model, isPrefix)
+	if len(p.AllowedPrefixes) > 0 && !containsFold(p.AllowedPrefixes, prefix) {
+		return nil, &Violation{StatusCode: http.StatusForbidden, Message: fmt.Sprintf("API key is not allowed to use model %s", req.Model)}
+	}
+	if len(p.AllowedModels) > 0 && !matchAny(p.AllowedModels, req.Model) && !matchAny(p.AllowedModels, baseModel) {
+		return nil, &Violation{StatusCode: http.StatusForbidden, Message: fmt.Sprintf("API key is not allowed to use model %s", req.Model)}
+	}
+	providers := req.Providers
+	if len(p.AllowedProviders) > 0 {
+		providers = make([]string, 0, len(req.Providers))
+		for _, provider := range req.Providers {
+			if containsFold(p.AllowedProviders, provider) {
+				providers = append(providers, provider)
+			}
+		}
+		if len(providers) == 0 {
+			return nil, &Violation{StatusCode: http.StatusForbidden, Message: fmt.Sprintf("API key is not allowed to use the providers serving model %s", req.Model)}
+		}
+	}
+	return providers, nil
+}
+
+// HandleUsage implements coreusage.Plugin, accumulating token consumption per client key.
+func (e *Enforcer) HandleUsage(_ context.Context, record coreusage.Record) {
+	key := strings.TrimSpace(record.APIKey)
+	if key == "" {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	if tokens <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.stateLocked(key)
+	e.rollLocked(state, e.now().UTC())
+	state.dayTokens += tokens
+	state.monthTokens += tokens
+}
+
+// Usage returns the tracked consumption for every key with a policy or recorded usage.
+func (e *Enforcer) Usage() map[string]Usage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now().UTC()
+	out := make(map[string]Usage, len(e.states))
+	keys := make([]string, 0, len(e.states)+len(e.policies))
+	for key := range e.states {
+		keys = append(keys, key)
+	}
+	for key := range e.policies {
+		if _, ok := e.states[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		state := e.stateLocked(key)
+		e.rollLocked(state, now)
+		state.requests = pruneWindow(state.requests, now)
+		u := Usage{
+			RequestsLastMinute:   len(state.requests),
+			TokensToday:          state.dayTokens,
+			TokensThisMonth:      state.monthTokens,
+			RejectedRequests:     state.rejected,
+			LastRejectionMessage: state.lastRejected,
+		}
+		if p, ok := e.policies[key]; ok {
+			u.HasPolicy = true
+			u.Name = p.Name
+			u.RequestsPerMinute = p.RequestsPerMinute
+			u.TokensPerDay = p.TokensPerDay
+			u.MonthlyTokenBudget = p.MonthlyTokenBudget
+		}
+		out[key] = u
+	}
+	return out
+}
+
+func (e *Enforcer) stateLocked(key string) *keyState {
+	state, ok := e.states[key]
+	if !ok {
+		state = &keyState{}
+		if len(e.seeds) > 0 {
+			hash := util.HashAPIKey(key)
+			if seed, seeded := e.seeds[hash]; seeded {
+				*state = seed
+				delete(e.seeds, hash)
+			}
+		}
+		e.states[key] = state
+	}
+	return state
+}
+
+// rollLocked resets the day and month counters when the UTC period changes.
+func (e *Enforcer) rollLocked(state *keyState, now time.Time) {
+	day := now.Format("2006-01-02")
+	if state.day != day {
+		state.day = day
+		state.dayTokens = 0
+	}
+	month := now.Format("2006-01")
+	if state.month != month {
+		state.month = month
+		state.monthTokens = 0
+	}
+}
+
+func pruneWindow(requests []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-time.Minute)
+	start := 0
+	for start < len(requests) && !requests[start].After(cutoff) {
+		start++
+	}
+	return requests[start:]
+}
+
+// splitModelPrefix separates a leading credential prefix ("teamA/model") from the model name.
+// Only configured prefixes are split off, so names such as "moonshotai/kimi-k2" stay intact.
+func splitModelPrefix(model string, isPrefix func(string) bool) (string, string) {
+	model = strings.TrimSpace(model)
+	if isPrefix == nil {
+		return "", model
+	}
+	if idx := strings.Index(model, "/"); idx > 0 && isPrefix(model[:idx]) {
+		return model[:idx], model[idx+1:]
+	}
+	return "", model
+}
+
+func containsFold(values []string, needle string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if matchWildcard(strings.ToLower(strings.TrimSpace(pattern)), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard reports whether value matches pattern, where '*' matches any substring.
+func matchWildcard(pattern, value string) bool {
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+	parts := strings.Split(pattern, "*")
+	if prefix := parts[0]; prefix != "" {
+		if !strings.HasPrefix(value, prefix) {
+			return false
+		}
+		value = value[len(prefix):]
+	}
+	if suffix := parts[len(parts)-1]; suffix != "" {
+		if !strings.HasSuffix(value, suffix) {
+			return false
+		}
+		value = value[:len(value)-len(suffix)]
+	}
+	for i := 1; i < len(parts)-1; i++ {
+		segment := parts[i]
+		if segment == "" {
+			continue
+		}
+		idx := strings.Index(value, segment)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(segment):]
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestEnforcerCheck_Allowlists(t *testing.T) {
+	e := NewEnforcer()
+	e.SetPolicies([]config.APIKeyPolicy{{
+		APIKey:           "ci",
+		AllowedModels:    []string{"gemini-2.5-*"},
+		AllowedProviders: []string{"gemini"},
+	}})
+
+	providers, v := e.Check(Request{APIKey: "ci", Model: "gemini-2.5-pro", Providers: []string{"gemini-cli", "gemini"}})
+	if v != nil {
+		t.Fatalf("Check() violation = %v", v)
+	}
+	if len(providers) != 1 || providers[0] != "gemini" {
+		t.Fatalf("Check() providers = %v, want [gemini]", providers)
+	}
+	if _, v = e.Check(Request{APIKey: "ci", Model: "claude-sonnet-4", Providers: []string{"claude"}}); v == nil || v.StatusCode != http.StatusForbidden {
+		t.Fatalf("Check() disallowed model violation = %v, want 403", v)
+	}
+	if _, v = e.Check(Request{APIKey: "ci", Model: "gemini-2.5-flash", Providers: []string{"vertex"}}); v == nil || v.StatusCode != http.StatusForbidden {
+		t.Fatalf("Check() disallowed provider violation = %v, want 403", v)
+	}
+	if _, v = e.Check(Request{APIKey: "other", Model: "claude-sonnet-4", Providers: []string{"claude"}}); v != nil {
+		t.Fatalf("Check() key without policy violation = %v, want nil", v)
+	}
+}
+
+func TestEnforcerCheck_Prefixes(t *testing.T) {
+	e := NewEnforcer()
+	e.SetPrefixResolver(func(prefix string) bool { return prefix == "teamA" })
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "team", AllowedPrefixes: []string{"teamA"}, AllowedModels: []string{"gemini-*"}}})
+
+	if _, v := e.Check(Request{APIKey: "team", Model: "teamA/gemini-2.5-pro", Providers: []string{"gemini"}}); v != nil {
+		t.Fatalf("Check() prefixed violation = %v, want nil", v)
+	}
+	if _, v := e.Check(Request{APIKey: "team", Model: "gemini-2.5-pro", Providers: []string{"gemini"}}); v == nil || v.StatusCode != http.StatusForbidden {
+		t.Fatalf("Check() unprefixed violation = %v, want 403", v)
+	}
+}
+
+func TestEnforcerCheck_SlashInModelNameIsNotAPrefix(t *testing.T) {
+	e := NewEnforcer()
+	e.SetPrefixResolver(func(prefix string) bool { return prefix == "teamA" })
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "k", AllowedModels: []string{"kimi-*"}}})
+
+	if _, v := e.Check(Request{APIKey: "k", Model: "moonshotai/kimi-k2", Providers: []string{"openai-compat"}}); v == nil || v.StatusCode != http.StatusForbidden {
+		t.Fatalf("Check() vendor-qualified model violation = %v, want 403", v)
+	}
+	if _, v := e.Check(Request{APIKey: "k", Model: "teamA/kimi-k2", Providers: []string{"openai-compat"}}); v != nil {
+		t.Fatalf("Check() prefixed model violation = %v, want nil", v)
+	}
+}
+
+func TestEnforcerCheck_SkipRateLimit(t *testing.T) {
+	e := NewEnforcer()
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "k", RequestsPerMinute: 1}})
+
+	count := Request{APIKey: "k", Model: "m", Providers: []string{"p"}, SkipRateLimit: true}
+	for i := 0; i < 3; i++ {
+		if _, v := e.Check(count); v != nil {
+			t.Fatalf("Check() count #%d violation = %v", i, v)
+		}
+	}
+	if _, v := e.Check(Request{APIKey: "k", Model: "m", Providers: []string{"p"}}); v != nil {
+		t.Fatalf("Check() after counts violation = %v, want nil", v)
+	}
+}
+
+func TestEnforcerSeed_RestoresBudgets(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	e := NewEnforcer()
+	e.now = func() time.Time { return now }
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "k", TokensPerDay: 100, MonthlyTokenBudget: 500}})
+	hash := util.HashAPIKey("k")
+	e.Seed(map[string]int64{hash: 100}, map[string]int64{hash: 300})
+
+	if _, v := e.Check(Request{APIKey: "k", Model: "m", Providers: []string{"p"}}); v == nil || v.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Check() seeded day violation = %v, want 429", v)
+	}
+	if got := e.Usage()["k"]; got.TokensToday != 100 || got.TokensThisMonth != 300 {
+		t.Fatalf("Usage() = %+v, want seeded totals", got)
+	}
+}
+
+func TestEnforcerCheck_RequestsPerMinute(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	e := NewEnforcer()
+	e.now = func() time.Time { return now }
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "k", RequestsPerMinute: 2}})
+
+	req := Request{APIKey: "k", Model: "m", Providers: []string{"p"}}
+	for i := 0; i < 2; i++ {
+		if _, v := e.Check(req); v != nil {
+			t.Fatalf("Check() #%d violation = %v", i, v)
+		}
+	}
+	_, v := e.Check(req)
+	if v == nil || v.StatusCode != http.StatusTooManyRequests || v.RetryAfter <= 0 {
+		t.Fatalf("Check() over limit violation = %+v, want 429 with Retry-After", v)
+	}
+	now = now.Add(61 * time.Second)
+	if _, v = e.Check(req); v != nil {
+		t.Fatalf("Check() after window violation = %v", v)
+	}
+}
+
+func TestEnforcerCheck_TokenLimitsFromUsage(t *testing.T) {
+	now := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	e := NewEnforcer()
+	e.now = func() time.Time { return now }
+	e.SetPolicies([]config.APIKeyPolicy{{APIKey: "k", TokensPerDay: 100, MonthlyTokenBudget: 150}})
+	req := Request{APIKey: "k", Model: "m", Providers: []string{"p"}}
+
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "k", Detail: coreusage.Detail{TotalTokens: 100}})
+	if _, v := e.Check(req); v == nil || v.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Check() over daily limit violation = %v, want 429", v)
+	}
+
+	now = now.Add(2 * time.Hour) // next day, next month
+	if _, v := e.Check(req); v != nil {
+		t.Fatalf("Check() after rollover violation = %v", v)
+	}
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "k", Detail: coreusage.Detail{InputTokens: 60, OutputTokens: 40}})
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "k", Detail: coreusage.Detail{TotalTokens: 50}})
+	_, v := e.Check(req)
+	if v == nil || v.StatusCode != http.StatusTooManyRequests || v.Message != "API key has exhausted its monthly token budget" {
+		t.Fatalf("Check() over monthly budget violation = %+v", v)
+	}
+
+	usage := e.Usage()["k"]
+	if usage.TokensThisMonth != 150 || usage.RejectedRequests != 2 || !usage.HasPolicy {
+		t.Fatalf("Usage() = %+v", usage)
+	}
+}
@@ -0,0 +1,57 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// api-key-policies: []APIKeyPolicy
+func (h *Handler) GetAPIKeyPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"api-key-policies": h.cfg.APIKeyPolicies})
+}
+
+func (h *Handler) PutAPIKeyPolicies(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var arr []config.APIKeyPolicy
+	if err = json.Unmarshal(data, &arr); err != nil {
+		var obj struct {
+			Items []config.APIKeyPolicy `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &obj); err2 != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		arr = obj.Items
+	}
+	policies := make([]config.APIKeyPolicy, 0, len(arr))
+	for _, p := range arr {
+		p.APIKey = strings.TrimSpace(p.APIKey)
+		if p.APIKey == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "api-key is required"})
+			return
+		}
+		policies = append(policies, p)
+	}
+	h.cfg.APIKeyPolicies = policies
+	h.persist(c)
+}
+
+// GetAPIKeyPolicyUsage reports request and token consumption per client key with masked keys.
+func (h *Handler) GetAPIKeyPolicyUsage(c *gin.Context) {
+	usage := policy.Default().Usage()
+	out := make(map[string]policy.Usage, len(usage))
+	for key, u := range usage {
+		out[util.HideAPIKey(key)] = u
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": out})
+}
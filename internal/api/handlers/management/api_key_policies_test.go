@@ -0,0 +1,66 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestAPIKeyPolicies_PutValidatesAndUsageMasksKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := &config.Config{}
+	h := NewHandler(cfg, configPath, nil)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPut, "/v0/management/api-key-policies", strings.NewReader(body))
+		h.PutAPIKeyPolicies(c)
+		return rec
+	}
+	if rec := put(`[{"name":"ci","requests-per-minute":5}]`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT without api-key status = %d, want 400", rec.Code)
+	}
+	if rec := put(`{"items":[{"api-key":" sk-policy-test-key ","name":"ci","requests-per-minute":5}]}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(cfg.APIKeyPolicies) != 1 || cfg.APIKeyPolicies[0].APIKey != "sk-policy-test-key" {
+		t.Fatalf("policies not stored: %+v", cfg.APIKeyPolicies)
+	}
+
+	policy.Default().SetPolicies(cfg.APIKeyPolicies)
+	t.Cleanup(func() { policy.Default().SetPolicies(nil) })
+	policy.Default().HandleUsage(context.Background(), coreusage.Record{APIKey: "sk-policy-test-key", Detail: coreusage.Detail{TotalTokens: 42}})
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v0/management/api-key-policies/usage", nil)
+	h.GetAPIKeyPolicyUsage(c)
+	if strings.Contains(rec.Body.String(), "sk-policy-test-key") {
+		t.Fatalf("usage exposes the raw key: %s", rec.Body.String())
+	}
+	var body struct {
+		Usage map[string]policy.Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode usage: %v", err)
+	}
+	got, ok := body.Usage["sk-p...-key"]
+	if !ok || got.Name != "ci" || got.TokensToday != 42 || got.RequestsPerMinute != 5 {
+		t.Fatalf("usage = %+v, want masked entry for the policy key", body.Usage)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -345,7 +346,7 @@ func (h *Handler) listAuthFilesFromDisk(c *gin.Context) {
 
 			// Read file to get type field
 			full := filepath.Join(h.cfg.AuthDir, name)
-			if data, errRead := os.ReadFile(full); errRead == nil {
+			if data, errRead := tokencrypt.ReadFile(full); errRead == nil {
 				typeValue := gjson.GetBytes(data, "type").String()
 				emailValue := gjson.GetBytes(data, "email").String()
 				fileData["type"] = typeValue
@@ -393,6 +394,14 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if email := authEmail(auth); email != "" {
 		entry["email"] = email
 	}
+	if auth.Prefix != "" {
+		entry["prefix"] = auth.Prefix
+	}
+	if priority, ok := auth.Metadata["priority"]; ok {
+		entry["priority"] = priority
+	} else if priority, err := strconv.Atoi(authAttribute(auth, "priority")); err == nil {
+		entry["priority"] = priority
+	}
 	if accountType, account := auth.AccountInfo(); accountType != "" || account != "" {
 		if accountType != "" {
 			entry["account_type"] = accountType
@@ -411,6 +420,11 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if !auth.LastRefreshedAt.IsZero() {
 		entry["last_refresh"] = auth.LastRefreshedAt
 	}
+	if h.authManager != nil {
+		if probe, ok := h.authManager.ProbeResult(auth.ID); ok {
+			entry["health_check"] = probe
+		}
+	}
 	if path != "" {
 		entry["path"] = path
 		entry["source"] = "file"
@@ -513,7 +527,7 @@ func (h *Handler) DownloadAuthFile(c *gin.Context) {
 		return
 	}
 	full := filepath.Join(h.cfg.AuthDir, name)
-	data, err := os.ReadFile(full)
+	data, err := tokencrypt.ReadFile(full)
 	if err != nil {
 		if os.IsNotExist(err) {
 			c.JSON(404, gin.H{"error": "file not found"})
@@ -545,13 +559,20 @@ func (h *Handler) UploadAuthFile(c *gin.Context) {
 				dst = abs
 			}
 		}
-		if errSave := c.SaveUploadedFile(file, dst); errSave != nil {
-			c.JSON(500, gin.H{"error": fmt.Sprintf("failed to save file: %v", errSave)})
+		src, errOpen := file.Open()
+		if errOpen != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to open uploaded file: %v", errOpen)})
 			return
 		}
-		data, errRead := os.ReadFile(dst)
+		data, errRead := io.ReadAll(src)
+		_ = src.Close()
 		if errRead != nil {
-			c.JSON(500, gin.H{"error": fmt.Sprintf("failed to read saved file: %v", errRead)})
+			c.JSON(400, gin.H{"error": fmt.Sprintf("failed to read uploaded file: %v", errRead)})
+			return
+		}
+		data, errWrite := writeUploadedAuth(dst, data)
+		if errWrite != nil {
+			c.JSON(errWrite.code, gin.H{"error": errWrite.msg})
 			return
 		}
 		if errReg := h.registerAuthFromFile(ctx, dst, data); errReg != nil {
@@ -581,8 +602,9 @@ func (h *Handler) UploadAuthFile(c *gin.Context) {
 			dst = abs
 		}
 	}
-	if errWrite := os.WriteFile(dst, data, 0o600); errWrite != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to write file: %v", errWrite)})
+	data, errWrite := writeUploadedAuth(dst, data)
+	if errWrite != nil {
+		c.JSON(errWrite.code, gin.H{"error": errWrite.msg})
 		return
 	}
 	if err = h.registerAuthFromFile(ctx, dst, data); err != nil {
@@ -592,6 +614,25 @@ func (h *Handler) UploadAuthFile(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
+type uploadError struct {
+	code int
+	msg  string
+}
+
+// writeUploadedAuth stores an uploaded credential at dst. Uploads may already be encrypted, so
+// they are opened first and sealed again in memory; plaintext never reaches the disk when a key
+// is configured. It returns the plaintext for registration.
+func writeUploadedAuth(dst string, data []byte) ([]byte, *uploadError) {
+	plain, err := tokencrypt.Decrypt(data)
+	if err != nil {
+		return nil, &uploadError{code: 400, msg: fmt.Sprintf("failed to decrypt auth file: %v", err)}
+	}
+	if err = tokencrypt.WriteFile(dst, plain, 0o600); err != nil {
+		return nil, &uploadError{code: 500, msg: fmt.Sprintf("failed to write file: %v", err)}
+	}
+	return plain, nil
+}
+
 // Delete auth files: single by name or all
 func (h *Handler) DeleteAuthFile(c *gin.Context) {
 	if h.authManager == nil {
@@ -659,6 +700,161 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
+// authFilePatch is the body of PATCH /auth-files. Omitted fields are left unchanged.
+type authFilePatch struct {
+	AuthIndex string  `json:"auth_index"`
+	Name      string  `json:"name"`
+	Disabled  *bool   `json:"disabled"`
+	Label     *string `json:"label"`
+	Prefix    *string `json:"prefix"`
+	ProxyURL  *string `json:"proxy_url"`
+	Priority  *int    `json:"priority"`
+	// ResetCooldown clears cooldown and quota state for Models, or for every model when empty.
+	ResetCooldown bool     `json:"reset_cooldown"`
+	Models        []string `json:"models"`
+}
+
+// SetModelRegistrar installs the function that rebinds a credential's models in the global
+// registry, as the file watcher does when an auth file changes.
+func (h *Handler) SetModelRegistrar(register func(*coreauth.Auth)) { h.registerModels = register }
+
+// PatchAuthFile edits a credential at runtime: enable/disable, label, model prefix, proxy URL and
+// routing priority, and optionally resets its per-model cooldowns. Credentials are addressed by
+// auth_index or name. Changes to file-backed credentials are written to the active token store;
+// credentials from the config file are changed in memory only. A new prefix re-registers the
+// credential's models so they are listed and routed under it right away.
+func (h *Handler) PatchAuthFile(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	var body authFilePatch
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	auth := h.findAuthForPatch(body.AuthIndex, body.Name)
+	if auth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+		return
+	}
+
+	changed := false
+	prefixChanged := false
+	setMetadata := func(key string, value any, keep bool) {
+		if auth.Metadata == nil {
+			return
+		}
+		if keep {
+			auth.Metadata[key] = value
+		} else {
+			delete(auth.Metadata, key)
+		}
+	}
+	if body.Disabled != nil {
+		auth.Disabled = *body.Disabled
+		if auth.Disabled {
+			auth.Status = coreauth.StatusDisabled
+			auth.StatusMessage = "disabled via management API"
+		} else {
+			auth.Status = coreauth.StatusActive
+			auth.StatusMessage = ""
+		}
+		setMetadata("disabled", true, auth.Disabled)
+		changed = true
+	}
+	if body.Label != nil {
+		auth.Label = strings.TrimSpace(*body.Label)
+		setMetadata("label", auth.Label, auth.Label != "")
+		changed = true
+	}
+	if body.Prefix != nil {
+		prefix := strings.Trim(strings.TrimSpace(*body.Prefix), "/")
+		if strings.Contains(prefix, "/") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "prefix must not contain '/'"})
+			return
+		}
+		prefixChanged = prefix != auth.Prefix
+		auth.Prefix = prefix
+		setMetadata("prefix", prefix, prefix != "")
+		changed = true
+	}
+	if body.ProxyURL != nil {
+		proxyURL := strings.TrimSpace(*body.ProxyURL)
+		if proxyURL != "" {
+			if parsed, err := url.Parse(proxyURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid proxy_url"})
+				return
+			}
+		}
+		auth.ProxyURL = proxyURL
+		setMetadata("proxy_url", proxyURL, proxyURL != "")
+		changed = true
+	}
+	if body.Priority != nil {
+		if auth.Attributes != nil {
+			delete(auth.Attributes, "priority")
+		}
+		if auth.Metadata != nil {
+			setMetadata("priority", *body.Priority, *body.Priority != 0)
+		} else if *body.Priority != 0 {
+			if auth.Attributes == nil {
+				auth.Attributes = make(map[string]string)
+			}
+			auth.Attributes["priority"] = strconv.Itoa(*body.Priority)
+		}
+		changed = true
+	}
+	if !changed && !body.ResetCooldown {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no changes requested"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if changed {
+		auth.UpdatedAt = time.Now()
+		updated, err := h.authManager.Update(ctx, auth)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to update auth: %v", err)})
+			return
+		}
+		auth = updated
+		if prefixChanged && h.registerModels != nil {
+			h.registerModels(auth)
+		}
+	}
+	if body.ResetCooldown {
+		updated, err := h.authManager.ResetCooldown(ctx, auth.ID, body.Models...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to reset cooldown: %v", err)})
+			return
+		}
+		auth = updated
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"persisted": auth.Metadata != nil && !isRuntimeOnlyAuth(auth),
+		"file":      h.buildAuthFileEntry(auth),
+	})
+}
+
+// findAuthForPatch resolves a credential by auth_index, or by file name or ID.
+func (h *Handler) findAuthForPatch(authIndex, name string) *coreauth.Auth {
+	if auth := h.authByIndex(authIndex); auth != nil {
+		return auth
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	for _, auth := range h.authManager.List() {
+		if auth.ID == name || auth.FileName == name || filepath.Base(authAttribute(auth, "path")) == name {
+			return auth
+		}
+	}
+	return nil
+}
+
 func (h *Handler) authIDForPath(path string) string {
 	path = strings.TrimSpace(path)
 	if path == "" {
@@ -691,6 +887,10 @@ func (h *Handler) registerAuthFromFile(ctx context.Context, path string, data []
 			return fmt.Errorf("failed to read auth file: %w", err)
 		}
 	}
+	data, errDecrypt := tokencrypt.Decrypt(data)
+	if errDecrypt != nil {
+		return fmt.Errorf("failed to decrypt auth file: %w", errDecrypt)
+	}
 	metadata := make(map[string]any)
 	if err := json.Unmarshal(data, &metadata); err != nil {
 		return fmt.Errorf("invalid auth file: %w", err)
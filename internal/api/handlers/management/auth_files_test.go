@@ -0,0 +1,113 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func patchAuthFile(t *testing.T, h *Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPatch, "/v0/management/auth-files", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.PatchAuthFile(c)
+	return rec
+}
+
+func TestPatchAuthFile_UpdatesAndPersistsFields(t *testing.T) {
+	store := &memoryAuthStore{}
+	manager := coreauth.NewManager(store, nil, nil)
+	auth := &coreauth.Auth{
+		ID:       "codex-user.json",
+		FileName: "codex-user.json",
+		Provider: "codex",
+		Status:   coreauth.StatusActive,
+		Metadata: map[string]any{"type": "codex", "email": "user@example.com"},
+		ModelStates: map[string]*coreauth.ModelState{
+			"gpt-5": {Status: coreauth.StatusError, Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)},
+		},
+	}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, manager)
+
+	rec := patchAuthFile(t, h, `{"name":"codex-user.json","disabled":true,"label":"team","prefix":"/teamA/","proxy_url":"socks5://127.0.0.1:1080","priority":2,"reset_cooldown":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	got, _ := manager.GetByID(auth.ID)
+	if !got.Disabled || got.Status != coreauth.StatusDisabled || got.Label != "team" || got.Prefix != "teamA" || got.ProxyURL != "socks5://127.0.0.1:1080" {
+		t.Fatalf("auth not updated: %+v", got)
+	}
+	if len(got.ModelStates) != 0 {
+		t.Fatalf("model states not reset: %+v", got.ModelStates)
+	}
+	saved := store.items[auth.ID]
+	if saved == nil || saved.Metadata["disabled"] != true || saved.Metadata["prefix"] != "teamA" || saved.Metadata["priority"] != 2 {
+		t.Fatalf("changes not persisted: %+v", saved)
+	}
+
+	rec = patchAuthFile(t, h, `{"name":"codex-user.json","disabled":false,"prefix":""}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	saved = store.items[auth.ID]
+	if _, ok := saved.Metadata["disabled"]; ok {
+		t.Fatalf("disabled flag not cleared: %+v", saved.Metadata)
+	}
+	if _, ok := saved.Metadata["prefix"]; ok {
+		t.Fatalf("prefix not cleared: %+v", saved.Metadata)
+	}
+}
+
+func TestPatchAuthFile_ReregistersModelsOnPrefixChange(t *testing.T) {
+	manager := coreauth.NewManager(&memoryAuthStore{}, nil, nil)
+	auth := &coreauth.Auth{ID: "claude-key", Provider: "claude", Status: coreauth.StatusActive, Prefix: "old"}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, manager)
+	var registered []string
+	h.SetModelRegistrar(func(a *coreauth.Auth) { registered = append(registered, a.Prefix) })
+
+	for _, body := range []string{`{"name":"claude-key","label":"x"}`, `{"name":"claude-key","prefix":"old"}`, `{"name":"claude-key","prefix":"new"}`} {
+		if rec := patchAuthFile(t, h, body); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if len(registered) != 1 || registered[0] != "new" {
+		t.Fatalf("registered = %v, want one re-registration under the new prefix", registered)
+	}
+}
+
+func TestPatchAuthFile_RejectsInvalidInput(t *testing.T) {
+	manager := coreauth.NewManager(&memoryAuthStore{}, nil, nil)
+	if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: "a.json", FileName: "a.json", Provider: "codex", Metadata: map[string]any{}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, manager)
+
+	cases := map[string]int{
+		`{"name":"missing.json","disabled":true}`: http.StatusNotFound,
+		`{"name":"a.json","prefix":"a/b"}`:        http.StatusBadRequest,
+		`{"name":"a.json","proxy_url":"nope"}`:    http.StatusBadRequest,
+		`{"name":"a.json"}`:                       http.StatusBadRequest,
+	}
+	for body, want := range cases {
+		if rec := patchAuthFile(t, h, body); rec.Code != want {
+			t.Errorf("PATCH %s = %d, want %d", body, rec.Code, want)
+		}
+	}
+}
@@ -267,6 +267,12 @@ func normalizeRoutingStrategy(strategy string) (string, bool) {
 		return "round-robin", true
 	case "fill-first", "fillfirst", "ff":
 		return "fill-first", true
+	case "weighted", "wrr":
+		return "weighted", true
+	case "least-in-flight", "leastinflight", "least-requests", "lif":
+		return "least-in-flight", true
+	case "quota-aware", "quotaaware", "quota":
+		return "quota-aware", true
 	default:
 		return "", false
 	}
@@ -307,3 +313,22 @@ func (h *Handler) DeleteProxyURL(c *gin.Context) {
 	h.cfg.ProxyURL = ""
 	h.persist(c)
 }
+
+// GetSessionAffinity reports sticky routing counters: pin hits, new pins, and broken pins.
+func (h *Handler) GetSessionAffinity(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session-affinity": h.authManager.SessionAffinityStats()})
+}
+
+// DeleteSessionAffinity drops every session pin.
+func (h *Handler) DeleteSessionAffinity(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	h.authManager.ClearSessionAffinity()
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
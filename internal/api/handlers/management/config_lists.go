@@ -148,6 +148,7 @@ func (h *Handler) PatchGeminiKey(c *gin.Context) {
 	type geminiKeyPatch struct {
 		APIKey         *string            `json:"api-key"`
 		Prefix         *string            `json:"prefix"`
+		Weight         *int               `json:"weight"`
 		BaseURL        *string            `json:"base-url"`
 		ProxyURL       *string            `json:"proxy-url"`
 		Headers        *map[string]string `json:"headers"`
@@ -196,6 +197,9 @@ func (h *Handler) PatchGeminiKey(c *gin.Context) {
 	if body.Value.Prefix != nil {
 		entry.Prefix = strings.TrimSpace(*body.Value.Prefix)
 	}
+	if body.Value.Weight != nil {
+		entry.Weight = *body.Value.Weight
+	}
 	if body.Value.BaseURL != nil {
 		entry.BaseURL = strings.TrimSpace(*body.Value.BaseURL)
 	}
@@ -274,6 +278,7 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 	type claudeKeyPatch struct {
 		APIKey         *string               `json:"api-key"`
 		Prefix         *string               `json:"prefix"`
+		Weight         *int                  `json:"weight"`
 		BaseURL        *string               `json:"base-url"`
 		ProxyURL       *string               `json:"proxy-url"`
 		Models         *[]config.ClaudeModel `json:"models"`
@@ -314,6 +319,9 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 	if body.Value.Prefix != nil {
 		entry.Prefix = strings.TrimSpace(*body.Value.Prefix)
 	}
+	if body.Value.Weight != nil {
+		entry.Weight = *body.Value.Weight
+	}
 	if body.Value.BaseURL != nil {
 		entry.BaseURL = strings.TrimSpace(*body.Value.BaseURL)
 	}
@@ -394,13 +402,23 @@ func (h *Handler) PutOpenAICompat(c *gin.Context) {
 	h.persist(c)
 }
 func (h *Handler) PatchOpenAICompat(c *gin.Context) {
+	// openAICompatKeyPatch updates a single api-key-entry, selected by index or by key.
+	type openAICompatKeyPatch struct {
+		Index    *int    `json:"index"`
+		Match    *string `json:"match"`
+		ProxyURL *string `json:"proxy-url"`
+		Weight   *int    `json:"weight"`
+	}
 	type openAICompatPatch struct {
-		Name          *string                             `json:"name"`
-		Prefix        *string                             `json:"prefix"`
-		BaseURL       *string                             `json:"base-url"`
-		APIKeyEntries *[]config.OpenAICompatibilityAPIKey `json:"api-key-entries"`
-		Models        *[]config.OpenAICompatibilityModel  `json:"models"`
-		Headers       *map[string]string                  `json:"headers"`
+		Name           *string                                   `json:"name"`
+		Prefix         *string                                   `json:"prefix"`
+		BaseURL        *string                                   `json:"base-url"`
+		Protocol       *string                                   `json:"protocol"`
+		APIKeyEntries  *[]config.OpenAICompatibilityAPIKey       `json:"api-key-entries"`
+		APIKeyEntry    *openAICompatKeyPatch                     `json:"api-key-entry"`
+		Models         *[]config.OpenAICompatibilityModel        `json:"models"`
+		Headers        *map[string]string                        `json:"headers"`
+		ModelDiscovery *config.OpenAICompatibilityModelDiscovery `json:"model-discovery"`
 	}
 	var body struct {
 		Name  *string            `json:"name"`
@@ -446,15 +464,47 @@ func (h *Handler) PatchOpenAICompat(c *gin.Context) {
 		}
 		entry.BaseURL = trimmed
 	}
+	if body.Value.Protocol != nil {
+		entry.Protocol = strings.TrimSpace(*body.Value.Protocol)
+	}
 	if body.Value.APIKeyEntries != nil {
 		entry.APIKeyEntries = append([]config.OpenAICompatibilityAPIKey(nil), (*body.Value.APIKeyEntries)...)
 	}
+	if keyPatch := body.Value.APIKeyEntry; keyPatch != nil {
+		keyIndex := -1
+		if keyPatch.Index != nil && *keyPatch.Index >= 0 && *keyPatch.Index < len(entry.APIKeyEntries) {
+			keyIndex = *keyPatch.Index
+		}
+		if keyIndex == -1 && keyPatch.Match != nil {
+			match := strings.TrimSpace(*keyPatch.Match)
+			for i := range entry.APIKeyEntries {
+				if match != "" && entry.APIKeyEntries[i].APIKey == match {
+					keyIndex = i
+					break
+				}
+			}
+		}
+		if keyIndex == -1 {
+			c.JSON(404, gin.H{"error": "api key entry not found"})
+			return
+		}
+		entry.APIKeyEntries = append([]config.OpenAICompatibilityAPIKey(nil), entry.APIKeyEntries...)
+		if keyPatch.ProxyURL != nil {
+			entry.APIKeyEntries[keyIndex].ProxyURL = strings.TrimSpace(*keyPatch.ProxyURL)
+		}
+		if keyPatch.Weight != nil {
+			entry.APIKeyEntries[keyIndex].Weight = *keyPatch.Weight
+		}
+	}
 	if body.Value.Models != nil {
 		entry.Models = append([]config.OpenAICompatibilityModel(nil), (*body.Value.Models)...)
 	}
 	if body.Value.Headers != nil {
 		entry.Headers = config.NormalizeHeaders(*body.Value.Headers)
 	}
+	if body.Value.ModelDiscovery != nil {
+		entry.ModelDiscovery = *body.Value.ModelDiscovery
+	}
 	normalizeOpenAICompatibilityEntry(&entry)
 	h.cfg.OpenAICompatibility[targetIndex] = entry
 	h.cfg.SanitizeOpenAICompatibility()
@@ -519,6 +569,7 @@ func (h *Handler) PatchVertexCompatKey(c *gin.Context) {
 	type vertexCompatPatch struct {
 		APIKey   *string                     `json:"api-key"`
 		Prefix   *string                     `json:"prefix"`
+		Weight   *int                        `json:"weight"`
 		BaseURL  *string                     `json:"base-url"`
 		ProxyURL *string                     `json:"proxy-url"`
 		Headers  *map[string]string          `json:"headers"`
@@ -567,6 +618,9 @@ func (h *Handler) PatchVertexCompatKey(c *gin.Context) {
 	if body.Value.Prefix != nil {
 		entry.Prefix = strings.TrimSpace(*body.Value.Prefix)
 	}
+	if body.Value.Weight != nil {
+		entry.Weight = *body.Value.Weight
+	}
 	if body.Value.BaseURL != nil {
 		trimmed := strings.TrimSpace(*body.Value.BaseURL)
 		if trimmed == "" {
@@ -839,6 +893,7 @@ func (h *Handler) PatchCodexKey(c *gin.Context) {
 	type codexKeyPatch struct {
 		APIKey         *string              `json:"api-key"`
 		Prefix         *string              `json:"prefix"`
+		Weight         *int                 `json:"weight"`
 		BaseURL        *string              `json:"base-url"`
 		ProxyURL       *string              `json:"proxy-url"`
 		Models         *[]config.CodexModel `json:"models"`
@@ -879,6 +934,9 @@ func (h *Handler) PatchCodexKey(c *gin.Context) {
 	if body.Value.Prefix != nil {
 		entry.Prefix = strings.TrimSpace(*body.Value.Prefix)
 	}
+	if body.Value.Weight != nil {
+		entry.Weight = *body.Value.Weight
+	}
 	if body.Value.BaseURL != nil {
 		trimmed := strings.TrimSpace(*body.Value.BaseURL)
 		if trimmed == "" {
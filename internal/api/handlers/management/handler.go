@@ -40,6 +40,9 @@ type Handler struct {
 	allowRemoteOverride bool
 	envSecret           string
 	logDir              string
+	replayer            RequestReplayer
+	// registerModels rebinds a credential's models in the global registry, e.g. after its prefix changed.
+	registerModels func(*coreauth.Auth)
 }
 
 // NewHandler creates a new management handler instance.
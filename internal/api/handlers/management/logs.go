@@ -240,14 +240,24 @@ func (h *Handler) GetRequestLogByID(c *gin.Context) {
 		return
 	}
 
+	fullPath, matchedFile, status, err := findRequestLogFile(dir, requestID)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.FileAttachment(fullPath, matchedFile)
+}
+
+// findRequestLogFile resolves the request log of requestID inside dir. On failure it returns the
+// HTTP status to report together with the error.
+func findRequestLogFile(dir, requestID string) (string, string, int, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "log directory not found"})
-			return
+			return "", "", http.StatusNotFound, fmt.Errorf("log directory not found")
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list log directory: %v", err)})
-		return
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to list log directory: %v", err)
 	}
 
 	suffix := "-" + requestID + ".log"
@@ -264,37 +274,30 @@ func (h *Handler) GetRequestLogByID(c *gin.Context) {
 	}
 
 	if matchedFile == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "log file not found for the given request ID"})
-		return
+		return "", "", http.StatusNotFound, fmt.Errorf("log file not found for the given request ID")
 	}
 
 	dirAbs, errAbs := filepath.Abs(dir)
 	if errAbs != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to resolve log directory: %v", errAbs)})
-		return
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to resolve log directory: %v", errAbs)
 	}
 	fullPath := filepath.Clean(filepath.Join(dirAbs, matchedFile))
 	prefix := dirAbs + string(os.PathSeparator)
 	if !strings.HasPrefix(fullPath, prefix) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log file path"})
-		return
+		return "", "", http.StatusBadRequest, fmt.Errorf("invalid log file path")
 	}
 
 	info, errStat := os.Stat(fullPath)
 	if errStat != nil {
 		if os.IsNotExist(errStat) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "log file not found"})
-			return
+			return "", "", http.StatusNotFound, fmt.Errorf("log file not found")
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read log file: %v", errStat)})
-		return
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to read log file: %v", errStat)
 	}
 	if info.IsDir() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log file"})
-		return
+		return "", "", http.StatusBadRequest, fmt.Errorf("invalid log file")
 	}
-
-	c.FileAttachment(fullPath, matchedFile)
+	return fullPath, matchedFile, http.StatusOK, nil
 }
 
 // DownloadRequestErrorLog downloads a specific error request log file by name.
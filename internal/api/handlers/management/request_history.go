@@ -0,0 +1,98 @@
+package management
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+const (
+	defaultRequestHistoryPageSize = 50
+	maxRequestHistoryPageSize     = 500
+)
+
+// GetRequestHistory pages through the structured request history (requests.jsonl), newest first.
+// Filters: from/to (unix seconds or RFC3339), status ("429" or "5xx"), model, api-key (raw key or
+// api_key_hash) and request-id. Pagination: page (1-based) and page-size; has-more reports a next page.
+func (h *Handler) GetRequestHistory(c *gin.Context) {
+	if h == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "handler unavailable"})
+		return
+	}
+	dir := h.logDirectory()
+	if strings.TrimSpace(dir) == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "log directory not configured"})
+		return
+	}
+
+	from, err := parseHistoryTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid from: %v", err)})
+		return
+	}
+	to, err := parseHistoryTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid to: %v", err)})
+		return
+	}
+	page := 1
+	if raw := strings.TrimSpace(c.Query("page")); raw != "" {
+		if page, err = parseLimit(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid page: %v", err)})
+			return
+		}
+	}
+	pageSize := defaultRequestHistoryPageSize
+	if raw := strings.TrimSpace(c.Query("page-size")); raw != "" {
+		if pageSize, err = parseLimit(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid page-size: %v", err)})
+			return
+		}
+	}
+	pageSize = min(pageSize, maxRequestHistoryPageSize)
+
+	records, more, err := logging.QueryRequestHistory(dir, logging.RequestHistoryQuery{
+		From:      from,
+		To:        to,
+		RequestID: strings.TrimSpace(c.Query("request-id")),
+		Status:    c.Query("status"),
+		Model:     strings.TrimSpace(c.Query("model")),
+		APIKey:    strings.TrimSpace(c.Query("api-key")),
+		Offset:    (page - 1) * pageSize,
+		Limit:     pageSize,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logging.ErrInvalidStatusFilter) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if records == nil {
+		records = []logging.RequestRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"records":   records,
+		"has-more":  more,
+		"page":      page,
+		"page-size": pageSize,
+	})
+}
+
+func parseHistoryTime(raw string) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,274 @@
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ReplayRequest is an inbound API request re-executed in-process.
+type ReplayRequest struct {
+	Method string
+	// URL is the request path with its query string.
+	URL     string
+	Headers http.Header
+	Body    []byte
+	// Provider and AuthID pin execution to a provider and/or a single credential.
+	Provider string
+	AuthID   string
+	// APIKey is the client key the request runs as, so key policies, usage attribution and
+	// key-scoped data such as stored responses apply as they did originally.
+	APIKey string
+}
+
+// ReplayResult is the client-facing response of a replayed request.
+type ReplayResult struct {
+	Status    int
+	Headers   http.Header
+	Body      []byte
+	LatencyMs int64
+	// Provider and AuthIndex identify the credential that served the request, when known.
+	Provider  string
+	AuthIndex string
+}
+
+// RequestReplayer re-executes a request through the API handlers.
+type RequestReplayer func(ctx context.Context, req ReplayRequest) (ReplayResult, error)
+
+// replayRequestBody is the payload of POST /request-replay.
+type replayRequestBody struct {
+	RequestID string `json:"request_id"`
+	AuthIndex string `json:"auth_index"`
+	Model     string `json:"model"`
+	Provider  string `json:"provider"`
+	// APIKey names the client key to replay as when the logged, masked key is ambiguous.
+	APIKey string `json:"api_key"`
+}
+
+// replaySkippedHeaders are not carried over from the logged request: credentials were masked
+// when logged, and transport headers are recomputed.
+var replaySkippedHeaders = map[string]struct{}{
+	"authorization":     {},
+	"x-api-key":         {},
+	"x-goog-api-key":    {},
+	"cookie":            {},
+	"content-length":    {},
+	"accept-encoding":   {},
+	"connection":        {},
+	"host":              {},
+	"transfer-encoding": {},
+}
+
+// SetRequestReplayer installs the function used to re-execute logged requests.
+func (h *Handler) SetRequestReplayer(replayer RequestReplayer) { h.replayer = replayer }
+
+// ReplayLoggedRequest re-executes the request recorded in a request log (see GetRequestLogByID)
+// with its original inbound body and source format, optionally pinned to a credential
+// (auth_index), a provider or a different model, and returns the replayed response next to the
+// original one. Request logs must be enabled for the original request to have been captured;
+// values masked or redacted in the log are replayed as logged. The request runs as the client
+// key it was sent with: the masked key in the log is matched against the configured api-keys,
+// or api_key names it. A replay whose key cannot be determined is rejected.
+func (h *Handler) ReplayLoggedRequest(c *gin.Context) {
+	if h == nil || h.replayer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "request replay unavailable"})
+		return
+	}
+	var body replayRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	requestID := strings.TrimSpace(body.RequestID)
+	if requestID == "" || strings.ContainsAny(requestID, "/\\") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request_id"})
+		return
+	}
+	dir := h.logDirectory()
+	if strings.TrimSpace(dir) == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "log directory not configured"})
+		return
+	}
+	fullPath, _, status, err := findRequestLogFile(dir, requestID)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read log file: %v", err)})
+		return
+	}
+	logged, err := logging.ParseRequestLog(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := ReplayRequest{
+		Method:   logged.Method,
+		URL:      replayURL(logged.URL),
+		Headers:  make(http.Header),
+		Body:     logged.Body,
+		Provider: strings.TrimSpace(body.Provider),
+	}
+	apiKey, errKey := h.replayAPIKey(strings.TrimSpace(body.APIKey), logged.Headers, logged.URL)
+	if errKey != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errKey.Error()})
+		return
+	}
+	req.APIKey = apiKey
+	for key, values := range logged.Headers {
+		if _, skip := replaySkippedHeaders[strings.ToLower(key)]; skip {
+			continue
+		}
+		for _, value := range values {
+			req.Headers.Add(key, value)
+		}
+	}
+	if authIndex := strings.TrimSpace(body.AuthIndex); authIndex != "" {
+		auth := h.authByIndex(authIndex)
+		if auth == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "auth not found for auth_index"})
+			return
+		}
+		req.AuthID = auth.ID
+		if req.Provider == "" {
+			req.Provider = auth.Provider
+		}
+	}
+	if model := strings.TrimSpace(body.Model); model != "" {
+		if req.URL, req.Body, err = overrideReplayModel(req.URL, req.Body, model); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.replayer(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("replay failed: %v", err)})
+		return
+	}
+
+	original := gin.H{"available": logged.HasResponse}
+	if logged.HasResponse {
+		original["status"] = logged.Status
+		original["body"] = string(logged.ResponseBody)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"request_id": requestID,
+		"method":     req.Method,
+		"url":        req.URL,
+		"overrides": gin.H{
+			"model":      strings.TrimSpace(body.Model),
+			"provider":   req.Provider,
+			"auth_index": strings.TrimSpace(body.AuthIndex),
+		},
+		"original": original,
+		"replay": gin.H{
+			"status":       result.Status,
+			"body":         string(result.Body),
+			"latency_ms":   result.LatencyMs,
+			"provider":     result.Provider,
+			"auth_index":   result.AuthIndex,
+			"served_model": result.Headers.Get(coreauth.ServedModelHeader),
+		},
+	})
+}
+
+// replayAPIKey resolves the client key a logged request was sent with. An explicit key must be
+// one of the configured api-keys; otherwise the masked credentials in the logged headers and
+// query must match exactly one of them. Without configured keys the proxy does not authenticate
+// clients, so the replay runs without a key as the original did.
+func (h *Handler) replayAPIKey(explicit string, headers http.Header, rawURL string) (string, error) {
+	var keys []string
+	if h.cfg != nil {
+		keys = h.cfg.APIKeys
+	}
+	if explicit != "" {
+		for _, key := range keys {
+			if key == explicit {
+				return key, nil
+			}
+		}
+		return "", fmt.Errorf("api_key is not a configured client key")
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	var masked []string
+	for name, values := range headers {
+		switch strings.ToLower(name) {
+		case "authorization":
+			for _, value := range values {
+				if _, token, ok := strings.Cut(strings.TrimSpace(value), " "); ok {
+					masked = append(masked, strings.TrimSpace(token))
+				}
+			}
+		case "x-api-key", "x-goog-api-key":
+			masked = append(masked, values...)
+		}
+	}
+	if _, query, ok := strings.Cut(rawURL, "?"); ok {
+		if values, errParse := url.ParseQuery(query); errParse == nil {
+			masked = append(masked, values["key"]...)
+		}
+	}
+	match := ""
+	for _, key := range keys {
+		hidden := util.HideAPIKey(key)
+		for _, value := range masked {
+			if value != hidden || key == match {
+				continue
+			}
+			if match != "" {
+				return "", fmt.Errorf("the logged client key matches several configured keys; pass api_key")
+			}
+			match = key
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("the logged request's client key is unknown; pass api_key")
+	}
+	return match, nil
+}
+
+// replayURL drops credentials from the logged URL; they were masked when logged.
+func replayURL(raw string) string {
+	path, query, _ := strings.Cut(raw, "?")
+	if query = util.StripSensitiveQuery(query); query != "" {
+		return path + "?" + query
+	}
+	return path
+}
+
+// overrideReplayModel swaps the model in the request body, or in the path for Gemini-style
+// routes such as /v1beta/models/{model}:generateContent.
+func overrideReplayModel(url string, body []byte, model string) (string, []byte, error) {
+	if gjson.GetBytes(body, "model").Exists() {
+		updated, err := sjson.SetBytes(body, "model", model)
+		if err != nil {
+			return url, body, fmt.Errorf("failed to override model: %v", err)
+		}
+		return url, updated, nil
+	}
+	if idx := strings.Index(url, "/models/"); idx >= 0 {
+		start := idx + len("/models/")
+		end := strings.IndexAny(url[start:], ":?")
+		if end < 0 {
+			end = len(url) - start
+		}
+		return url[:start] + model + url[start+end:], body, nil
+	}
+	return url, body, fmt.Errorf("the logged request does not name a model to override")
+}
@@ -0,0 +1,37 @@
+package management
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+func TestReplayAPIKey_ResolvesLoggedClientKey(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.APIKeys = []string{"sk-tenant-one-0001", "sk-tenant-two-0002", "sk-tenant-six-0001"}
+	h := &Handler{cfg: cfg}
+
+	headers := http.Header{"Authorization": {"Bearer " + util.HideAPIKey("sk-tenant-two-0002")}}
+	if got, err := h.replayAPIKey("", headers, "/v1/chat/completions"); err != nil || got != "sk-tenant-two-0002" {
+		t.Fatalf("replayAPIKey(bearer) = %q, %v", got, err)
+	}
+	if got, err := h.replayAPIKey("", http.Header{}, "/v1beta/models/m:generateContent?key="+util.HideAPIKey("sk-tenant-one-0001")); err == nil {
+		t.Fatalf("ambiguous masked key resolved to %q", got)
+	}
+	if got, err := h.replayAPIKey("sk-tenant-one-0001", http.Header{}, "/v1/chat/completions"); err != nil || got != "sk-tenant-one-0001" {
+		t.Fatalf("replayAPIKey(explicit) = %q, %v", got, err)
+	}
+	if _, err := h.replayAPIKey("sk-unknown", headers, "/v1/chat/completions"); err == nil {
+		t.Fatal("unconfigured api_key was accepted")
+	}
+	if _, err := h.replayAPIKey("", http.Header{}, "/v1/chat/completions"); err == nil {
+		t.Fatal("replay without a resolvable client key was accepted")
+	}
+
+	open := &Handler{cfg: &config.Config{}}
+	if got, err := open.replayAPIKey("", http.Header{}, "/v1/chat/completions"); err != nil || got != "" {
+		t.Fatalf("replayAPIKey(no keys) = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,67 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
+)
+
+// requestStreamHeartbeat keeps idle connections open through proxies.
+const requestStreamHeartbeat = 15 * time.Second
+
+// GetRequestStream streams live request events as Server-Sent Events until the client disconnects.
+// Each event is sent with its type as the SSE event name and the JSON-encoded event as data.
+// Optional filters: model and api-key (raw key or api_key_hash). Keys in events are masked.
+func (h *Handler) GetRequestStream(c *gin.Context) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming not supported"})
+		return
+	}
+
+	stream, unsubscribe := events.Default().Subscribe(events.Filter{
+		Model:  strings.TrimSpace(c.Query("model")),
+		APIKey: strings.TrimSpace(c.Query("api-key")),
+	}, events.DefaultBufferSize)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	_, _ = fmt.Fprint(c.Writer, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(requestStreamHeartbeat)
+	defer heartbeat.Stop()
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, okEvent := <-stream:
+			if !okEvent {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+)
+
+// GetResponseCache reports the response cache settings, counters and cached entries.
+func (h *Handler) GetResponseCache(c *gin.Context) {
+	responseCache := cache.DefaultResponseCache()
+	c.JSON(http.StatusOK, gin.H{
+		"stats":   responseCache.Stats(),
+		"entries": responseCache.Entries(),
+	})
+}
+
+// GetResponseCacheEntry returns the cached body for the entry given by the "key" query parameter.
+func (h *Handler) GetResponseCacheEntry(c *gin.Context) {
+	key := strings.TrimSpace(c.Query("key"))
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+	entry, ok := cache.DefaultResponseCache().Entry(key)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+	out := gin.H{"entry": entry}
+	if entry.Stream {
+		chunks := make([]string, 0, len(entry.Chunks))
+		for _, chunk := range entry.Chunks {
+			chunks = append(chunks, string(chunk))
+		}
+		out["chunks"] = chunks
+	} else {
+		out["payload"] = string(entry.Payload)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteResponseCache removes one entry (?key=) or flushes the whole cache (?all=true).
+func (h *Handler) DeleteResponseCache(c *gin.Context) {
+	responseCache := cache.DefaultResponseCache()
+	if all := c.Query("all"); all == "true" || all == "1" || all == "*" {
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "removed": responseCache.Flush()})
+		return
+	}
+	key := strings.TrimSpace(c.Query("key"))
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key or all=true is required"})
+		return
+	}
+	if !responseCache.Delete(key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "removed": 1})
+}
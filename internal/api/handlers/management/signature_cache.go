@@ -0,0 +1,69 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+)
+
+// GetSignatureCache summarizes the thinking signature cache backend and its sessions.
+func (h *Handler) GetSignatureCache(c *gin.Context) {
+	store := cache.ActiveSignatureStore()
+	ttl, maxEntries := cache.SignatureCacheLimits()
+	sessions := store.Sessions()
+	if sessions == nil {
+		sessions = []cache.SignatureSession{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"backend":                 store.Name(),
+		"ttl-seconds":             int(ttl.Seconds()),
+		"max-entries-per-session": maxEntries,
+		"sessions":                sessions,
+	})
+}
+
+// GetSignatureCacheSession lists the signatures cached for the session given by the "id" query parameter.
+func (h *Handler) GetSignatureCacheSession(c *gin.Context) {
+	sessionID := strings.TrimSpace(c.Query("id"))
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	entries := cache.ActiveSignatureStore().Entries(sessionID)
+	if len(entries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	for i := range entries {
+		entries[i].Signature = truncateSignature(entries[i].Signature)
+	}
+	c.JSON(http.StatusOK, gin.H{"session_id": sessionID, "entries": entries})
+}
+
+// truncateSignature shortens a signature for display; full signatures are replayable
+// credentials for the thinking blocks they sign and are never returned.
+func truncateSignature(signature string) string {
+	const keep = 12
+	if len(signature) <= keep {
+		return signature
+	}
+	return signature[:keep] + "..."
+}
+
+// DeleteSignatureCache purges one session (?id=) or every session (?all=true).
+func (h *Handler) DeleteSignatureCache(c *gin.Context) {
+	if all := c.Query("all"); all == "true" || all == "1" || all == "*" {
+		cache.ClearSignatureCache("")
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		return
+	}
+	sessionID := strings.TrimSpace(c.Query("id"))
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id or all=true is required"})
+		return
+	}
+	cache.ClearSignatureCache(sessionID)
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "session_id": sessionID})
+}
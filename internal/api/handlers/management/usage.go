@@ -3,6 +3,8 @@ package management
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -77,3 +79,85 @@ func (h *Handler) ImportUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+// GetUsageHistory aggregates persisted usage records into time buckets.
+// Query parameters: from, to (RFC3339, YYYY-MM-DD or unix seconds; default the last 24 hours),
+// bucket (hour, day, or empty for totals), group_by (api_key, auth_index, provider, model)
+// and the exact-match filters api_key, auth_index, provider and model. Client keys are
+// stored and reported masked; the api_key filter takes the full key and matches its hash.
+func (h *Handler) GetUsageHistory(c *gin.Context) {
+	store := usage.HistoryStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "usage store not configured"})
+		return
+	}
+	now := time.Now().UTC()
+	from, errFrom := parseUsageTime(c.Query("from"), now.Add(-24*time.Hour))
+	if errFrom != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	to, errTo := parseUsageTime(c.Query("to"), now)
+	if errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+		return
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	query := usage.HistoryQuery{
+		From:      from,
+		To:        to,
+		APIKey:    strings.TrimSpace(c.Query("api_key")),
+		AuthIndex: strings.TrimSpace(c.Query("auth_index")),
+		Provider:  strings.TrimSpace(c.Query("provider")),
+		Model:     strings.TrimSpace(c.Query("model")),
+		Bucket:    strings.ToLower(strings.TrimSpace(c.Query("bucket"))),
+		GroupBy:   strings.ToLower(strings.TrimSpace(c.Query("group_by"))),
+	}
+	switch query.Bucket {
+	case usage.BucketNone, usage.BucketHour, usage.BucketDay:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be hour or day"})
+		return
+	}
+	switch query.GroupBy {
+	case "", "api_key", "auth_index", "provider", "model":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be api_key, auth_index, provider or model"})
+		return
+	}
+	buckets, err := store.History(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"backend":  store.Name(),
+		"from":     from,
+		"to":       to,
+		"bucket":   query.Bucket,
+		"group_by": query.GroupBy,
+		"buckets":  buckets,
+	})
+}
+
+// parseUsageTime accepts RFC3339 timestamps, YYYY-MM-DD dates or unix seconds.
+func parseUsageTime(raw string, fallback time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
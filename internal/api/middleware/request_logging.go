@@ -8,10 +8,14 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 )
 
 // RequestLoggingMiddleware creates a Gin middleware that logs HTTP requests and responses.
@@ -36,6 +40,8 @@ func RequestLoggingMiddleware(logger logging.RequestLogger) gin.HandlerFunc {
 			return
 		}
 
+		start := time.Now()
+
 		// Capture request information
 		requestInfo, err := captureRequestInfo(c)
 		if err != nil {
@@ -50,6 +56,14 @@ func RequestLoggingMiddleware(logger logging.RequestLogger) gin.HandlerFunc {
 		if !logger.IsEnabled() {
 			wrapper.logOnErrorOnly = true
 		}
+		recorder, _ := logger.(logging.RequestRecorder)
+		if recorder != nil && !recorder.RecordsEnabled() {
+			recorder = nil
+		}
+		if recorder != nil {
+			wrapper.recordLimit = recorder.RecordBodyLimit()
+			usage.TrackRequestRecords(c)
+		}
 		c.Writer = wrapper
 
 		// Process the request
@@ -60,7 +74,52 @@ func RequestLoggingMiddleware(logger logging.RequestLogger) gin.HandlerFunc {
 			// Log error but don't interrupt the response
 			// In a real implementation, you might want to use a proper logger here
 		}
+
+		if recorder != nil {
+			if err = recorder.LogRecord(buildRequestRecord(c, wrapper, start)); err != nil {
+				log.Warnf("failed to write request history record: %v", err)
+			}
+		}
+	}
+}
+
+// buildRequestRecord assembles the structured history record for a finished request from the
+// captured request, the response status and the usage records published while serving it.
+func buildRequestRecord(c *gin.Context, w *ResponseWriterWrapper, start time.Time) logging.RequestRecord {
+	status := w.statusCode
+	if status == 0 {
+		status = w.ResponseWriter.Status()
+	}
+	record := logging.RequestRecord{
+		Timestamp: start,
+		Status:    status,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Stream:    w.isStreaming,
+		APIKey:    c.GetString("apiKey"),
+		Response:  w.recordBody.String(),
+	}
+	if info := w.requestInfo; info != nil {
+		record.RequestID = info.RequestID
+		record.Method = info.Method
+		record.URL = info.URL
+		record.Request = string(info.Body)
+		record.Model = gjson.GetBytes(info.Body, "model").String()
+	}
+	for _, usageRecord := range usage.RequestRecords(c) {
+		// The last record belongs to the attempt that served the request.
+		record.Provider = usageRecord.Provider
+		record.AuthIndex = usageRecord.AuthIndex
+		record.Cache = usageRecord.Cache
+		if usageRecord.Model != "" {
+			record.Model = usageRecord.Model
+		}
+		record.Tokens.Input += usageRecord.Detail.InputTokens
+		record.Tokens.Output += usageRecord.Detail.OutputTokens
+		record.Tokens.Reasoning += usageRecord.Detail.ReasoningTokens
+		record.Tokens.Cached += usageRecord.Detail.CachedTokens
+		record.Tokens.Total += usageRecord.Detail.TotalTokens
 	}
+	return record
 }
 
 // captureRequestInfo extracts relevant information from the incoming HTTP request.
@@ -36,6 +36,8 @@ type ResponseWriterWrapper struct {
 	statusCode     int                        // statusCode stores the HTTP status code of the response.
 	headers        map[string][]string        // headers stores the response headers.
 	logOnErrorOnly bool                       // logOnErrorOnly enables logging only when an error response is detected.
+	recordLimit    int                        // recordLimit caps the response bytes kept for the request history; 0 disables capture.
+	recordBody     bytes.Buffer               // recordBody holds the start of the response for the request history.
 }
 
 // NewResponseWriterWrapper creates and initializes a new ResponseWriterWrapper.
@@ -70,6 +72,7 @@ func (w *ResponseWriterWrapper) Write(data []byte) (int, error) {
 
 	// CRITICAL: Write to client first (zero latency)
 	n, err := w.ResponseWriter.Write(data)
+	w.captureRecordBody(data)
 
 	// THEN: Handle logging based on response type
 	if w.isStreaming && w.chunkChannel != nil {
@@ -106,6 +109,14 @@ func (w *ResponseWriterWrapper) shouldBufferResponseBody() bool {
 	return status >= http.StatusBadRequest
 }
 
+// captureRecordBody keeps the start of the response for the request history. One byte beyond
+// the limit is kept so the history can tell the body was truncated.
+func (w *ResponseWriterWrapper) captureRecordBody(data []byte) {
+	if remaining := w.recordLimit + 1 - w.recordBody.Len(); w.recordLimit > 0 && remaining > 0 {
+		w.recordBody.Write(data[:min(len(data), remaining)])
+	}
+}
+
 // WriteString wraps the underlying ResponseWriter's WriteString method to capture response data.
 // Some handlers (and fmt/io helpers) write via io.StringWriter; without this override, those writes
 // bypass Write() and would be missing from request logs.
@@ -114,6 +125,9 @@ func (w *ResponseWriterWrapper) WriteString(data string) (int, error) {
 
 	// CRITICAL: Write to client first (zero latency)
 	n, err := w.ResponseWriter.WriteString(data)
+	if w.recordLimit > 0 {
+		w.captureRecordBody([]byte(data))
+	}
 
 	// THEN: Capture for logging
 	if w.isStreaming && w.chunkChannel != nil {
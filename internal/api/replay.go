@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	managementHandlers "github.com/router-for-me/CLIProxyAPI/v6/internal/api/handlers/management"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// replayContextKey marks requests issued in-process by replayRequest. The marker only exists on
+// contexts created by the server, so clients cannot use it to skip authentication.
+type replayContextKey struct{}
+
+// replayState carries a replay's execution target into the handler chain and the usage records
+// of the replayed request back out.
+type replayState struct {
+	target  auth.ExecutionTarget
+	apiKey  string
+	records []coreusage.Record
+}
+
+// replayRequest re-executes req through the server's routes and handlers as req.APIKey, bypassing
+// client authentication and the response cache.
+func (s *Server) replayRequest(ctx context.Context, req managementHandlers.ReplayRequest) (managementHandlers.ReplayResult, error) {
+	state := &replayState{target: auth.ExecutionTarget{Provider: req.Provider, AuthID: req.AuthID}, apiKey: req.APIKey}
+	httpReq, err := http.NewRequestWithContext(context.WithValue(ctx, replayContextKey{}, state), req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return managementHandlers.ReplayResult{}, fmt.Errorf("build request: %w", err)
+	}
+	for key, values := range req.Headers {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	if httpReq.Header.Get("Content-Type") == "" {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set(handlers.ResponseCacheHeader, "bypass")
+
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	s.engine.ServeHTTP(recorder, httpReq)
+	result := managementHandlers.ReplayResult{
+		Status:    recorder.Code,
+		Headers:   recorder.Header().Clone(),
+		Body:      recorder.Body.Bytes(),
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if n := len(state.records); n > 0 {
+		result.Provider = state.records[n-1].Provider
+		result.AuthIndex = state.records[n-1].AuthIndex
+	}
+	return result, nil
+}
+
+// serveReplay runs the rest of the chain for a replayed request and reports whether c was one.
+func serveReplay(c *gin.Context) bool {
+	state, ok := c.Request.Context().Value(replayContextKey{}).(*replayState)
+	if !ok || state == nil {
+		return false
+	}
+	if state.apiKey != "" {
+		c.Set("apiKey", state.apiKey)
+	}
+	auth.SetExecutionTarget(c, state.target)
+	coreusage.TrackRequestRecords(c)
+	c.Next()
+	state.records = coreusage.RequestRecords(c)
+	return true
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,13 +20,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
 	managementHandlers "github.com/router-for-me/CLIProxyAPI/v6/internal/api/handlers/management"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -169,6 +174,9 @@ type Server struct {
 	keepAliveOnTimeout func()
 	keepAliveHeartbeat chan struct{}
 	keepAliveStop      chan struct{}
+
+	// metricsServer serves /metrics on metrics.listen when configured.
+	metricsServer *http.Server
 }
 
 // NewServer creates and initializes a new API server instance.
@@ -202,6 +210,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	// Add middleware
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
+	engine.Use(tracing.GinMiddleware())
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
@@ -219,6 +228,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 			if setter, ok := requestLogger.(interface{ SetEnabled(bool) }); ok {
 				toggle = setter.SetEnabled
 			}
+			applyRequestLogOptions(requestLogger, cfg)
 		}
 	}
 
@@ -254,6 +264,13 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	policy.Default().SetPolicies(cfg.APIKeyPolicies)
+	applyResponseCacheConfig(cfg)
+	applyTokenCountCacheConfig(cfg)
+	metrics.SetEnabled(cfg.Metrics.Enable)
+	if errTracing := tracing.Configure(cfg.Tracing); errTracing != nil {
+		log.Errorf("failed to configure tracing: %v", errTracing)
+	}
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	if optionState.localPassword != "" {
@@ -264,6 +281,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		logDir = filepath.Join(base, "logs")
 	}
 	s.mgmt.SetLogDirectory(logDir)
+	s.mgmt.SetRequestReplayer(s.replayRequest)
 	s.localPassword = optionState.localPassword
 
 	// Setup routes
@@ -302,6 +320,11 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
 		Handler: engine,
 	}
+	if listen := strings.TrimSpace(cfg.Metrics.Listen); listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.standaloneMetricsHandler())
+		s.metricsServer = &http.Server{Addr: listen, Handler: mux}
+	}
 
 	return s
 }
@@ -310,6 +333,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 // It defines the endpoints and associates them with their respective handlers.
 func (s *Server) setupRoutes() {
 	s.engine.GET("/management.html", s.serveManagementControlPanel)
+	s.engine.GET("/metrics", s.metricsAvailabilityMiddleware(), s.mgmt.Middleware(), gin.WrapH(metrics.Handler()))
 	openaiHandlers := openai.NewOpenAIAPIHandler(s.handlers)
 	geminiHandlers := gemini.NewGeminiAPIHandler(s.handlers)
 	geminiCLIHandlers := gemini.NewGeminiCLIAPIHandler(s.handlers)
@@ -326,6 +350,10 @@ func (s *Server) setupRoutes() {
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
+		v1.GET("/responses/:id", openaiResponsesHandlers.GetResponse)
+		v1.DELETE("/responses/:id", openaiResponsesHandlers.DeleteResponse)
+		v1.GET("/responses/:id/input_items", openaiResponsesHandlers.ListInputItems)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
 	}
 
 	// Gemini compatible API routes
@@ -478,6 +506,7 @@ func (s *Server) registerManagementRoutes() {
 	{
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
 		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
+		mgmt.GET("/usage/history", s.mgmt.GetUsageHistory)
 		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigYAML)
@@ -530,6 +559,15 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/request-error-logs", s.mgmt.GetRequestErrorLogs)
 		mgmt.GET("/request-error-logs/:name", s.mgmt.DownloadRequestErrorLog)
 		mgmt.GET("/request-log-by-id/:id", s.mgmt.GetRequestLogByID)
+		mgmt.GET("/request-history", s.mgmt.GetRequestHistory)
+		mgmt.GET("/request-stream", s.mgmt.GetRequestStream)
+		mgmt.POST("/request-replay", s.mgmt.ReplayLoggedRequest)
+		mgmt.GET("/signature-cache", s.mgmt.GetSignatureCache)
+		mgmt.GET("/signature-cache/session", s.mgmt.GetSignatureCacheSession)
+		mgmt.DELETE("/signature-cache", s.mgmt.DeleteSignatureCache)
+		mgmt.GET("/response-cache", s.mgmt.GetResponseCache)
+		mgmt.GET("/response-cache/entry", s.mgmt.GetResponseCacheEntry)
+		mgmt.DELETE("/response-cache", s.mgmt.DeleteResponseCache)
 		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
 		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
 		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
@@ -575,6 +613,12 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/routing/strategy", s.mgmt.GetRoutingStrategy)
 		mgmt.PUT("/routing/strategy", s.mgmt.PutRoutingStrategy)
 		mgmt.PATCH("/routing/strategy", s.mgmt.PutRoutingStrategy)
+		mgmt.GET("/routing/session-affinity", s.mgmt.GetSessionAffinity)
+		mgmt.DELETE("/routing/session-affinity", s.mgmt.DeleteSessionAffinity)
+
+		mgmt.GET("/api-key-policies", s.mgmt.GetAPIKeyPolicies)
+		mgmt.PUT("/api-key-policies", s.mgmt.PutAPIKeyPolicies)
+		mgmt.GET("/api-key-policies/usage", s.mgmt.GetAPIKeyPolicyUsage)
 
 		mgmt.GET("/claude-api-key", s.mgmt.GetClaudeKeys)
 		mgmt.PUT("/claude-api-key", s.mgmt.PutClaudeKeys)
@@ -611,6 +655,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/auth-files/download", s.mgmt.DownloadAuthFile)
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
+		mgmt.PATCH("/auth-files", s.mgmt.PatchAuthFile)
 		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
 
 		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
@@ -635,6 +680,31 @@ func (s *Server) managementAvailabilityMiddleware() gin.HandlerFunc {
 	}
 }
 
+// metricsAvailabilityMiddleware hides /metrics on the main port unless metrics are enabled
+// without a dedicated listen address.
+func (s *Server) metricsAvailabilityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := s.cfg
+		if cfg == nil || !cfg.Metrics.Enable || s.metricsServer != nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Next()
+	}
+}
+
+// standaloneMetricsHandler serves metrics on the dedicated listener while metrics are enabled.
+func (s *Server) standaloneMetricsHandler() http.Handler {
+	handler := metrics.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !metrics.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	cfg := s.cfg
 	if cfg == nil || cfg.RemoteManagement.DisableControlPanel {
@@ -769,6 +839,15 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to start HTTP server: server not initialized")
 	}
 
+	if s.metricsServer != nil {
+		go func(srv *http.Server) {
+			log.Infof("Starting metrics server on %s", srv.Addr)
+			if errServe := srv.ListenAndServe(); errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+				log.Errorf("metrics server stopped: %v", errServe)
+			}
+		}(s.metricsServer)
+	}
+
 	useTLS := s.cfg != nil && s.cfg.TLS.Enable
 	if useTLS {
 		cert := strings.TrimSpace(s.cfg.TLS.Cert)
@@ -809,10 +888,17 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			log.Warnf("failed to shutdown metrics server: %v", err)
+		}
+	}
+
 	// Shutdown the HTTP server.
 	if err := s.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
 	}
+	tracing.Shutdown()
 
 	log.Debug("API server stopped")
 	return nil
@@ -847,6 +933,48 @@ func (s *Server) applyAccessConfig(oldCfg, newCfg *config.Config) {
 	}
 }
 
+// applyRequestLogOptions installs the configured redaction rules and request history settings
+// on loggers that support them.
+func applyRequestLogOptions(requestLogger logging.RequestLogger, cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	if setter, ok := requestLogger.(interface {
+		SetRedaction(config.RequestLogRedactionConfig) error
+	}); ok {
+		if err := setter.SetRedaction(cfg.RequestLogRedaction); err != nil {
+			log.Errorf("failed to apply request log redaction: %v", err)
+		}
+	}
+	if setter, ok := requestLogger.(interface {
+		SetHistory(config.RequestLogJSONLConfig)
+	}); ok {
+		setter.SetHistory(cfg.RequestLogJSONL)
+	}
+}
+
+// applyResponseCacheConfig pushes the response cache settings to the shared cache.
+func applyResponseCacheConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{
+		Enabled:    cfg.ResponseCache.Enabled,
+		TTL:        time.Duration(cfg.ResponseCache.TTLSeconds) * time.Second,
+		MaxEntries: cfg.ResponseCache.MaxEntries,
+		MaxBytes:   cfg.ResponseCache.MaxBytes,
+		Force:      cfg.ResponseCache.Force,
+	})
+}
+
+// applyTokenCountCacheConfig pushes the count-tokens cache settings to the shared cache.
+func applyTokenCountCacheConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	cache.DefaultTokenCountCache().SetOptions(time.Duration(cfg.CountTokens.CacheTTLSeconds)*time.Second, cfg.CountTokens.CacheMaxEntries)
+}
+
 // UpdateClients updates the server's client list and configuration.
 // This method is called when the configuration or authentication tokens change.
 //
@@ -878,6 +1006,10 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if s.requestLogger != nil && (oldCfg == nil || !reflect.DeepEqual(oldCfg.RequestLogRedaction, cfg.RequestLogRedaction) || oldCfg.RequestLogJSONL != cfg.RequestLogJSONL) {
+		applyRequestLogOptions(s.requestLogger, cfg)
+	}
+
 	if oldCfg == nil || oldCfg.LoggingToFile != cfg.LoggingToFile || oldCfg.LogsMaxTotalSizeMB != cfg.LogsMaxTotalSizeMB {
 		if err := logging.ConfigureLogOutput(cfg); err != nil {
 			log.Errorf("failed to reconfigure log output: %v", err)
@@ -904,6 +1036,22 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if oldCfg == nil || oldCfg.Metrics.Enable != cfg.Metrics.Enable {
+		metrics.SetEnabled(cfg.Metrics.Enable)
+		if oldCfg != nil {
+			log.Debugf("metrics.enable updated from %t to %t", oldCfg.Metrics.Enable, cfg.Metrics.Enable)
+		} else {
+			log.Debugf("metrics.enable toggled to %t", cfg.Metrics.Enable)
+		}
+	}
+	if oldCfg != nil && strings.TrimSpace(oldCfg.Metrics.Listen) != strings.TrimSpace(cfg.Metrics.Listen) {
+		log.Warn("metrics.listen changed; restart required for the new address to take effect")
+	}
+
+	if errTracing := tracing.Configure(cfg.Tracing); errTracing != nil {
+		log.Errorf("failed to reconfigure tracing: %v", errTracing)
+	}
+
 	if oldCfg == nil || oldCfg.DisableCooling != cfg.DisableCooling {
 		auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 		if oldCfg != nil {
@@ -915,6 +1063,12 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	if s.handlers != nil && s.handlers.AuthManager != nil {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
+	if oldCfg == nil || oldCfg.SignatureCache.TTLSeconds != cfg.SignatureCache.TTLSeconds || oldCfg.SignatureCache.MaxEntriesPerSession != cfg.SignatureCache.MaxEntriesPerSession {
+		cache.SetSignatureCacheLimits(time.Duration(cfg.SignatureCache.TTLSeconds)*time.Second, cfg.SignatureCache.MaxEntriesPerSession)
+	}
+	policy.Default().SetPolicies(cfg.APIKeyPolicies)
+	applyResponseCacheConfig(cfg)
+	applyTokenCountCacheConfig(cfg)
 
 	// Update log level dynamically when debug flag changes
 	if oldCfg == nil || oldCfg.Debug != cfg.Debug {
@@ -1024,6 +1178,15 @@ func (s *Server) SetWebsocketAuthChangeHandler(fn func(bool, bool)) {
 	s.wsAuthChanged = fn
 }
 
+// SetModelRegistrar installs the function the management API uses to rebind a credential's
+// models in the global registry after editing it.
+func (s *Server) SetModelRegistrar(fn func(*auth.Auth)) {
+	if s == nil || s.mgmt == nil {
+		return
+	}
+	s.mgmt.SetModelRegistrar(fn)
+}
+
 // (management handlers moved to internal/api/handlers/management)
 
 // AuthMiddleware returns a Gin middleware handler that authenticates requests
@@ -1031,6 +1194,9 @@ func (s *Server) SetWebsocketAuthChangeHandler(fn func(bool, bool)) {
 // it allows all requests (legacy behaviour).
 func AuthMiddleware(manager *sdkaccess.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if serveReplay(c) {
+			return
+		}
 		if manager == nil {
 			c.Next()
 			return
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 )
 
 // NormalizeCookie normalizes raw cookie strings for iFlow authentication flows.
@@ -77,7 +79,7 @@ func CheckDuplicateBXAuth(authDir, bxAuth string) (string, error) {
 		}
 
 		filePath := filepath.Join(authDir, name)
-		data, err := os.ReadFile(filePath)
+		data, err := tokencrypt.ReadFile(filePath)
 		if err != nil {
 			continue
 		}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 )
 
 // IFlowTokenStorage persists iFlow OAuth credentials alongside the derived API key.
@@ -31,14 +32,12 @@ func (ts *IFlowTokenStorage) SaveTokenToFile(authFilePath string) error {
 		return fmt.Errorf("iflow token: create directory failed: %w", err)
 	}
 
-	f, err := os.Create(authFilePath)
+	data, err := json.Marshal(ts)
 	if err != nil {
-		return fmt.Errorf("iflow token: create file failed: %w", err)
-	}
-	defer func() { _ = f.Close() }()
-
-	if err = json.NewEncoder(f).Encode(ts); err != nil {
 		return fmt.Errorf("iflow token: encode token failed: %w", err)
 	}
+	if err = tokencrypt.WriteFile(authFilePath, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("iflow token: write file failed: %w", err)
+	}
 	return nil
 }
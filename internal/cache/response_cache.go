@@ -0,0 +1,326 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultResponseCacheTTL is how long cached responses are served when no TTL is configured.
+	DefaultResponseCacheTTL = 10 * time.Minute
+	// DefaultResponseCacheMaxEntries bounds the number of cached responses.
+	DefaultResponseCacheMaxEntries = 1000
+	// DefaultResponseCacheMaxBytes bounds the total size of cached response bodies.
+	DefaultResponseCacheMaxBytes = 64 << 20
+)
+
+// responseCacheIgnoredFields do not influence the generated output and are left out of cache keys.
+var responseCacheIgnoredFields = map[string]struct{}{
+	"stream":           {},
+	"stream_options":   {},
+	"user":             {},
+	"metadata":         {},
+	"prompt_cache_key": {},
+	"store":            {},
+}
+
+// ResponseCacheOptions configures the response cache.
+type ResponseCacheOptions struct {
+	Enabled    bool
+	TTL        time.Duration
+	MaxEntries int
+	MaxBytes   int64
+	// Force caches requests regardless of their sampling temperature.
+	Force bool
+}
+
+// CachedResponse is a response stored in the cache. Non-streaming entries hold Payload;
+// streaming entries hold the chunks in the order they were sent to the client.
+type CachedResponse struct {
+	Key       string    `json:"key"`
+	Model     string    `json:"model"`
+	Format    string    `json:"format"`
+	Stream    bool      `json:"stream"`
+	Size      int64     `json:"size"`
+	Hits      int64     `json:"hits"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Payload   []byte    `json:"-"`
+	Chunks    [][]byte  `json:"-"`
+}
+
+// ResponseCacheStats summarizes the cache for the management API.
+type ResponseCacheStats struct {
+	Enabled    bool  `json:"enabled"`
+	Force      bool  `json:"force"`
+	TTLSeconds int   `json:"ttl-seconds"`
+	MaxEntries int   `json:"max-entries"`
+	MaxBytes   int64 `json:"max-bytes"`
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Bypassed   int64 `json:"bypassed"`
+}
+
+// ResponseCache is an LRU cache of upstream responses for deterministic requests.
+type ResponseCache struct {
+	mu       sync.Mutex
+	opts     ResponseCacheOptions
+	entries  map[string]*list.Element
+	order    *list.List
+	bytes    int64
+	hits     int64
+	misses   int64
+	bypassed int64
+}
+
+var defaultResponseCache = NewResponseCache()
+
+// DefaultResponseCache returns the process-wide response cache.
+func DefaultResponseCache() *ResponseCache { return defaultResponseCache }
+
+// NewResponseCache constructs a disabled response cache.
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// SetOptions applies configuration. Non-positive limits use the defaults; disabling the
+// cache drops every entry.
+func (c *ResponseCache) SetOptions(opts ResponseCacheOptions) {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultResponseCacheTTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultResponseCacheMaxEntries
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultResponseCacheMaxBytes
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts = opts
+	if !opts.Enabled {
+		c.flushLocked()
+		return
+	}
+	c.evictLocked()
+}
+
+// Enabled reports whether the cache is switched on.
+func (c *ResponseCache) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opts.Enabled
+}
+
+// Cacheable reports whether a request may be answered from the cache. Requests are cacheable
+// when they sample at temperature 0, or when caching is forced by configuration or by the caller.
+// A request without a temperature uses the provider default, which is not deterministic.
+func (c *ResponseCache) Cacheable(payload []byte, force bool) bool {
+	c.mu.Lock()
+	enabled, forced := c.opts.Enabled, c.opts.Force
+	c.mu.Unlock()
+	if !enabled {
+		return false
+	}
+	if force || forced {
+		return true
+	}
+	for _, path := range []string{"temperature", "generationConfig.temperature", "request.generationConfig.temperature"} {
+		if temperature := gjson.GetBytes(payload, path); temperature.Exists() {
+			return temperature.Float() <= 0
+		}
+	}
+	return false
+}
+
+// Bypass counts a request that skipped the cache because it was not cacheable.
+func (c *ResponseCache) Bypass() {
+	c.mu.Lock()
+	c.bypassed++
+	c.mu.Unlock()
+}
+
+// ResponseCacheKey derives the cache key from the client identity (a hashed API key), source
+// format, model, streaming mode, alternate response format and the request payload with its
+// keys sorted and non-semantic fields removed. Scoping by owner keeps clients from reading each
+// other's completions or probing which prompts others sent.
+func ResponseCacheKey(owner, format, model string, stream bool, alt string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(owner))
+	h.Write([]byte{0})
+	h.Write([]byte(format))
+	h.Write([]byte{0})
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	if stream {
+		h.Write([]byte("stream"))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(alt))
+	h.Write([]byte{0})
+	h.Write(normalizePayload(payload))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizePayload(payload []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		return payload
+	}
+	if object, ok := root.(map[string]any); ok {
+		for field := range responseCacheIgnoredFields {
+			delete(object, field)
+		}
+	}
+	normalized, err := json.Marshal(root)
+	if err != nil {
+		return payload
+	}
+	return normalized
+}
+
+// Get returns the live entry stored under key and records a hit or a miss.
+func (c *ResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok {
+		entry := element.Value.(*CachedResponse)
+		if time.Now().Before(entry.ExpiresAt) {
+			entry.Hits++
+			c.hits++
+			c.order.MoveToFront(element)
+			return entry, true
+		}
+		c.removeLocked(element)
+	}
+	c.misses++
+	return nil, false
+}
+
+// Put stores a completed response. Entries larger than the byte limit are not cached.
+func (c *ResponseCache) Put(entry *CachedResponse) {
+	if entry == nil || entry.Key == "" {
+		return
+	}
+	size := int64(len(entry.Payload))
+	for _, chunk := range entry.Chunks {
+		size += int64(len(chunk))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.opts.Enabled || size > c.opts.MaxBytes {
+		return
+	}
+	now := time.Now()
+	entry.Size = size
+	entry.CreatedAt = now
+	entry.ExpiresAt = now.Add(c.opts.TTL)
+	if existing, ok := c.entries[entry.Key]; ok {
+		c.removeLocked(existing)
+	}
+	c.entries[entry.Key] = c.order.PushFront(entry)
+	c.bytes += size
+	c.evictLocked()
+}
+
+// Entries lists live entries, most recently created first.
+func (c *ResponseCache) Entries() []CachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	out := make([]CachedResponse, 0, len(c.entries))
+	for _, element := range c.entries {
+		entry := element.Value.(*CachedResponse)
+		if now.Before(entry.ExpiresAt) {
+			out = append(out, *entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// Entry returns a copy of the live entry stored under key without counting a hit.
+func (c *ResponseCache) Entry(key string) (CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return CachedResponse{}, false
+	}
+	entry := element.Value.(*CachedResponse)
+	if !time.Now().Before(entry.ExpiresAt) {
+		return CachedResponse{}, false
+	}
+	return *entry, true
+}
+
+// Delete removes one entry and reports whether it existed.
+func (c *ResponseCache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok {
+		c.removeLocked(element)
+	}
+	return ok
+}
+
+// Flush removes every entry and returns how many were dropped.
+func (c *ResponseCache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked()
+}
+
+// Stats returns the cache configuration, size and counters.
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ResponseCacheStats{
+		Enabled:    c.opts.Enabled,
+		Force:      c.opts.Force,
+		TTLSeconds: int(c.opts.TTL.Seconds()),
+		MaxEntries: c.opts.MaxEntries,
+		MaxBytes:   c.opts.MaxBytes,
+		Entries:    len(c.entries),
+		Bytes:      c.bytes,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Bypassed:   c.bypassed,
+	}
+}
+
+func (c *ResponseCache) flushLocked() int {
+	dropped := len(c.entries)
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+	return dropped
+}
+
+func (c *ResponseCache) removeLocked(element *list.Element) {
+	entry := element.Value.(*CachedResponse)
+	c.order.Remove(element)
+	delete(c.entries, entry.Key)
+	c.bytes -= entry.Size
+}
+
+// evictLocked drops least recently used entries until both limits hold.
+func (c *ResponseCache) evictLocked() {
+	for c.order.Len() > 0 && (c.order.Len() > c.opts.MaxEntries || c.bytes > c.opts.MaxBytes) {
+		c.removeLocked(c.order.Back())
+	}
+}
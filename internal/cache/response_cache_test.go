@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResponseCacheKeyNormalizesPayload(t *testing.T) {
+	a := ResponseCacheKey("", "openai", "m", false, "", []byte(`{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}],"user":"ci-1"}`))
+	b := ResponseCacheKey("", "openai", "m", false, "", []byte(`{"messages":[{"content":"hi","role":"user"}],"temperature":0,"model":"m","user":"ci-2"}`))
+	if a != b {
+		t.Fatal("equivalent payloads produced different keys")
+	}
+	if a == ResponseCacheKey("", "openai", "m", true, "", []byte(`{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)) {
+		t.Fatal("stream and non-stream requests share a key")
+	}
+	if a == ResponseCacheKey("", "claude", "m", false, "", []byte(`{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)) {
+		t.Fatal("different source formats share a key")
+	}
+	if a == ResponseCacheKey("owner", "openai", "m", false, "", []byte(`{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)) {
+		t.Fatal("different owners share a key")
+	}
+}
+
+func TestResponseCacheCacheable(t *testing.T) {
+	c := NewResponseCache()
+	if c.Cacheable([]byte(`{"temperature":0}`), false) {
+		t.Fatal("disabled cache accepted a request")
+	}
+	c.SetOptions(ResponseCacheOptions{Enabled: true})
+	cases := map[string]bool{
+		`{"temperature":0}`:                                  true,
+		`{"generationConfig":{"temperature":0}}`:             true,
+		`{"temperature":0.7}`:                                false,
+		`{"messages":[]}`:                                    false,
+		`{"request":{"generationConfig":{"temperature":0}}}`: true,
+	}
+	for payload, want := range cases {
+		if got := c.Cacheable([]byte(payload), false); got != want {
+			t.Errorf("Cacheable(%s) = %v, want %v", payload, got, want)
+		}
+	}
+	if !c.Cacheable([]byte(`{"temperature":1}`), true) {
+		t.Fatal("forced request was not cacheable")
+	}
+}
+
+func TestResponseCacheLimitsAndExpiry(t *testing.T) {
+	c := NewResponseCache()
+	c.SetOptions(ResponseCacheOptions{Enabled: true, TTL: time.Hour, MaxEntries: 2, MaxBytes: 10})
+
+	c.Put(&CachedResponse{Key: "a", Payload: []byte("1234")})
+	c.Put(&CachedResponse{Key: "b", Chunks: [][]byte{[]byte("12"), []byte("34")}})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry a missing")
+	}
+	c.Put(&CachedResponse{Key: "c", Payload: []byte("1234")})
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("least recently used entry b was not evicted")
+	}
+	c.Put(&CachedResponse{Key: "huge", Payload: []byte("12345678901")})
+	if _, ok := c.Get("huge"); ok {
+		t.Fatal("entry above the byte limit was cached")
+	}
+	stats := c.Stats()
+	if stats.Entries != 2 || stats.Bytes != 8 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	c.SetOptions(ResponseCacheOptions{Enabled: true, TTL: time.Nanosecond})
+	c.Put(&CachedResponse{Key: "short", Payload: []byte("x")})
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("expired entry was served")
+	}
+	if c.Flush() == 0 {
+		t.Fatal("Flush() removed nothing")
+	}
+}
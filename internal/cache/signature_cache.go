@@ -3,8 +3,7 @@ package cache
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"sort"
-	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,10 +14,10 @@ type SignatureEntry struct {
 }
 
 const (
-	// SignatureCacheTTL is how long signatures are valid
+	// SignatureCacheTTL is the default duration signatures stay valid
 	SignatureCacheTTL = 1 * time.Hour
 
-	// MaxEntriesPerSession limits memory usage per session
+	// MaxEntriesPerSession is the default per-session entry limit
 	MaxEntriesPerSession = 100
 
 	// SignatureTextHashLen is the length of the hash key (16 hex chars = 64-bit key space)
@@ -28,13 +27,32 @@ const (
 	MinValidSignatureLen = 50
 )
 
-// signatureCache stores signatures by sessionId -> textHash -> SignatureEntry
-var signatureCache sync.Map
+var (
+	signatureTTL        atomic.Int64
+	signatureMaxEntries atomic.Int64
+)
+
+func init() {
+	signatureTTL.Store(int64(SignatureCacheTTL))
+	signatureMaxEntries.Store(MaxEntriesPerSession)
+}
+
+// SetSignatureCacheLimits updates the TTL and per-session entry limit.
+// Non-positive values restore the defaults.
+func SetSignatureCacheLimits(ttl time.Duration, maxEntries int) {
+	if ttl <= 0 {
+		ttl = SignatureCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = MaxEntriesPerSession
+	}
+	signatureTTL.Store(int64(ttl))
+	signatureMaxEntries.Store(int64(maxEntries))
+}
 
-// sessionCache is the inner map type
-type sessionCache struct {
-	mu      sync.RWMutex
-	entries map[string]SignatureEntry
+// SignatureCacheLimits returns the active TTL and per-session entry limit.
+func SignatureCacheLimits() (time.Duration, int) {
+	return time.Duration(signatureTTL.Load()), int(signatureMaxEntries.Load())
 }
 
 // hashText creates a stable, Unicode-safe key from text content
@@ -43,16 +61,6 @@ func hashText(text string) string {
 	return hex.EncodeToString(h[:])[:SignatureTextHashLen]
 }
 
-// getOrCreateSession gets or creates a session cache
-func getOrCreateSession(sessionID string) *sessionCache {
-	if val, ok := signatureCache.Load(sessionID); ok {
-		return val.(*sessionCache)
-	}
-	sc := &sessionCache{entries: make(map[string]SignatureEntry)}
-	actual, _ := signatureCache.LoadOrStore(sessionID, sc)
-	return actual.(*sessionCache)
-}
-
 // CacheSignature stores a thinking signature for a given session and text.
 // Used for Claude models that require signed thinking blocks in multi-turn conversations.
 func CacheSignature(sessionID, text, signature string) {
@@ -63,53 +71,11 @@ func CacheSignature(sessionID, text, signature string) {
 		return
 	}
 
-	sc := getOrCreateSession(sessionID)
-	textHash := hashText(text)
-
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-
-	// Evict expired entries if at capacity
-	if len(sc.entries) >= MaxEntriesPerSession {
-		now := time.Now()
-		for key, entry := range sc.entries {
-			if now.Sub(entry.Timestamp) > SignatureCacheTTL {
-				delete(sc.entries, key)
-			}
-		}
-		// If still at capacity, remove oldest entries
-		if len(sc.entries) >= MaxEntriesPerSession {
-			// Find and remove oldest quarter
-			oldest := make([]struct {
-				key string
-				ts  time.Time
-			}, 0, len(sc.entries))
-			for key, entry := range sc.entries {
-				oldest = append(oldest, struct {
-					key string
-					ts  time.Time
-				}{key, entry.Timestamp})
-			}
-			// Sort by timestamp (oldest first) using sort.Slice
-			sort.Slice(oldest, func(i, j int) bool {
-				return oldest[i].ts.Before(oldest[j].ts)
-			})
-
-			toRemove := len(oldest) / 4
-			if toRemove < 1 {
-				toRemove = 1
-			}
-
-			for i := 0; i < toRemove; i++ {
-				delete(sc.entries, oldest[i].key)
-			}
-		}
-	}
-
-	sc.entries[textHash] = SignatureEntry{
+	ttl, maxEntries := SignatureCacheLimits()
+	activeSignatureStore().Put(sessionID, hashText(text), SignatureEntry{
 		Signature: signature,
 		Timestamp: time.Now(),
-	}
+	}, ttl, maxEntries)
 }
 
 // GetCachedSignature retrieves a cached signature for a given session and text.
@@ -119,27 +85,17 @@ func GetCachedSignature(sessionID, text string) string {
 		return ""
 	}
 
-	val, ok := signatureCache.Load(sessionID)
-	if !ok {
-		return ""
-	}
-	sc := val.(*sessionCache)
-
+	store := activeSignatureStore()
 	textHash := hashText(text)
-
-	sc.mu.RLock()
-	entry, exists := sc.entries[textHash]
-	sc.mu.RUnlock()
-
+	entry, exists := store.Get(sessionID, textHash)
 	if !exists {
 		return ""
 	}
 
 	// Check if expired
-	if time.Since(entry.Timestamp) > SignatureCacheTTL {
-		sc.mu.Lock()
-		delete(sc.entries, textHash)
-		sc.mu.Unlock()
+	ttl, _ := SignatureCacheLimits()
+	if time.Since(entry.Timestamp) > ttl {
+		store.Delete(sessionID, textHash)
 		return ""
 	}
 
@@ -148,13 +104,11 @@ func GetCachedSignature(sessionID, text string) string {
 
 // ClearSignatureCache clears signature cache for a specific session or all sessions.
 func ClearSignatureCache(sessionID string) {
+	store := activeSignatureStore()
 	if sessionID != "" {
-		signatureCache.Delete(sessionID)
+		store.DeleteSession(sessionID)
 	} else {
-		signatureCache.Range(func(key, _ any) bool {
-			signatureCache.Delete(key)
-			return true
-		})
+		store.Clear()
 	}
 }
 
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -214,3 +216,85 @@ func TestCacheSignature_ExpirationLogic(t *testing.T) {
 	// but the logic is verified by the implementation
 	_ = time.Now() // Acknowledge we're not testing time passage
 }
+
+func TestFileSignatureStore_SharedAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewFileSignatureStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileSignatureStore: %v", err)
+	}
+	second, err := NewFileSignatureStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileSignatureStore: %v", err)
+	}
+
+	SetSignatureStore(first)
+	defer SetSignatureStore(nil)
+
+	sig := "fileSig12345678901234567890123456789012345678901234567890"
+	CacheSignature("file-session", "thinking text", sig)
+
+	// A second process pointed at the same directory must observe the entry.
+	SetSignatureStore(second)
+	if got := GetCachedSignature("file-session", "thinking text"); got != sig {
+		t.Fatalf("Expected signature from shared directory, got '%s'", got)
+	}
+	sessions := second.Sessions()
+	if len(sessions) != 1 || sessions[0].SessionID != "file-session" || sessions[0].Entries != 1 {
+		t.Fatalf("Unexpected sessions summary: %+v", sessions)
+	}
+
+	ClearSignatureCache("file-session")
+	if got := GetCachedSignature("file-session", "thinking text"); got != "" {
+		t.Fatalf("Expected session to be purged, got '%s'", got)
+	}
+}
+
+func TestSetSignatureCacheLimits_EvictsBeyondMaxEntries(t *testing.T) {
+	ClearSignatureCache("")
+	SetSignatureCacheLimits(time.Hour, 4)
+	defer SetSignatureCacheLimits(0, 0)
+
+	sig := "limitSig123456789012345678901234567890123456789012345678"
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		CacheSignature("limit-session", text, sig)
+		time.Sleep(time.Millisecond)
+	}
+
+	entries := ActiveSignatureStore().Entries("limit-session")
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries after eviction, got %d", len(entries))
+	}
+	if got := GetCachedSignature("limit-session", "a"); got != "" {
+		t.Error("Oldest entry should have been evicted")
+	}
+}
+
+func TestFileSignatureStore_ConcurrentWritersKeepAllEntries(t *testing.T) {
+	dir := t.TempDir()
+	stores := make([]*FileSignatureStore, 2)
+	for i := range stores {
+		store, err := NewFileSignatureStore(dir)
+		if err != nil {
+			t.Fatalf("NewFileSignatureStore: %v", err)
+		}
+		stores[i] = store
+	}
+
+	sig := "lockSig1234567890123456789012345678901234567890123456789"
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		wg.Add(1)
+		go func(i int, store *FileSignatureStore) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				store.Put("shared-session", fmt.Sprintf("hash-%d-%d", i, j), SignatureEntry{Signature: sig, Timestamp: time.Now()}, time.Hour, 1000)
+			}
+		}(i, store)
+	}
+	wg.Wait()
+
+	if entries := stores[0].Entries("shared-session"); len(entries) != 40 {
+		t.Fatalf("Expected 40 entries from both writers, got %d", len(entries))
+	}
+}
@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// signatureLockWait bounds how long a writer waits for another process's session lock.
+	signatureLockWait = 2 * time.Second
+	// signatureLockStale is the age after which a lock file is assumed abandoned by a crashed
+	// process and removed.
+	signatureLockStale = 30 * time.Second
+)
+
+// FileSignatureStore persists signatures as one JSON document per session inside a directory.
+// Every operation reads through to disk so several processes sharing the directory observe
+// each other's writes. Writers take a per-session lock file so concurrent read-modify-write
+// cycles from different processes do not overwrite each other's entries.
+type FileSignatureStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+type signatureFileDocument struct {
+	SessionID string                        `json:"session_id"`
+	Entries   map[string]signatureFileEntry `json:"entries"`
+}
+
+type signatureFileEntry struct {
+	Signature string    `json:"signature"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewFileSignatureStore prepares a directory-backed signature store.
+func NewFileSignatureStore(dir string) (*FileSignatureStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("signature cache: directory is required")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("signature cache: resolve directory: %w", err)
+	}
+	if err = os.MkdirAll(absDir, 0o700); err != nil {
+		return nil, fmt.Errorf("signature cache: create directory: %w", err)
+	}
+	return &FileSignatureStore{dir: absDir}, nil
+}
+
+// Name implements SignatureStore.
+func (s *FileSignatureStore) Name() string { return "file" }
+
+// Dir returns the directory holding session documents.
+func (s *FileSignatureStore) Dir() string { return s.dir }
+
+func (s *FileSignatureStore) sessionPath(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// lockSession takes the cross-process lock of a session document by exclusively creating
+// "<path>.lock". The returned function releases the lock.
+func (s *FileSignatureStore) lockSession(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(signatureLockWait)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = file.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, errStat := os.Stat(lockPath); errStat == nil && time.Since(info.ModTime()) > signatureLockStale {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", filepath.Base(lockPath))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *FileSignatureStore) readDocument(path string) (*signatureFileDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc signatureFileDocument
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Entries == nil {
+		doc.Entries = make(map[string]signatureFileEntry)
+	}
+	return &doc, nil
+}
+
+func (s *FileSignatureStore) writeDocument(path string, doc *signatureFileDocument) error {
+	if len(doc.Entries) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".sig-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// Get implements SignatureStore.
+func (s *FileSignatureStore) Get(sessionID, textHash string) (SignatureEntry, bool) {
+	doc, err := s.readDocument(s.sessionPath(sessionID))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debugf("signature cache: read session document: %v", err)
+		}
+		return SignatureEntry{}, false
+	}
+	entry, ok := doc.Entries[textHash]
+	if !ok {
+		return SignatureEntry{}, false
+	}
+	return SignatureEntry{Signature: entry.Signature, Timestamp: entry.Timestamp}, true
+}
+
+// Put implements SignatureStore.
+func (s *FileSignatureStore) Put(sessionID, textHash string, entry SignatureEntry, ttl time.Duration, maxEntries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.sessionPath(sessionID)
+	unlock, err := s.lockSession(path)
+	if err != nil {
+		log.Warnf("signature cache: lock session document: %v", err)
+		return
+	}
+	defer unlock()
+	doc, err := s.readDocument(path)
+	if err != nil {
+		doc = &signatureFileDocument{SessionID: sessionID, Entries: make(map[string]signatureFileEntry)}
+	}
+	entries := doc.toEntries()
+	evictSignatureEntries(entries, ttl, maxEntries, time.Now())
+	entries[textHash] = entry
+	doc.fromEntries(entries)
+	if err = s.writeDocument(path, doc); err != nil {
+		log.Warnf("signature cache: write session document: %v", err)
+	}
+}
+
+// Delete implements SignatureStore.
+func (s *FileSignatureStore) Delete(sessionID, textHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.sessionPath(sessionID)
+	unlock, err := s.lockSession(path)
+	if err != nil {
+		log.Warnf("signature cache: lock session document: %v", err)
+		return
+	}
+	defer unlock()
+	doc, err := s.readDocument(path)
+	if err != nil {
+		return
+	}
+	if _, ok := doc.Entries[textHash]; !ok {
+		return
+	}
+	delete(doc.Entries, textHash)
+	if err = s.writeDocument(path, doc); err != nil {
+		log.Warnf("signature cache: write session document: %v", err)
+	}
+}
+
+// DeleteSession implements SignatureStore.
+func (s *FileSignatureStore) DeleteSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeDocument(s.sessionPath(sessionID))
+}
+
+// Clear implements SignatureStore.
+func (s *FileSignatureStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range s.documentPaths() {
+		s.removeDocument(path)
+	}
+}
+
+func (s *FileSignatureStore) removeDocument(path string) {
+	unlock, err := s.lockSession(path)
+	if err != nil {
+		log.Warnf("signature cache: lock session document: %v", err)
+		return
+	}
+	defer unlock()
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("signature cache: remove session document: %v", err)
+	}
+}
+
+// Sessions implements SignatureStore.
+func (s *FileSignatureStore) Sessions() []SignatureSession {
+	out := make([]SignatureSession, 0)
+	for _, path := range s.documentPaths() {
+		doc, err := s.readDocument(path)
+		if err != nil {
+			continue
+		}
+		if summary, ok := summarizeSignatureSession(doc.SessionID, doc.toEntries()); ok {
+			out = append(out, summary)
+		}
+	}
+	sortSignatureSessions(out)
+	return out
+}
+
+// Entries implements SignatureStore.
+func (s *FileSignatureStore) Entries(sessionID string) []SignatureRecord {
+	doc, err := s.readDocument(s.sessionPath(sessionID))
+	if err != nil {
+		return nil
+	}
+	return signatureRecords(doc.toEntries())
+}
+
+func (s *FileSignatureStore) documentPaths() []string {
+	items, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.IsDir() || !strings.HasSuffix(item.Name(), ".json") {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.dir, item.Name()))
+	}
+	return paths
+}
+
+func (d *signatureFileDocument) toEntries() map[string]SignatureEntry {
+	entries := make(map[string]SignatureEntry, len(d.Entries))
+	for hash, entry := range d.Entries {
+		entries[hash] = SignatureEntry{Signature: entry.Signature, Timestamp: entry.Timestamp}
+	}
+	return entries
+}
+
+func (d *signatureFileDocument) fromEntries(entries map[string]SignatureEntry) {
+	d.Entries = make(map[string]signatureFileEntry, len(entries))
+	for hash, entry := range entries {
+		d.Entries[hash] = signatureFileEntry{Signature: entry.Signature, Timestamp: entry.Timestamp}
+	}
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SignatureStore persists thinking signatures keyed by session ID and text hash.
+// Implementations must be safe for concurrent use.
+type SignatureStore interface {
+	// Name identifies the backend (e.g. "memory", "file", "postgres").
+	Name() string
+	// Get returns the entry stored for the session and text hash.
+	Get(sessionID, textHash string) (SignatureEntry, bool)
+	// Put stores an entry, evicting expired and oldest entries beyond maxEntries.
+	Put(sessionID, textHash string, entry SignatureEntry, ttl time.Duration, maxEntries int)
+	// Delete removes a single entry.
+	Delete(sessionID, textHash string)
+	// DeleteSession removes every entry stored for a session.
+	DeleteSession(sessionID string)
+	// Clear removes every entry.
+	Clear()
+	// Sessions summarizes all stored sessions.
+	Sessions() []SignatureSession
+	// Entries lists the entries stored for a session.
+	Entries(sessionID string) []SignatureRecord
+}
+
+// SignatureSession summarizes the entries cached for one session.
+type SignatureSession struct {
+	SessionID string    `json:"session_id"`
+	Entries   int       `json:"entries"`
+	Oldest    time.Time `json:"oldest"`
+	Newest    time.Time `json:"newest"`
+}
+
+// SignatureRecord describes a single cached signature.
+type SignatureRecord struct {
+	TextHash  string    `json:"text_hash"`
+	Signature string    `json:"signature"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type signatureStoreHolder struct {
+	store SignatureStore
+}
+
+var (
+	signatureStoreValue   atomic.Value
+	defaultSignatureStore = NewMemorySignatureStore()
+)
+
+// SetSignatureStore replaces the active signature store; nil restores the in-memory store.
+func SetSignatureStore(store SignatureStore) {
+	if store == nil {
+		store = defaultSignatureStore
+	}
+	signatureStoreValue.Store(signatureStoreHolder{store: store})
+}
+
+// ActiveSignatureStore returns the signature store currently in use.
+func ActiveSignatureStore() SignatureStore {
+	return activeSignatureStore()
+}
+
+func activeSignatureStore() SignatureStore {
+	if holder, ok := signatureStoreValue.Load().(signatureStoreHolder); ok && holder.store != nil {
+		return holder.store
+	}
+	return defaultSignatureStore
+}
+
+// MemorySignatureStore keeps signatures in process memory.
+type MemorySignatureStore struct {
+	sessions sync.Map
+}
+
+// sessionCache is the inner map type
+type sessionCache struct {
+	mu      sync.RWMutex
+	entries map[string]SignatureEntry
+}
+
+// NewMemorySignatureStore constructs an empty in-memory signature store.
+func NewMemorySignatureStore() *MemorySignatureStore {
+	return &MemorySignatureStore{}
+}
+
+// Name implements SignatureStore.
+func (s *MemorySignatureStore) Name() string { return "memory" }
+
+// getOrCreateSession gets or creates a session cache
+func (s *MemorySignatureStore) getOrCreateSession(sessionID string) *sessionCache {
+	if val, ok := s.sessions.Load(sessionID); ok {
+		return val.(*sessionCache)
+	}
+	sc := &sessionCache{entries: make(map[string]SignatureEntry)}
+	actual, _ := s.sessions.LoadOrStore(sessionID, sc)
+	return actual.(*sessionCache)
+}
+
+// Get implements SignatureStore.
+func (s *MemorySignatureStore) Get(sessionID, textHash string) (SignatureEntry, bool) {
+	val, ok := s.sessions.Load(sessionID)
+	if !ok {
+		return SignatureEntry{}, false
+	}
+	sc := val.(*sessionCache)
+	sc.mu.RLock()
+	entry, exists := sc.entries[textHash]
+	sc.mu.RUnlock()
+	return entry, exists
+}
+
+// Put implements SignatureStore.
+func (s *MemorySignatureStore) Put(sessionID, textHash string, entry SignatureEntry, ttl time.Duration, maxEntries int) {
+	sc := s.getOrCreateSession(sessionID)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	evictSignatureEntries(sc.entries, ttl, maxEntries, time.Now())
+	sc.entries[textHash] = entry
+}
+
+// Delete implements SignatureStore.
+func (s *MemorySignatureStore) Delete(sessionID, textHash string) {
+	val, ok := s.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+	sc := val.(*sessionCache)
+	sc.mu.Lock()
+	delete(sc.entries, textHash)
+	sc.mu.Unlock()
+}
+
+// DeleteSession implements SignatureStore.
+func (s *MemorySignatureStore) DeleteSession(sessionID string) {
+	s.sessions.Delete(sessionID)
+}
+
+// Clear implements SignatureStore.
+func (s *MemorySignatureStore) Clear() {
+	s.sessions.Range(func(key, _ any) bool {
+		s.sessions.Delete(key)
+		return true
+	})
+}
+
+// Sessions implements SignatureStore.
+func (s *MemorySignatureStore) Sessions() []SignatureSession {
+	out := make([]SignatureSession, 0)
+	s.sessions.Range(func(key, value any) bool {
+		sc := value.(*sessionCache)
+		sc.mu.RLock()
+		summary, ok := summarizeSignatureSession(key.(string), sc.entries)
+		sc.mu.RUnlock()
+		if ok {
+			out = append(out, summary)
+		}
+		return true
+	})
+	sortSignatureSessions(out)
+	return out
+}
+
+// Entries implements SignatureStore.
+func (s *MemorySignatureStore) Entries(sessionID string) []SignatureRecord {
+	val, ok := s.sessions.Load(sessionID)
+	if !ok {
+		return nil
+	}
+	sc := val.(*sessionCache)
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return signatureRecords(sc.entries)
+}
+
+// evictSignatureEntries drops expired entries once a session reaches maxEntries,
+// then removes the oldest quarter if the session is still full.
+func evictSignatureEntries(entries map[string]SignatureEntry, ttl time.Duration, maxEntries int, now time.Time) {
+	if maxEntries <= 0 || len(entries) < maxEntries {
+		return
+	}
+	for key, entry := range entries {
+		if now.Sub(entry.Timestamp) > ttl {
+			delete(entries, key)
+		}
+	}
+	if len(entries) < maxEntries {
+		return
+	}
+	oldest := make([]struct {
+		key string
+		ts  time.Time
+	}, 0, len(entries))
+	for key, entry := range entries {
+		oldest = append(oldest, struct {
+			key string
+			ts  time.Time
+		}{key, entry.Timestamp})
+	}
+	sort.Slice(oldest, func(i, j int) bool {
+		return oldest[i].ts.Before(oldest[j].ts)
+	})
+	toRemove := len(oldest) / 4
+	if toRemove < 1 {
+		toRemove = 1
+	}
+	for i := 0; i < toRemove; i++ {
+		delete(entries, oldest[i].key)
+	}
+}
+
+func summarizeSignatureSession(sessionID string, entries map[string]SignatureEntry) (SignatureSession, bool) {
+	if len(entries) == 0 {
+		return SignatureSession{}, false
+	}
+	summary := SignatureSession{SessionID: sessionID, Entries: len(entries)}
+	for _, entry := range entries {
+		if summary.Oldest.IsZero() || entry.Timestamp.Before(summary.Oldest) {
+			summary.Oldest = entry.Timestamp
+		}
+		if entry.Timestamp.After(summary.Newest) {
+			summary.Newest = entry.Timestamp
+		}
+	}
+	return summary, true
+}
+
+func sortSignatureSessions(sessions []SignatureSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Newest.After(sessions[j].Newest)
+	})
+}
+
+func signatureRecords(entries map[string]SignatureEntry) []SignatureRecord {
+	out := make([]SignatureRecord, 0, len(entries))
+	for hash, entry := range entries {
+		out = append(out, SignatureRecord{TextHash: hash, Signature: entry.Signature, Timestamp: entry.Timestamp})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Timestamp.After(out[j].Timestamp)
+	})
+	return out
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTokenCountCacheTTL is how long token counts are reused when no TTL is configured.
+	DefaultTokenCountCacheTTL = 5 * time.Minute
+	// DefaultTokenCountCacheMaxEntries bounds the number of cached token counts.
+	DefaultTokenCountCacheMaxEntries = 2000
+)
+
+type tokenCountEntry struct {
+	key       string
+	payload   []byte
+	expiresAt time.Time
+}
+
+// TokenCountCache is an LRU cache of token count responses keyed by payload hash. Clients such
+// as Claude Code count the same conversation prefix repeatedly, so identical payloads are
+// answered without another upstream call.
+type TokenCountCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+var defaultTokenCountCache = NewTokenCountCache()
+
+// DefaultTokenCountCache returns the process-wide token count cache.
+func DefaultTokenCountCache() *TokenCountCache { return defaultTokenCountCache }
+
+// NewTokenCountCache constructs a token count cache with the default limits.
+func NewTokenCountCache() *TokenCountCache {
+	return &TokenCountCache{
+		ttl:        DefaultTokenCountCacheTTL,
+		maxEntries: DefaultTokenCountCacheMaxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// SetOptions applies configuration. A zero TTL or entry limit uses the default; a negative TTL
+// disables the cache and drops every entry.
+func (c *TokenCountCache) SetOptions(ttl time.Duration, maxEntries int) {
+	if ttl == 0 {
+		ttl = DefaultTokenCountCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultTokenCountCacheMaxEntries
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl, c.maxEntries = ttl, maxEntries
+	if ttl < 0 {
+		c.entries = make(map[string]*list.Element)
+		c.order.Init()
+		return
+	}
+	for c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// TokenCountKey derives the cache key from the source format, model, alternate response
+// format and the raw request payload.
+func TokenCountKey(format, model, alt string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(format))
+	h.Write([]byte{0})
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write([]byte(alt))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns a copy of the live response stored under key.
+func (c *TokenCountCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*tokenCountEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.removeLocked(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return append([]byte(nil), entry.payload...), true
+}
+
+// Put stores a copy of payload under key, evicting the least recently used entries beyond the limit.
+func (c *TokenCountCache) Put(key string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl < 0 || key == "" {
+		return
+	}
+	entry := &tokenCountEntry{key: key, payload: append([]byte(nil), payload...), expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+func (c *TokenCountCache) removeLocked(element *list.Element) {
+	if element == nil {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*tokenCountEntry).key)
+}
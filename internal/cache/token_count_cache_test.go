@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenCountCacheEvictsAndExpires(t *testing.T) {
+	c := NewTokenCountCache()
+	c.SetOptions(time.Hour, 2)
+
+	c.Put("a", []byte(`{"input_tokens":1}`))
+	c.Put("b", []byte(`{"input_tokens":2}`))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry a missing")
+	}
+	c.Put("c", []byte(`{"input_tokens":3}`))
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("least recently used entry b was not evicted")
+	}
+	if got, ok := c.Get("c"); !ok || string(got) != `{"input_tokens":3}` {
+		t.Fatalf("Get(c) = %s, %v", got, ok)
+	}
+
+	c.SetOptions(-1, 0)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("disabling the cache kept entries")
+	}
+	c.Put("d", []byte("x"))
+	if _, ok := c.Get("d"); ok {
+		t.Fatal("disabled cache stored an entry")
+	}
+}
+
+func TestTokenCountKeyDependsOnFormatAndModel(t *testing.T) {
+	payload := []byte(`{"messages":[]}`)
+	if TokenCountKey("claude", "m", "", payload) == TokenCountKey("gemini", "m", "", payload) {
+		t.Fatal("different formats share a key")
+	}
+	if TokenCountKey("claude", "m1", "", payload) == TokenCountKey("claude", "m2", "", payload) {
+		t.Fatal("different models share a key")
+	}
+}
@@ -0,0 +1,59 @@
+// Package cmd contains CLI helpers. This file implements re-encrypting every stored
+// credential with the primary token encryption key.
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoEncryptAuthFiles rewrites every credential of the active token store that is still stored as
+// plaintext or sealed with a rotated-out key, so that all of them are encrypted with the primary
+// key. Saving through the token store also updates the Git, object storage or Postgres copy.
+func DoEncryptAuthFiles(cfg *config.Config) {
+	if !tokencrypt.Enabled() {
+		log.Errorf("encrypt-auth: no encryption key configured (set TOKEN_ENCRYPTION_KEY or TOKEN_ENCRYPTION_KEY_FILE)")
+		return
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if resolved, errResolve := util.ResolveAuthDir(cfg.AuthDir); errResolve == nil {
+		cfg.AuthDir = resolved
+	}
+
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	ctx := context.Background()
+	auths, errList := store.List(ctx)
+	if errList != nil {
+		log.Errorf("encrypt-auth: list credentials failed: %v", errList)
+		return
+	}
+	saved, skipped, failed := 0, 0, 0
+	for _, auth := range auths {
+		if auth == nil {
+			continue
+		}
+		if auth.Metadata == nil {
+			log.Warnf("encrypt-auth: %s has no metadata, skipped", auth.ID)
+			skipped++
+			continue
+		}
+		if _, errSave := store.Save(ctx, auth); errSave != nil {
+			log.Errorf("encrypt-auth: save %s failed: %v", auth.ID, errSave)
+			failed++
+			continue
+		}
+		saved++
+	}
+	fmt.Printf("%d credentials encrypted with key %s, %d skipped, %d failed\n", saved, tokencrypt.PrimaryKeyID(), skipped, failed)
+}
@@ -49,6 +49,12 @@ type Config struct {
 	// When exceeded, the oldest log files are deleted until within the limit. Set to 0 to disable.
 	LogsMaxTotalSizeMB int `yaml:"logs-max-total-size-mb" json:"logs-max-total-size-mb"`
 
+	// RequestLogRedaction scrubs secrets and prompts from request logs before they are written.
+	RequestLogRedaction RequestLogRedactionConfig `yaml:"request-log-redaction,omitempty" json:"request-log-redaction,omitempty"`
+
+	// RequestLogJSONL writes one structured JSON record per request to requests.jsonl in the logs directory.
+	RequestLogJSONL RequestLogJSONLConfig `yaml:"request-log-jsonl,omitempty" json:"request-log-jsonl,omitempty"`
+
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
@@ -66,6 +72,12 @@ type Config struct {
 	// Routing controls credential selection behavior.
 	Routing RoutingConfig `yaml:"routing" json:"routing"`
 
+	// UpstreamTimeouts bounds how long upstream calls may stay silent before failing over.
+	UpstreamTimeouts UpstreamTimeoutConfig `yaml:"upstream-timeouts,omitempty" json:"upstream-timeouts,omitempty"`
+
+	// HealthCheck probes credentials in the background so revoked ones leave rotation early.
+	HealthCheck HealthCheckConfig `yaml:"health-check,omitempty" json:"health-check,omitempty"`
+
 	// WebsocketAuth enables or disables authentication for the WebSocket API.
 	WebsocketAuth bool `yaml:"ws-auth" json:"ws-auth"`
 
@@ -102,6 +114,24 @@ type Config struct {
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
+	// SignatureCache configures where Claude/Antigravity thinking signatures are stored.
+	SignatureCache SignatureCacheConfig `yaml:"signature-cache" json:"signature-cache"`
+
+	// UsageStore persists usage records for historical queries.
+	UsageStore UsageStoreConfig `yaml:"usage-store" json:"usage-store"`
+
+	// ResponseStore keeps Responses API outputs for previous_response_id and retrieval.
+	ResponseStore ResponseStoreConfig `yaml:"response-store" json:"response-store"`
+
+	// ResponseCache serves repeated deterministic requests from memory.
+	ResponseCache ResponseCacheConfig `yaml:"response-cache" json:"response-cache"`
+
+	// Metrics configures the Prometheus metrics endpoint.
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// Tracing configures OpenTelemetry trace export.
+	Tracing TracingConfig `yaml:"tracing" json:"tracing"`
+
 	legacyMigrationPending bool `yaml:"-" json:"-"`
 }
 
@@ -141,8 +171,182 @@ type QuotaExceeded struct {
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first".
+	// Supported values: "round-robin" (default), "fill-first", "weighted", "least-in-flight", "quota-aware".
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// SessionAffinity pins multi-turn conversations to the credential that served them.
+	SessionAffinity SessionAffinityConfig `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
+
+	// Fallbacks maps a requested model to the models tried, in order, when every credential
+	// for it is cooling down or unavailable.
+	Fallbacks map[string][]FallbackTarget `yaml:"fallbacks,omitempty" json:"fallbacks,omitempty"`
+}
+
+// FallbackTarget names a model to try when the requested model cannot be served.
+type FallbackTarget struct {
+	// Model is the fallback model ID as listed by /v1/models.
+	Model string `yaml:"model" json:"model"`
+
+	// Provider optionally restricts the fallback to one provider (e.g. "antigravity").
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+}
+
+// SessionAffinityConfig configures sticky credential routing keyed on a conversation identifier.
+// The identifier is taken from Header when set, then Claude metadata.user_id,
+// then OpenAI prompt_cache_key or user.
+type SessionAffinityConfig struct {
+	// Enabled turns sticky routing on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Header optionally names a request header carrying the session identifier (e.g. "X-Session-ID").
+	Header string `yaml:"header,omitempty" json:"header,omitempty"`
+
+	// TTLSeconds is how long an idle session stays pinned. Defaults to 3600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// MaxEntries bounds the pin table; least recently used sessions are evicted first. Defaults to 10000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+}
+
+// UpstreamTimeouts holds upstream timeout values in seconds. Zero disables the corresponding check.
+type UpstreamTimeouts struct {
+	// ConnectSeconds bounds establishing the upstream connection (dial, proxy and TLS handshake).
+	ConnectSeconds int `yaml:"connect-seconds,omitempty" json:"connect-seconds,omitempty"`
+
+	// FirstByteSeconds bounds the wait for the first streamed chunk, or for the response headers of a
+	// non-stream call. A stall triggers failover to the next credential.
+	FirstByteSeconds int `yaml:"first-byte-seconds,omitempty" json:"first-byte-seconds,omitempty"`
+
+	// IdleSeconds bounds the gap between streamed chunks. A stall ends the stream with an error event.
+	IdleSeconds int `yaml:"idle-seconds,omitempty" json:"idle-seconds,omitempty"`
+}
+
+// UpstreamTimeoutConfig configures upstream timeouts globally with optional per-provider overrides.
+type UpstreamTimeoutConfig struct {
+	UpstreamTimeouts `yaml:",inline"`
+
+	// Providers overrides the global values per provider key (e.g. "claude", "gemini", or an openai-compatibility name).
+	// Zero fields inherit the global value.
+	Providers map[string]UpstreamTimeouts `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// HealthCheckConfig configures background credential probing. Each probe is a cheap upstream
+// call such as listing models; a 401 or 403 takes the credential out of rotation until a later
+// probe succeeds.
+type HealthCheckConfig struct {
+	// Enabled turns periodic probing on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// IntervalSeconds is the time between probe rounds. Defaults to 600.
+	IntervalSeconds int `yaml:"interval-seconds,omitempty" json:"interval-seconds,omitempty"`
+
+	// TimeoutSeconds bounds a single probe. Defaults to 30.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+
+	// Concurrency bounds the number of probes running at once. Defaults to 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// Providers optionally restricts probing to these provider keys (e.g. "codex", "antigravity").
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// RequestLogRedactionConfig lists what request logs must not contain in clear text.
+type RequestLogRedactionConfig struct {
+	// Headers names request, response and upstream headers whose values are redacted (case-insensitive).
+	Headers []string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// JSONPaths selects JSON body fields to redact, using dot-separated paths where "#" or "*"
+	// matches every array element or object member (e.g. "messages.#.content").
+	JSONPaths []string `yaml:"json-paths,omitempty" json:"json-paths,omitempty"`
+	// Patterns are regular expressions whose matches are redacted anywhere in the log (e.g. "sk-[A-Za-z0-9_-]{20,}").
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+	// Hash replaces redacted values with a short SHA-256 digest instead of dropping them,
+	// so identical values can still be correlated across logs.
+	Hash bool `yaml:"hash,omitempty" json:"hash,omitempty"`
+}
+
+// RequestLogJSONLConfig configures the structured request history sink.
+type RequestLogJSONLConfig struct {
+	// Enabled turns the sink on independently of request-log.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MaxBodyBytes truncates the request and response bodies kept in each record. Defaults to 4096.
+	MaxBodyBytes int `yaml:"max-body-bytes,omitempty" json:"max-body-bytes,omitempty"`
+	// MaxSizeMB rotates requests.jsonl to requests.1.jsonl once it grows past this size. Defaults to 100.
+	MaxSizeMB int `yaml:"max-size-mb,omitempty" json:"max-size-mb,omitempty"`
+}
+
+// SignatureCacheConfig configures the thinking signature cache backend and limits.
+type SignatureCacheConfig struct {
+	// Backend selects the storage backend: "memory" (default), "file", or "postgres".
+	// The postgres backend reuses the PGSTORE_DSN connection and falls back to memory when unavailable.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// Dir is the directory used by the file backend. Defaults to "signature-cache" next to the config file.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// TTLSeconds controls how long signatures stay valid. <= 0 uses the default of one hour.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+	// MaxEntriesPerSession limits entries kept per session. <= 0 uses the default of 100.
+	MaxEntriesPerSession int `yaml:"max-entries-per-session,omitempty" json:"max-entries-per-session,omitempty"`
+}
+
+// ResponseStoreConfig configures where stored Responses API outputs are kept.
+type ResponseStoreConfig struct {
+	// Backend selects the storage backend: "memory" (default), "file", or "postgres".
+	// The postgres backend reuses the PGSTORE_DSN connection and falls back to memory when unavailable.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// Dir is the directory used by the file backend. Defaults to "responses" next to the config file.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// TTLSeconds controls how long responses stay retrievable. <= 0 uses the default of 30 days.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+	// MaxBytes bounds the total size of the memory backend. <= 0 uses the default of 256 MiB.
+	MaxBytes int64 `yaml:"max-bytes,omitempty" json:"max-bytes,omitempty"`
+}
+
+// ResponseCacheConfig configures the opt-in cache for identical deterministic requests.
+type ResponseCacheConfig struct {
+	// Enabled turns the cache on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTLSeconds controls how long a response is served from the cache. <= 0 uses the default of 600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+	// MaxEntries bounds the number of cached responses. <= 0 uses the default of 1000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+	// MaxBytes bounds the total size of cached responses. <= 0 uses the default of 64 MiB.
+	MaxBytes int64 `yaml:"max-bytes,omitempty" json:"max-bytes,omitempty"`
+	// Force also caches requests sampling above temperature 0.
+	Force bool `yaml:"force,omitempty" json:"force,omitempty"`
+}
+
+// UsageStoreConfig configures persistence of usage records across restarts.
+type UsageStoreConfig struct {
+	// Backend selects the storage backend: "" (disabled, default), "sqlite", or "postgres".
+	// The postgres backend reuses the PGSTORE_DSN connection.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// Path is the SQLite database file. Defaults to "usage.db" next to the config file.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// RetentionDays deletes records older than this many days. <= 0 keeps records forever.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+}
+
+// MetricsConfig configures Prometheus metrics exposition.
+type MetricsConfig struct {
+	// Enable toggles metric collection and the /metrics endpoint.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Listen optionally serves /metrics on a separate address (e.g. "127.0.0.1:9090") without
+	// authentication. When empty, /metrics is served on the main port and requires the management key.
+	// Changes to Listen take effect after a restart.
+	Listen string `yaml:"listen,omitempty" json:"listen,omitempty"`
+}
+
+// TracingConfig configures OpenTelemetry trace export over OTLP/HTTP.
+type TracingConfig struct {
+	// Enable toggles span export. Disabled by default.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Endpoint is the OTLP/HTTP collector URL. Defaults to "http://localhost:4318".
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Headers are sent with every export request, e.g. collector authentication.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// ServiceName overrides the reported service name. Defaults to "cli-proxy-api".
+	ServiceName string `yaml:"service-name,omitempty" json:"service-name,omitempty"`
+	// SampleRatio samples a fraction of new traces in (0, 1). Other values sample everything.
+	SampleRatio float64 `yaml:"sample-ratio,omitempty" json:"sample-ratio,omitempty"`
 }
 
 // ModelNameMapping defines a model ID mapping for a specific channel.
@@ -245,6 +449,9 @@ type ClaudeKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/claude-sonnet-4").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Weight biases the "weighted" routing strategy toward this credential (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
 	// BaseURL is the base URL for the Claude API endpoint.
 	// If empty, the default Claude API URL will be used.
 	BaseURL string `yaml:"base-url" json:"base-url"`
@@ -283,6 +490,9 @@ type CodexKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gpt-5-codex").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Weight biases the "weighted" routing strategy toward this credential (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
 	// BaseURL is the base URL for the Codex API endpoint.
 	// If empty, the default Codex API URL will be used.
 	BaseURL string `yaml:"base-url" json:"base-url"`
@@ -321,6 +531,9 @@ type GeminiKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gemini-3-pro-preview").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Weight biases the "weighted" routing strategy toward this credential (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
 	// BaseURL optionally overrides the Gemini API endpoint.
 	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
 
@@ -361,6 +574,10 @@ type OpenAICompatibility struct {
 	// BaseURL is the base URL for the external OpenAI-compatible API endpoint.
 	BaseURL string `yaml:"base-url" json:"base-url"`
 
+	// Protocol selects the upstream API: "chat" (/chat/completions, default), "responses"
+	// (/responses) or "messages" (Anthropic /messages).
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+
 	// APIKeyEntries defines API keys with optional per-key proxy configuration.
 	APIKeyEntries []OpenAICompatibilityAPIKey `yaml:"api-key-entries,omitempty" json:"api-key-entries,omitempty"`
 
@@ -369,6 +586,29 @@ type OpenAICompatibility struct {
 
 	// Headers optionally adds extra HTTP headers for requests sent to this provider.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// ModelDiscovery optionally registers the models listed by the upstream /models endpoint.
+	ModelDiscovery OpenAICompatibilityModelDiscovery `yaml:"model-discovery,omitempty" json:"model-discovery,omitempty"`
+}
+
+// Upstream protocols supported by OpenAI-compatibility providers.
+const (
+	OpenAICompatProtocolChat      = "chat"
+	OpenAICompatProtocolResponses = "responses"
+	OpenAICompatProtocolMessages  = "messages"
+)
+
+// NormalizeOpenAICompatProtocol maps a configured protocol name to one of the
+// OpenAICompatProtocol constants. Empty and unknown values select the chat protocol.
+func NormalizeOpenAICompatProtocol(protocol string) string {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "responses", "openai-response", "openai-responses":
+		return OpenAICompatProtocolResponses
+	case "messages", "anthropic", "claude":
+		return OpenAICompatProtocolMessages
+	default:
+		return OpenAICompatProtocolChat
+	}
 }
 
 // OpenAICompatibilityAPIKey represents an API key configuration with optional proxy setting.
@@ -378,6 +618,9 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+
+	// Weight biases the "weighted" routing strategy toward this credential (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -581,6 +824,10 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 		e.Prefix = normalizeModelPrefix(e.Prefix)
 		e.BaseURL = strings.TrimSpace(e.BaseURL)
 		e.Headers = NormalizeHeaders(e.Headers)
+		if e.Protocol = NormalizeOpenAICompatProtocol(e.Protocol); e.Protocol == OpenAICompatProtocolChat {
+			e.Protocol = ""
+		}
+		e.ModelDiscovery.sanitize()
 		if e.BaseURL == "" {
 			// Skip providers with no base-url; treated as removed
 			continue
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultModelDiscoveryInterval is how often upstream model lists are refreshed when no
+	// interval is configured.
+	DefaultModelDiscoveryInterval = time.Hour
+	// minModelDiscoveryInterval keeps misconfigured intervals from hammering the upstream.
+	minModelDiscoveryInterval = time.Minute
+
+	// modelDiscoveryNamePlaceholder is replaced by the upstream model ID in alias templates.
+	modelDiscoveryNamePlaceholder = "{name}"
+)
+
+// OpenAICompatibilityModelDiscovery configures automatic registration of the models an
+// OpenAI-compatible provider lists on its /models endpoint. Discovered models are added next
+// to the statically configured ones; a configured entry always wins over a discovered one.
+type OpenAICompatibilityModelDiscovery struct {
+	// Enabled turns discovery on for the provider.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// IntervalSeconds is the time between refreshes of the upstream model list (default 3600).
+	IntervalSeconds int `yaml:"interval-seconds,omitempty" json:"interval-seconds,omitempty"`
+
+	// Include optionally restricts discovery to model IDs matching one of these wildcard
+	// patterns ('*' matches any substring). An empty list includes every model.
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+
+	// Exclude drops model IDs matching one of these wildcard patterns; it wins over Include.
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+
+	// AliasTemplate derives the client-facing model name from the upstream ID, for example
+	// "relay-{name}". An empty template exposes models under their upstream ID.
+	AliasTemplate string `yaml:"alias-template,omitempty" json:"alias-template,omitempty"`
+}
+
+// Interval returns the refresh interval with the default applied.
+func (d OpenAICompatibilityModelDiscovery) Interval() time.Duration {
+	if d.IntervalSeconds <= 0 {
+		return DefaultModelDiscoveryInterval
+	}
+	interval := time.Duration(d.IntervalSeconds) * time.Second
+	if interval < minModelDiscoveryInterval {
+		return minModelDiscoveryInterval
+	}
+	return interval
+}
+
+// Alias returns the client-facing name for a discovered upstream model ID.
+func (d OpenAICompatibilityModelDiscovery) Alias(name string) string {
+	if d.AliasTemplate == "" {
+		return name
+	}
+	return strings.ReplaceAll(d.AliasTemplate, modelDiscoveryNamePlaceholder, name)
+}
+
+func (d *OpenAICompatibilityModelDiscovery) sanitize() {
+	d.Include = NormalizeExcludedModels(d.Include)
+	d.Exclude = NormalizeExcludedModels(d.Exclude)
+	d.AliasTemplate = strings.TrimSpace(d.AliasTemplate)
+	if d.AliasTemplate != "" && strings.Count(d.AliasTemplate, modelDiscoveryNamePlaceholder) != 1 {
+		// Without exactly one placeholder the alias cannot be mapped back to the upstream ID.
+		d.AliasTemplate = ""
+	}
+}
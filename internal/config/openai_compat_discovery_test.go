@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestModelDiscoveryAlias(t *testing.T) {
+	discovery := OpenAICompatibilityModelDiscovery{AliasTemplate: "relay-{name}-beta"}
+	alias := discovery.Alias("qwen/qwen3")
+	if alias != "relay-qwen/qwen3-beta" {
+		t.Fatalf("Alias = %q", alias)
+	}
+	if plain := (OpenAICompatibilityModelDiscovery{}).Alias("gpt-4o"); plain != "gpt-4o" {
+		t.Fatalf("Alias without template = %q", plain)
+	}
+}
+
+func TestModelDiscoverySanitize(t *testing.T) {
+	cfg := &Config{OpenAICompatibility: []OpenAICompatibility{{
+		Name:    "relay",
+		BaseURL: "https://relay.example.com/v1",
+		ModelDiscovery: OpenAICompatibilityModelDiscovery{
+			Enabled:         true,
+			IntervalSeconds: 5,
+			Include:         []string{" GPT-* ", "gpt-*", ""},
+			AliasTemplate:   "relay-model",
+		},
+	}}}
+	cfg.SanitizeOpenAICompatibility()
+	discovery := cfg.OpenAICompatibility[0].ModelDiscovery
+	if len(discovery.Include) != 1 || discovery.Include[0] != "gpt-*" {
+		t.Fatalf("Include = %v", discovery.Include)
+	}
+	if discovery.AliasTemplate != "" {
+		t.Fatalf("template without {name} kept: %q", discovery.AliasTemplate)
+	}
+	if discovery.Interval() != time.Minute {
+		t.Fatalf("Interval = %s, want the one minute floor", discovery.Interval())
+	}
+}
@@ -20,11 +20,18 @@ type SDKConfig struct {
 	// APIKeys is a list of keys for authenticating clients to this proxy server.
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 
+	// APIKeyPolicies restricts what individual client API keys may do.
+	// Keys without a policy are unrestricted.
+	APIKeyPolicies []APIKeyPolicy `yaml:"api-key-policies,omitempty" json:"api-key-policies,omitempty"`
+
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
 
 	// Streaming configures server-side streaming behavior (keep-alives and safe bootstrap retries).
 	Streaming StreamingConfig `yaml:"streaming" json:"streaming"`
+
+	// CountTokens tunes the count-tokens endpoints.
+	CountTokens CountTokensConfig `yaml:"count-tokens" json:"count-tokens"`
 }
 
 // StreamingConfig holds server streaming behavior configuration.
@@ -39,6 +46,48 @@ type StreamingConfig struct {
 	BootstrapRetries int `yaml:"bootstrap-retries,omitempty" json:"bootstrap-retries,omitempty"`
 }
 
+// CountTokensConfig tunes the Claude and Gemini count-tokens endpoints.
+type CountTokensConfig struct {
+	// CacheTTLSeconds controls how long the count for an identical payload is reused.
+	// 0 uses the default of 300; a negative value disables the cache.
+	CacheTTLSeconds int `yaml:"cache-ttl-seconds,omitempty" json:"cache-ttl-seconds,omitempty"`
+	// CacheMaxEntries bounds the number of cached counts. <= 0 uses the default of 2000.
+	CacheMaxEntries int `yaml:"cache-max-entries,omitempty" json:"cache-max-entries,omitempty"`
+	// DisableLocalEstimate returns upstream errors instead of a local estimate when no
+	// credential can count the request.
+	DisableLocalEstimate bool `yaml:"disable-local-estimate,omitempty" json:"disable-local-estimate,omitempty"`
+}
+
+// APIKeyPolicy limits the models, providers, request rate and token consumption of one client API key.
+type APIKeyPolicy struct {
+	// APIKey is the client key the policy applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// Name is an optional label used in logs and management responses.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// AllowedModels lists model name globs the key may request ('*' matches any substring).
+	// Empty allows every model.
+	AllowedModels []string `yaml:"allowed-models,omitempty" json:"allowed-models,omitempty"`
+
+	// AllowedProviders lists provider keys (e.g. "gemini", "claude", "openrouter") the key may be routed to.
+	// Empty allows every provider.
+	AllowedProviders []string `yaml:"allowed-providers,omitempty" json:"allowed-providers,omitempty"`
+
+	// AllowedPrefixes lists credential prefixes the key must address (e.g. "teamA" for "teamA/gemini-2.5-pro").
+	// Empty allows unprefixed and prefixed models alike.
+	AllowedPrefixes []string `yaml:"allowed-prefixes,omitempty" json:"allowed-prefixes,omitempty"`
+
+	// RequestsPerMinute caps requests over a sliding one-minute window. <= 0 disables the limit.
+	RequestsPerMinute int `yaml:"requests-per-minute,omitempty" json:"requests-per-minute,omitempty"`
+
+	// TokensPerDay caps total tokens per UTC day. <= 0 disables the limit.
+	TokensPerDay int64 `yaml:"tokens-per-day,omitempty" json:"tokens-per-day,omitempty"`
+
+	// MonthlyTokenBudget caps total tokens per UTC calendar month. <= 0 disables the budget.
+	MonthlyTokenBudget int64 `yaml:"monthly-token-budget,omitempty" json:"monthly-token-budget,omitempty"`
+}
+
 // AccessConfig groups request authentication providers.
 type AccessConfig struct {
 	// Providers lists configured authentication providers.
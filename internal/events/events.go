@@ -0,0 +1,161 @@
+// Package events broadcasts the lifecycle of in-flight requests to live subscribers such as the
+// management request stream. Publishing is cheap when nobody is subscribed.
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// Event types, in the order a request usually produces them.
+const (
+	// TypeStarted fires when a handler starts executing a request.
+	TypeStarted = "started"
+	// TypeAuthSelected fires when the conductor picks a credential for an attempt.
+	TypeAuthSelected = "auth_selected"
+	// TypeFirstByte fires when an upstream stream produces its first chunk.
+	TypeFirstByte = "first_byte"
+	// TypeAttemptFailed fires when an attempt fails; the conductor may rotate to another credential.
+	TypeAttemptFailed = "attempt_failed"
+	// TypeRetry fires when the conductor waits for a credential cooldown before retrying.
+	TypeRetry = "retry"
+	// TypeCompleted fires when a request finished successfully.
+	TypeCompleted = "completed"
+	// TypeError fires when a request failed.
+	TypeError = "error"
+)
+
+// DefaultBufferSize is the per-subscriber queue length. Events beyond it are dropped for that subscriber.
+const DefaultBufferSize = 256
+
+// Event is one step in the lifecycle of a request.
+type Event struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Handler   string    `json:"handler,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	// APIKey is the masked client key; APIKeyHash identifies it for filtering (see util.HashAPIKey).
+	APIKey     string  `json:"api_key,omitempty"`
+	APIKeyHash string  `json:"api_key_hash,omitempty"`
+	Stream     bool    `json:"stream,omitempty"`
+	Provider   string  `json:"provider,omitempty"`
+	AuthID     string  `json:"auth_id,omitempty"`
+	AuthIndex  string  `json:"auth_index,omitempty"`
+	Status     int     `json:"status,omitempty"`
+	LatencyMs  int64   `json:"latency_ms,omitempty"`
+	WaitMs     int64   `json:"wait_ms,omitempty"`
+	Tokens     *Tokens `json:"tokens,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Tokens is the token usage reported with a completed request.
+type Tokens struct {
+	Input     int64 `json:"input"`
+	Output    int64 `json:"output"`
+	Reasoning int64 `json:"reasoning"`
+	Cached    int64 `json:"cached"`
+	Total     int64 `json:"total"`
+}
+
+// Filter narrows a subscription. Empty fields match everything.
+type Filter struct {
+	Model string
+	// APIKey matches a raw client key or its api_key_hash.
+	APIKey string
+
+	keyHash string
+}
+
+func (f Filter) match(event Event) bool {
+	if f.Model != "" && !strings.EqualFold(f.Model, event.Model) {
+		return false
+	}
+	if f.APIKey != "" && event.APIKeyHash != f.APIKey && event.APIKeyHash != f.keyHash {
+		return false
+	}
+	return true
+}
+
+type subscription struct {
+	filter Filter
+	ch     chan Event
+}
+
+// Bus fans events out to subscribers without ever blocking publishers.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	active atomic.Int32
+}
+
+var defaultBus = NewBus()
+
+// Default returns the process-wide bus.
+func Default() *Bus { return defaultBus }
+
+// NewBus creates an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*subscription]struct{})}
+}
+
+// Active reports whether anyone is subscribed, so publishers can skip building events.
+func (b *Bus) Active() bool {
+	return b != nil && b.active.Load() > 0
+}
+
+// Publish delivers event to every matching subscriber. Subscribers that are not keeping up miss the event.
+func (b *Bus) Publish(event Event) {
+	if !b.Active() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.APIKey != "" && event.APIKeyHash == "" {
+		event.APIKeyHash = util.HashAPIKey(event.APIKey)
+		event.APIKey = util.HideAPIKey(event.APIKey)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns its event channel together with a function that
+// unsubscribes and closes the channel.
+func (b *Bus) Subscribe(filter Filter, buffer int) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	if filter.APIKey != "" {
+		filter.keyHash = util.HashAPIKey(filter.APIKey)
+	}
+	sub := &subscription{filter: filter, ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.active.Add(1)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.active.Add(-1)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusFiltersAndUnsubscribes(t *testing.T) {
+	bus := NewBus()
+	if bus.Active() {
+		t.Fatal("new bus reports subscribers")
+	}
+	all, stopAll := bus.Subscribe(Filter{}, 4)
+	claude, stopClaude := bus.Subscribe(Filter{Model: "Claude-Sonnet-4", APIKey: "k1"}, 4)
+	if !bus.Active() {
+		t.Fatal("bus with subscribers reports inactive")
+	}
+
+	bus.Publish(Event{Type: TypeStarted, Model: "gpt-5", APIKey: "k1"})
+	bus.Publish(Event{Type: TypeStarted, Model: "claude-sonnet-4", APIKey: "k2"})
+	bus.Publish(Event{Type: TypeCompleted, Model: "claude-sonnet-4", APIKey: "k1"})
+
+	if got := len(all); got != 3 {
+		t.Fatalf("unfiltered subscriber got %d events, want 3", got)
+	}
+	if got := len(claude); got != 1 {
+		t.Fatalf("filtered subscriber got %d events, want 1", got)
+	}
+	if event := <-claude; event.Type != TypeCompleted || event.Time.IsZero() {
+		t.Fatalf("filtered event = %+v", event)
+	}
+
+	stopClaude()
+	stopClaude()
+	if _, ok := <-claude; ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	stopAll()
+	if bus.Active() {
+		t.Fatal("bus still active after every subscriber left")
+	}
+}
+
+func TestBusDropsEventsForSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	stream, stop := bus.Subscribe(Filter{}, 1)
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			bus.Publish(Event{Type: TypeStarted})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+	if got := len(stream); got != 1 {
+		t.Fatalf("buffered events = %d, want 1", got)
+	}
+}
+
+func TestBusMasksAPIKeys(t *testing.T) {
+	bus := NewBus()
+	byKey, stop := bus.Subscribe(Filter{APIKey: "sk-client-secret-one"}, 4)
+	defer stop()
+
+	bus.Publish(Event{Type: TypeStarted, APIKey: "sk-client-secret-one"})
+	bus.Publish(Event{Type: TypeStarted, APIKey: "sk-client-secret-two"})
+
+	if got := len(byKey); got != 1 {
+		t.Fatalf("key-filtered subscriber got %d events, want 1", got)
+	}
+	if event := <-byKey; event.APIKey != "sk-c...-one" || event.APIKeyHash == "" {
+		t.Fatalf("event key not masked: %+v", event)
+	}
+}
@@ -0,0 +1,231 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const redactedPlaceholder = "[REDACTED]"
+
+// headerLinePattern matches "Name: value" lines inside formatted log sections.
+var headerLinePattern = regexp.MustCompile(`^(\s*)([A-Za-z0-9_-]+):[ \t]*(.*?)(\r?)$`)
+
+// Redactor scrubs configured headers, JSON fields and secret patterns from log content.
+// A nil Redactor leaves content unchanged.
+type Redactor struct {
+	headers  map[string]struct{}
+	paths    [][]string
+	patterns []*regexp.Regexp
+	hash     bool
+}
+
+// NewRedactor compiles redaction rules. It returns nil when no rule is configured.
+func NewRedactor(cfg config.RequestLogRedactionConfig) (*Redactor, error) {
+	r := &Redactor{headers: make(map[string]struct{}), hash: cfg.Hash}
+	for _, header := range cfg.Headers {
+		if header = strings.ToLower(strings.TrimSpace(header)); header != "" {
+			r.headers[header] = struct{}{}
+		}
+	}
+	for _, path := range cfg.JSONPaths {
+		if path = strings.TrimSpace(path); path != "" {
+			r.paths = append(r.paths, strings.Split(path, "."))
+		}
+	}
+	for _, pattern := range cfg.Patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("request-log-redaction: invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, compiled)
+	}
+	if len(r.headers) == 0 && len(r.paths) == 0 && len(r.patterns) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// replacement returns what a redacted value is written as.
+func (r *Redactor) replacement(value string) string {
+	if !r.hash {
+		return redactedPlaceholder
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "[sha256:" + hex.EncodeToString(sum[:6]) + "]"
+}
+
+// Header returns the value to log for a header, reporting whether a rule applied.
+func (r *Redactor) Header(key, value string) (string, bool) {
+	if r == nil {
+		return value, false
+	}
+	if _, ok := r.headers[strings.ToLower(strings.TrimSpace(key))]; ok {
+		return r.replacement(value), true
+	}
+	return value, false
+}
+
+// Redact scrubs a body or a formatted log section. JSON documents have JSON paths applied;
+// other content is processed line by line so header lines and SSE "data:" payloads are covered.
+// Secret patterns apply everywhere.
+func (r *Redactor) Redact(data []byte) []byte {
+	if r == nil || len(data) == 0 {
+		return data
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return r.redactPatterns(r.redactJSON(data))
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = r.redactLine(line)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// Copy streams src to dst, redacting each line. Lines are read whole so SSE payloads keep
+// their JSON intact regardless of how the stream was chunked.
+func (r *Redactor) Copy(dst io.Writer, src io.Reader) error {
+	if r == nil {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			newline := bytes.HasSuffix(line, []byte("\n"))
+			line = r.redactLine(bytes.TrimSuffix(line, []byte("\n")))
+			if newline {
+				line = append(line, '\n')
+			}
+			if _, errWrite := dst.Write(line); errWrite != nil {
+				return errWrite
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Redactor) redactLine(line []byte) []byte {
+	if len(r.headers) > 0 {
+		if m := headerLinePattern.FindSubmatch(line); m != nil {
+			if value, ok := r.Header(string(m[2]), string(m[3])); ok {
+				line = []byte(string(m[1]) + string(m[2]) + ": " + value + string(m[4]))
+			}
+		}
+	}
+	if len(r.paths) > 0 {
+		trimmed := bytes.TrimSpace(line)
+		prefix := []byte(nil)
+		if bytes.HasPrefix(trimmed, []byte("data:")) {
+			prefix = []byte("data: ")
+			trimmed = bytes.TrimSpace(trimmed[len("data:"):])
+		}
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+			line = append(append([]byte{}, prefix...), r.redactJSON(trimmed)...)
+		}
+	}
+	return r.redactPatterns(line)
+}
+
+func (r *Redactor) redactJSON(data []byte) []byte {
+	if len(r.paths) == 0 {
+		return data
+	}
+	root := gjson.ParseBytes(data)
+	for _, segments := range r.paths {
+		var targets []string
+		expandJSONPath(root, segments, "", &targets)
+		for _, target := range targets {
+			value := gjson.GetBytes(data, target)
+			original := value.Raw
+			if value.Type == gjson.String {
+				original = value.String()
+			}
+			if updated, err := sjson.SetBytes(data, target, r.replacement(original)); err == nil {
+				data = updated
+			}
+		}
+		if len(targets) > 0 {
+			root = gjson.ParseBytes(data)
+		}
+	}
+	return data
+}
+
+func (r *Redactor) redactPatterns(data []byte) []byte {
+	for _, pattern := range r.patterns {
+		data = pattern.ReplaceAllFunc(data, func(match []byte) []byte {
+			return []byte(r.replacement(string(match)))
+		})
+	}
+	return data
+}
+
+// expandJSONPath resolves wildcard segments ("#" or "*") into concrete gjson paths.
+func expandJSONPath(node gjson.Result, segments []string, prefix string, out *[]string) {
+	if !node.Exists() {
+		return
+	}
+	if len(segments) == 0 {
+		if prefix != "" {
+			*out = append(*out, prefix)
+		}
+		return
+	}
+	segment, rest := segments[0], segments[1:]
+	if segment == "#" || segment == "*" {
+		if node.IsArray() {
+			for i, element := range node.Array() {
+				expandJSONPath(element, rest, joinJSONPath(prefix, strconv.Itoa(i)), out)
+			}
+		} else if node.IsObject() {
+			node.ForEach(func(key, value gjson.Result) bool {
+				expandJSONPath(value, rest, joinJSONPath(prefix, escapeJSONPathKey(key.String())), out)
+				return true
+			})
+		}
+		return
+	}
+	key := escapeJSONPathKey(segment)
+	expandJSONPath(node.Get(key), rest, joinJSONPath(prefix, key), out)
+}
+
+func joinJSONPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func escapeJSONPathKey(key string) string {
+	var b strings.Builder
+	for _, c := range key {
+		switch c {
+		case '.', '*', '?', '|', '#', '@', '\\', '!', '=', '<', '>', '%':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
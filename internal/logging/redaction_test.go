@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+func TestNewRedactorWithoutRulesIsNil(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{Hash: true})
+	if err != nil || r != nil {
+		t.Fatalf("NewRedactor() = %v, %v; want nil, nil", r, err)
+	}
+	if got := r.Redact([]byte("sk-abc")); string(got) != "sk-abc" {
+		t.Fatalf("nil Redactor changed content: %q", got)
+	}
+}
+
+func TestNewRedactorRejectsInvalidPattern(t *testing.T) {
+	if _, err := NewRedactor(config.RequestLogRedactionConfig{Patterns: []string{"("}}); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func TestRedactorJSONPaths(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{JSONPaths: []string{"messages.#.content", "metadata.user_id"}})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	body := []byte(`{"model":"m","messages":[{"role":"user","content":"secret one"},{"role":"user","content":"secret two"}],"metadata":{"user_id":"u-1"}}`)
+	got := r.Redact(body)
+	for _, path := range []string{"messages.0.content", "messages.1.content", "metadata.user_id"} {
+		if value := gjson.GetBytes(got, path).String(); value != redactedPlaceholder {
+			t.Fatalf("%s = %q, want redacted: %s", path, value, got)
+		}
+	}
+	if gjson.GetBytes(got, "model").String() != "m" {
+		t.Fatalf("unrelated field changed: %s", got)
+	}
+}
+
+func TestRedactorHeadersAndPatterns(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{
+		Headers:  []string{"X-Api-Key"},
+		Patterns: []string{`sk-[A-Za-z0-9]+`},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	if value, ok := r.Header("x-api-key", "abc"); !ok || value != redactedPlaceholder {
+		t.Fatalf("Header() = %q, %v", value, ok)
+	}
+	section := "=== HEADERS ===\nX-Api-Key: abc\nContent-Type: application/json\n\nuse sk-live123 please"
+	got := string(r.Redact([]byte(section)))
+	if strings.Contains(got, "abc") || strings.Contains(got, "sk-live123") {
+		t.Fatalf("secrets survived redaction: %q", got)
+	}
+	if !strings.Contains(got, "Content-Type: application/json") {
+		t.Fatalf("unrelated header changed: %q", got)
+	}
+}
+
+func TestRedactorHashesValues(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{Patterns: []string{`sk-[a-z0-9]+`}, Hash: true})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	first := string(r.Redact([]byte("key sk-one")))
+	second := string(r.Redact([]byte("key sk-one")))
+	other := string(r.Redact([]byte("key sk-two")))
+	if !strings.HasPrefix(first, "key [sha256:") || first != second {
+		t.Fatalf("hash not stable: %q vs %q", first, second)
+	}
+	if first == other {
+		t.Fatalf("different values produced the same hash: %q", first)
+	}
+}
+
+func TestRedactorCopyStreamingData(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{JSONPaths: []string{"delta.text"}})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	stream := "event: delta\ndata: {\"delta\":{\"text\":\"private\"}}\n\ndata: [DONE]\n"
+	var out bytes.Buffer
+	if err = r.Copy(&out, strings.NewReader(stream)); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	got := out.String()
+	if strings.Contains(got, "private") {
+		t.Fatalf("stream payload not redacted: %q", got)
+	}
+	if !strings.Contains(got, "event: delta\n") || !strings.HasSuffix(got, "data: [DONE]\n") {
+		t.Fatalf("stream framing changed: %q", got)
+	}
+}
+
+func TestWriteAPIErrorResponsesRedacts(t *testing.T) {
+	r, err := NewRedactor(config.RequestLogRedactionConfig{Patterns: []string{`sk-[A-Za-z0-9]+`}})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	var buf bytes.Buffer
+	errs := []*interfaces.ErrorMessage{{StatusCode: 401, Error: errors.New(`{"error":"invalid key sk-live123"}`)}}
+	if err = writeAPIErrorResponses(&buf, r, errs); err != nil {
+		t.Fatalf("writeAPIErrorResponses() error = %v", err)
+	}
+	if got := buf.String(); strings.Contains(got, "sk-live123") || !strings.Contains(got, "HTTP Status: 401") {
+		t.Fatalf("API error section = %q", got)
+	}
+}
@@ -0,0 +1,326 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestHistoryFileName is the JSON-lines file that holds the structured request history.
+	RequestHistoryFileName = "requests.jsonl"
+	// RequestHistoryRotatedFileName holds the previous generation after rotation.
+	RequestHistoryRotatedFileName = "requests.1.jsonl"
+
+	defaultRequestHistoryMaxBodyBytes = 4096
+	defaultRequestHistoryMaxSizeMB    = 100
+
+	// requestHistoryReadBlock is how much of a history file is read at a time when scanning
+	// backwards; requestHistoryMaxLine bounds a single record line.
+	requestHistoryReadBlock = 64 << 10
+	requestHistoryMaxLine   = 16 << 20
+)
+
+// ErrInvalidStatusFilter is returned by QueryRequestHistory for a malformed status filter.
+var ErrInvalidStatusFilter = errors.New("invalid status filter")
+
+// RequestRecord is one line of the structured request history.
+type RequestRecord struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	Stream    bool      `json:"stream,omitempty"`
+	// APIKey is the masked client key; APIKeyHash identifies it for filtering (see util.HashAPIKey).
+	APIKey     string        `json:"api_key,omitempty"`
+	APIKeyHash string        `json:"api_key_hash,omitempty"`
+	AuthIndex  string        `json:"auth_index,omitempty"`
+	Provider   string        `json:"provider,omitempty"`
+	Model      string        `json:"model,omitempty"`
+	Cache      string        `json:"cache,omitempty"`
+	Tokens     RequestTokens `json:"tokens"`
+	Request    string        `json:"request,omitempty"`
+	Response   string        `json:"response,omitempty"`
+	Truncated  bool          `json:"truncated,omitempty"`
+}
+
+// RequestTokens is the token usage of a request, summed over every upstream attempt.
+type RequestTokens struct {
+	Input     int64 `json:"input"`
+	Output    int64 `json:"output"`
+	Reasoning int64 `json:"reasoning"`
+	Cached    int64 `json:"cached"`
+	Total     int64 `json:"total"`
+}
+
+// RequestRecorder is implemented by request loggers that keep a structured request history.
+type RequestRecorder interface {
+	// RecordsEnabled reports whether records are currently written.
+	RecordsEnabled() bool
+	// RecordBodyLimit returns how many bytes of each body a record keeps.
+	RecordBodyLimit() int
+	// LogRecord appends a record to the history.
+	LogRecord(record RequestRecord) error
+}
+
+// RequestHistory appends RequestRecords to requests.jsonl with size-based rotation.
+type RequestHistory struct {
+	mu           sync.Mutex
+	dir          string
+	enabled      bool
+	maxBodyBytes int
+	maxSize      int64
+}
+
+// NewRequestHistory creates a disabled history writing into dir.
+func NewRequestHistory(dir string) *RequestHistory {
+	return &RequestHistory{
+		dir:          dir,
+		maxBodyBytes: defaultRequestHistoryMaxBodyBytes,
+		maxSize:      defaultRequestHistoryMaxSizeMB << 20,
+	}
+}
+
+// SetOptions applies configuration. Non-positive limits use the defaults.
+func (h *RequestHistory) SetOptions(cfg config.RequestLogJSONLConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enabled = cfg.Enabled
+	h.maxBodyBytes = cfg.MaxBodyBytes
+	if h.maxBodyBytes <= 0 {
+		h.maxBodyBytes = defaultRequestHistoryMaxBodyBytes
+	}
+	h.maxSize = int64(cfg.MaxSizeMB) << 20
+	if h.maxSize <= 0 {
+		h.maxSize = defaultRequestHistoryMaxSizeMB << 20
+	}
+}
+
+// Enabled reports whether records are written.
+func (h *RequestHistory) Enabled() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.enabled
+}
+
+// MaxBodyBytes returns the per-body truncation limit.
+func (h *RequestHistory) MaxBodyBytes() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.maxBodyBytes
+}
+
+// Append writes record as one JSON line, truncating its bodies and rotating the file when it
+// would exceed the size limit.
+func (h *RequestHistory) Append(record RequestRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.enabled {
+		return nil
+	}
+	if record.APIKey != "" && record.APIKeyHash == "" {
+		record.APIKeyHash = util.HashAPIKey(record.APIKey)
+		record.APIKey = util.HideAPIKey(record.APIKey)
+	}
+	var truncated bool
+	record.Request, truncated = truncateBody(record.Request, h.maxBodyBytes)
+	record.Truncated = record.Truncated || truncated
+	record.Response, truncated = truncateBody(record.Response, h.maxBodyBytes)
+	record.Truncated = record.Truncated || truncated
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("request history: encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err = os.MkdirAll(h.dir, 0o755); err != nil {
+		return fmt.Errorf("request history: create directory: %w", err)
+	}
+	path := filepath.Join(h.dir, RequestHistoryFileName)
+	if info, errStat := os.Stat(path); errStat == nil && info.Size()+int64(len(line)) > h.maxSize {
+		if errRename := os.Rename(path, filepath.Join(h.dir, RequestHistoryRotatedFileName)); errRename != nil {
+			log.Warnf("request history: failed to rotate %s: %v", path, errRename)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("request history: open file: %w", err)
+	}
+	defer func() {
+		if errClose := file.Close(); errClose != nil {
+			log.WithError(errClose).Warn("request history: failed to close file")
+		}
+	}()
+	_, err = file.Write(line)
+	return err
+}
+
+func truncateBody(body string, limit int) (string, bool) {
+	if limit <= 0 || len(body) <= limit {
+		return body, false
+	}
+	cut := limit
+	// Avoid splitting a UTF-8 sequence.
+	for cut > 0 && cut < len(body) && body[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return body[:cut], true
+}
+
+// RequestHistoryQuery filters the request history. Zero values match everything.
+type RequestHistoryQuery struct {
+	From      time.Time
+	To        time.Time
+	RequestID string
+	// Status matches an exact code ("429") or a class ("5xx").
+	Status string
+	Model  string
+	// APIKey matches a raw client key or its api_key_hash.
+	APIKey string
+	Offset int
+	Limit  int
+}
+
+// QueryRequestHistory reads the history in dir newest first and returns one page of matching
+// records, reporting whether more matches follow. Files are scanned backwards and the scan
+// stops as soon as the page is full.
+func QueryRequestHistory(dir string, query RequestHistoryQuery) ([]RequestRecord, bool, error) {
+	matchStatus, err := parseStatusFilter(query.Status)
+	if err != nil {
+		return nil, false, err
+	}
+	keyHash := ""
+	if query.APIKey != "" {
+		keyHash = util.HashAPIKey(query.APIKey)
+	}
+	skip := max(query.Offset, 0)
+	var page []RequestRecord
+	more := false
+	visit := func(record RequestRecord) bool {
+		if !query.From.IsZero() && record.Timestamp.Before(query.From) {
+			return true
+		}
+		if !query.To.IsZero() && record.Timestamp.After(query.To) {
+			return true
+		}
+		if query.RequestID != "" && record.RequestID != query.RequestID {
+			return true
+		}
+		if query.Model != "" && !strings.EqualFold(record.Model, query.Model) {
+			return true
+		}
+		if keyHash != "" && record.APIKeyHash != keyHash && record.APIKeyHash != query.APIKey {
+			return true
+		}
+		if !matchStatus(record.Status) {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if query.Limit > 0 && len(page) == query.Limit {
+			more = true
+			return false
+		}
+		page = append(page, record)
+		return true
+	}
+	for _, name := range []string{RequestHistoryFileName, RequestHistoryRotatedFileName} {
+		done, errScan := scanRequestHistoryReverse(filepath.Join(dir, name), visit)
+		if errScan != nil {
+			return nil, false, errScan
+		}
+		if done {
+			break
+		}
+	}
+	return page, more, nil
+}
+
+// scanRequestHistoryReverse visits the records of a history file from the last line to the
+// first until visit returns false, and reports whether it was stopped early.
+func scanRequestHistoryReverse(path string, visit func(RequestRecord) bool) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("request history: open %s: %w", filepath.Base(path), err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("request history: stat %s: %w", filepath.Base(path), err)
+	}
+	visitLine := func(line []byte) bool {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			return true
+		}
+		var record RequestRecord
+		if errDecode := json.Unmarshal(line, &record); errDecode != nil {
+			return true
+		}
+		return visit(record)
+	}
+	buf := make([]byte, requestHistoryReadBlock)
+	var partial []byte
+	for offset := info.Size(); offset > 0; {
+		n := min(int64(len(buf)), offset)
+		offset -= n
+		if _, err = file.ReadAt(buf[:n], offset); err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("request history: read %s: %w", filepath.Base(path), err)
+		}
+		// partial holds the start of the line that began before the previous block.
+		block := append(append(make([]byte, 0, int(n)+len(partial)), buf[:n]...), partial...)
+		for {
+			i := bytes.LastIndexByte(block, '\n')
+			if i < 0 {
+				break
+			}
+			if !visitLine(block[i+1:]) {
+				return true, nil
+			}
+			block = block[:i]
+		}
+		if len(block) > requestHistoryMaxLine {
+			return false, fmt.Errorf("request history: read %s: line exceeds %d bytes", filepath.Base(path), requestHistoryMaxLine)
+		}
+		partial = block
+	}
+	return !visitLine(partial), nil
+}
+
+func parseStatusFilter(raw string) (func(int) bool, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return func(int) bool { return true }, nil
+	}
+	if len(raw) == 3 && strings.HasSuffix(raw, "xx") && raw[0] >= '1' && raw[0] <= '5' {
+		class := int(raw[0] - '0')
+		return func(status int) bool { return status/100 == class }, nil
+	}
+	code, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidStatusFilter, raw)
+	}
+	return func(status int) bool { return status == code }, nil
+}
@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+func TestRequestHistoryAppendAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	history := NewRequestHistory(dir)
+	history.SetOptions(config.RequestLogJSONLConfig{Enabled: true})
+
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []RequestRecord{
+		{RequestID: "a", Timestamp: base, Status: 200, Model: "gpt-5", APIKey: "sk-client-one"},
+		{RequestID: "b", Timestamp: base.Add(time.Minute), Status: 429, Model: "gpt-5", APIKey: "sk-client-two"},
+		{RequestID: "c", Timestamp: base.Add(2 * time.Minute), Status: 503, Model: "claude-sonnet-4", APIKey: "sk-client-one"},
+	}
+	for _, record := range records {
+		if err := history.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	got, more, err := QueryRequestHistory(dir, RequestHistoryQuery{})
+	if err != nil {
+		t.Fatalf("QueryRequestHistory() error = %v", err)
+	}
+	if more || len(got) != 3 || got[0].RequestID != "c" {
+		t.Fatalf("query all = %d records (more %v), first %+v; want newest first", len(got), more, got)
+	}
+	if got[0].APIKey == "sk-client-one" || got[0].APIKeyHash == "" {
+		t.Fatalf("stored key is not masked: %+v", got[0])
+	}
+	if _, more, _ = QueryRequestHistory(dir, RequestHistoryQuery{Limit: 2}); !more {
+		t.Fatal("a full page did not report more records")
+	}
+
+	cases := []struct {
+		name  string
+		query RequestHistoryQuery
+		want  []string
+	}{
+		{"status class", RequestHistoryQuery{Status: "5xx"}, []string{"c"}},
+		{"exact status", RequestHistoryQuery{Status: "429"}, []string{"b"}},
+		{"model", RequestHistoryQuery{Model: "GPT-5"}, []string{"b", "a"}},
+		{"api key", RequestHistoryQuery{APIKey: "sk-client-one"}, []string{"c", "a"}},
+		{"api key hash", RequestHistoryQuery{APIKey: util.HashAPIKey("sk-client-two")}, []string{"b"}},
+		{"time range", RequestHistoryQuery{From: base.Add(30 * time.Second), To: base.Add(90 * time.Second)}, []string{"b"}},
+		{"page", RequestHistoryQuery{Offset: 1, Limit: 1}, []string{"b"}},
+		{"past the end", RequestHistoryQuery{Offset: 5, Limit: 1}, nil},
+	}
+	for _, tc := range cases {
+		got, _, err = QueryRequestHistory(dir, tc.query)
+		if err != nil {
+			t.Fatalf("%s: error = %v", tc.name, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, record := range got {
+			ids = append(ids, record.RequestID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%s: got %v, want %v", tc.name, ids, tc.want)
+		}
+	}
+
+	if _, _, err = QueryRequestHistory(dir, RequestHistoryQuery{Status: "bad"}); !errors.Is(err, ErrInvalidStatusFilter) {
+		t.Fatalf("invalid status error = %v", err)
+	}
+}
+
+func TestRequestHistoryTruncatesBodies(t *testing.T) {
+	dir := t.TempDir()
+	history := NewRequestHistory(dir)
+	history.SetOptions(config.RequestLogJSONLConfig{Enabled: true, MaxBodyBytes: 4})
+
+	if err := history.Append(RequestRecord{RequestID: "t", Request: "abcdefgh", Response: "ok"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	got, _, err := QueryRequestHistory(dir, RequestHistoryQuery{})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("QueryRequestHistory() = %v, %v", got, err)
+	}
+	if got[0].Request != "abcd" || got[0].Response != "ok" || !got[0].Truncated {
+		t.Fatalf("record = %+v", got[0])
+	}
+}
+
+func TestRequestHistoryRotatesAndDisables(t *testing.T) {
+	dir := t.TempDir()
+	history := NewRequestHistory(dir)
+	if err := history.Append(RequestRecord{RequestID: "off"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, RequestHistoryFileName)); !os.IsNotExist(err) {
+		t.Fatalf("disabled history wrote a file: %v", err)
+	}
+
+	history.SetOptions(config.RequestLogJSONLConfig{Enabled: true})
+	history.maxSize = 200
+	for _, id := range []string{"r1", "r2", "r3"} {
+		if err := history.Append(RequestRecord{RequestID: id}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, RequestHistoryRotatedFileName)); err != nil {
+		t.Fatalf("history was not rotated: %v", err)
+	}
+	got, _, err := QueryRequestHistory(dir, RequestHistoryQuery{})
+	if err != nil {
+		t.Fatalf("QueryRequestHistory() error = %v", err)
+	}
+	if len(got) < 2 || got[0].RequestID != "r3" {
+		t.Fatalf("records after rotation = %+v", got)
+	}
+}
+
+func TestQueryRequestHistory_ReadsAcrossBlocks(t *testing.T) {
+	dir := t.TempDir()
+	history := NewRequestHistory(dir)
+	history.SetOptions(config.RequestLogJSONLConfig{Enabled: true, MaxBodyBytes: 2048})
+	body := strings.Repeat("x", 1500)
+	for i := 0; i < 100; i++ {
+		if err := history.Append(RequestRecord{RequestID: strconv.Itoa(i), Request: body}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	got, more, err := QueryRequestHistory(dir, RequestHistoryQuery{Offset: 50, Limit: 60})
+	if err != nil {
+		t.Fatalf("QueryRequestHistory() error = %v", err)
+	}
+	if more || len(got) != 50 || got[0].RequestID != "49" || got[49].RequestID != "0" {
+		t.Fatalf("got %d records (more %v), first %+v", len(got), more, got[0].RequestID)
+	}
+}
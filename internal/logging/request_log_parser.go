@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ParsedRequestLog is the inbound request and the client-facing response recovered from a
+// request log file.
+type ParsedRequestLog struct {
+	URL     string
+	Method  string
+	Headers map[string][]string
+	Body    []byte
+
+	// HasResponse reports whether the log contains the response section.
+	HasResponse     bool
+	Status          int
+	ResponseHeaders map[string][]string
+	ResponseBody    []byte
+}
+
+// ParseRequestLog recovers the request and response sections of a log written by FileRequestLogger.
+// Values masked or redacted when the log was written stay masked.
+func ParseRequestLog(data []byte) (*ParsedRequestLog, error) {
+	infoStart := bytes.Index(data, []byte("=== REQUEST INFO ===\n"))
+	bodyMarker := []byte("=== REQUEST BODY ===\n")
+	bodyStart := bytes.Index(data, bodyMarker)
+	if infoStart < 0 || bodyStart < infoStart {
+		return nil, errors.New("not a request log")
+	}
+	parsed := &ParsedRequestLog{Headers: make(map[string][]string)}
+
+	inHeaders := false
+	for _, line := range strings.Split(string(data[infoStart:bodyStart]), "\n") {
+		switch {
+		case line == "=== HEADERS ===":
+			inHeaders = true
+		case inHeaders:
+			if key, value, ok := strings.Cut(line, ": "); ok {
+				parsed.Headers[key] = append(parsed.Headers[key], value)
+			}
+		case strings.HasPrefix(line, "URL: "):
+			parsed.URL = strings.TrimPrefix(line, "URL: ")
+		case strings.HasPrefix(line, "Method: "):
+			parsed.Method = strings.TrimPrefix(line, "Method: ")
+		}
+	}
+	if parsed.URL == "" || parsed.Method == "" {
+		return nil, errors.New("request log has no URL or method")
+	}
+
+	// The body is followed by a blank line and the next section header.
+	rest := data[bodyStart+len(bodyMarker):]
+	if end := bytes.Index(rest, []byte("\n\n=== ")); end >= 0 {
+		parsed.Body = rest[:end]
+	} else {
+		parsed.Body = bytes.TrimRight(rest, "\n")
+	}
+
+	responseMarker := []byte("\n=== RESPONSE ===\n")
+	responseStart := bytes.LastIndex(data, responseMarker)
+	if responseStart < 0 {
+		return parsed, nil
+	}
+	parsed.HasResponse = true
+	parsed.ResponseHeaders = make(map[string][]string)
+	response := data[responseStart+len(responseMarker):]
+	// Status and headers end at the first blank line; the section may have neither.
+	headerEnd, responseBodyStart := 0, 1
+	if !bytes.HasPrefix(response, []byte("\n")) {
+		headerEnd = bytes.Index(response, []byte("\n\n"))
+		if headerEnd < 0 {
+			headerEnd = len(response)
+		}
+		responseBodyStart = headerEnd + 2
+	}
+	for _, line := range strings.Split(string(response[:headerEnd]), "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		if key == "Status" {
+			parsed.Status, _ = strconv.Atoi(value)
+			continue
+		}
+		parsed.ResponseHeaders[key] = append(parsed.ResponseHeaders[key], value)
+	}
+	if responseBodyStart <= len(response) {
+		parsed.ResponseBody = bytes.TrimRight(response[responseBodyStart:], "\n")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestRequestLog(t *testing.T, requestID string, response []byte) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	logger := NewFileRequestLogger(true, dir, "")
+	headers := map[string][]string{
+		"Content-Type": {"application/json"},
+		"X-Trace":      {"a", "b"},
+	}
+	body := []byte("{\"model\":\"gpt-5\",\n\"input\":\"hi\"}")
+	responseHeaders := map[string][]string{"Content-Type": {"application/json"}}
+	if err := logger.LogRequest("/v1/responses?alt=sse", "POST", headers, body, 200, responseHeaders, response, nil, nil, nil, requestID); err != nil {
+		t.Fatalf("LogRequest() error = %v", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*-"+requestID+".log"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one log file, got %v (%v)", matches, err)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	return data
+}
+
+func TestParseRequestLogRoundTrip(t *testing.T) {
+	data := writeTestRequestLog(t, "abc123", []byte(`{"id":"resp_1"}`))
+
+	parsed, err := ParseRequestLog(data)
+	if err != nil {
+		t.Fatalf("ParseRequestLog() error = %v", err)
+	}
+	if parsed.URL != "/v1/responses?alt=sse" || parsed.Method != "POST" {
+		t.Fatalf("URL/Method = %q %q", parsed.URL, parsed.Method)
+	}
+	if got := parsed.Headers["X-Trace"]; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("X-Trace = %v", got)
+	}
+	if string(parsed.Body) != "{\"model\":\"gpt-5\",\n\"input\":\"hi\"}" {
+		t.Fatalf("Body = %q", parsed.Body)
+	}
+	if !parsed.HasResponse || parsed.Status != 200 {
+		t.Fatalf("HasResponse/Status = %v %d", parsed.HasResponse, parsed.Status)
+	}
+	if string(parsed.ResponseBody) != `{"id":"resp_1"}` {
+		t.Fatalf("ResponseBody = %q", parsed.ResponseBody)
+	}
+}
+
+func TestParseRequestLogWithoutResponse(t *testing.T) {
+	data := []byte("=== REQUEST INFO ===\nVersion: dev\nURL: /v1/chat/completions\nMethod: POST\n\n=== HEADERS ===\n\n=== REQUEST BODY ===\n{}\n\n")
+
+	parsed, err := ParseRequestLog(data)
+	if err != nil {
+		t.Fatalf("ParseRequestLog() error = %v", err)
+	}
+	if parsed.HasResponse || len(parsed.Headers) != 0 || string(parsed.Body) != "{}" {
+		t.Fatalf("unexpected parse result: %+v", parsed)
+	}
+}
+
+func TestParseRequestLogRejectsOtherFiles(t *testing.T) {
+	if _, err := ParseRequestLog([]byte("plain text")); err == nil {
+		t.Fatal("expected an error for a file that is not a request log")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 )
@@ -124,6 +125,12 @@ type FileRequestLogger struct {
 
 	// logsDir is the directory where log files are stored.
 	logsDir string
+
+	// redactor scrubs configured secrets before anything is written; nil disables redaction.
+	redactor atomic.Pointer[Redactor]
+
+	// history writes the structured JSON-lines request history.
+	history *RequestHistory
 }
 
 // NewFileRequestLogger creates a new file-based request logger.
@@ -147,6 +154,7 @@ func NewFileRequestLogger(enabled bool, logsDir string, configDir string) *FileR
 	return &FileRequestLogger{
 		enabled: enabled,
 		logsDir: logsDir,
+		history: NewRequestHistory(logsDir),
 	}
 }
 
@@ -167,6 +175,41 @@ func (l *FileRequestLogger) SetEnabled(enabled bool) {
 	l.enabled = enabled
 }
 
+// SetRedaction replaces the redaction rules applied to subsequent logs.
+// Invalid rules leave the previous rules in place.
+func (l *FileRequestLogger) SetRedaction(cfg config.RequestLogRedactionConfig) error {
+	redactor, err := NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	l.redactor.Store(redactor)
+	return nil
+}
+
+// SetHistory applies the structured request history configuration.
+func (l *FileRequestLogger) SetHistory(cfg config.RequestLogJSONLConfig) {
+	l.history.SetOptions(cfg)
+}
+
+// RecordsEnabled reports whether structured request records are written.
+func (l *FileRequestLogger) RecordsEnabled() bool {
+	return l.history.Enabled()
+}
+
+// RecordBodyLimit returns how many bytes of each body a structured record keeps.
+func (l *FileRequestLogger) RecordBodyLimit() int {
+	return l.history.MaxBodyBytes()
+}
+
+// LogRecord redacts the record's bodies and appends it to the structured request history.
+func (l *FileRequestLogger) LogRecord(record RequestRecord) error {
+	if redactor := l.redactor.Load(); redactor != nil {
+		record.Request = string(redactor.Redact([]byte(record.Request)))
+		record.Response = string(redactor.Redact([]byte(record.Response)))
+	}
+	return l.history.Append(record)
+}
+
 // LogRequest logs a complete non-streaming request/response cycle to a file.
 //
 // Parameters:
@@ -210,6 +253,11 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 	}
 	filePath := filepath.Join(l.logsDir, filename)
 
+	redactor := l.redactor.Load()
+	body = redactor.Redact(body)
+	apiRequest = redactor.Redact(apiRequest)
+	apiResponse = redactor.Redact(apiResponse)
+
 	requestBodyPath, errTemp := l.writeRequestBodyTempFile(body)
 	if errTemp != nil {
 		log.WithError(errTemp).Warn("failed to create request body temp file, falling back to direct write")
@@ -227,6 +275,7 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 		// If decompression fails, continue with original response and annotate the log output.
 		responseToWrite = response
 	}
+	responseToWrite = redactor.Redact(responseToWrite)
 
 	logFile, errOpen := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if errOpen != nil {
@@ -235,6 +284,7 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 
 	writeErr := l.writeNonStreamingLog(
 		logFile,
+		redactor,
 		url,
 		method,
 		requestHeaders,
@@ -300,7 +350,8 @@ func (l *FileRequestLogger) LogStreamingRequest(url, method string, headers map[
 		requestHeaders[key] = headerValues
 	}
 
-	requestBodyPath, errTemp := l.writeRequestBodyTempFile(body)
+	redactor := l.redactor.Load()
+	requestBodyPath, errTemp := l.writeRequestBodyTempFile(redactor.Redact(body))
 	if errTemp != nil {
 		return nil, fmt.Errorf("failed to create request body temp file: %w", errTemp)
 	}
@@ -315,6 +366,7 @@ func (l *FileRequestLogger) LogStreamingRequest(url, method string, headers map[
 	// Create streaming writer
 	writer := &FileStreamingLogWriter{
 		logFilePath:      filePath,
+		redactor:         redactor,
 		url:              url,
 		method:           method,
 		timestamp:        time.Now(),
@@ -488,6 +540,7 @@ func (l *FileRequestLogger) writeRequestBodyTempFile(body []byte) (string, error
 
 func (l *FileRequestLogger) writeNonStreamingLog(
 	w io.Writer,
+	redactor *Redactor,
 	url, method string,
 	requestHeaders map[string][]string,
 	requestBody []byte,
@@ -500,23 +553,24 @@ func (l *FileRequestLogger) writeNonStreamingLog(
 	response []byte,
 	decompressErr error,
 ) error {
-	if errWrite := writeRequestInfoWithBody(w, url, method, requestHeaders, requestBody, requestBodyPath, time.Now()); errWrite != nil {
+	if errWrite := writeRequestInfoWithBody(w, redactor, url, method, requestHeaders, requestBody, requestBodyPath, time.Now()); errWrite != nil {
 		return errWrite
 	}
 	if errWrite := writeAPISection(w, "=== API REQUEST ===\n", "=== API REQUEST", apiRequest); errWrite != nil {
 		return errWrite
 	}
-	if errWrite := writeAPIErrorResponses(w, apiResponseErrors); errWrite != nil {
+	if errWrite := writeAPIErrorResponses(w, redactor, apiResponseErrors); errWrite != nil {
 		return errWrite
 	}
 	if errWrite := writeAPISection(w, "=== API RESPONSE ===\n", "=== API RESPONSE", apiResponse); errWrite != nil {
 		return errWrite
 	}
-	return writeResponseSection(w, statusCode, true, responseHeaders, bytes.NewReader(response), decompressErr, true)
+	return writeResponseSection(w, redactor, statusCode, true, responseHeaders, bytes.NewReader(response), decompressErr, true)
 }
 
 func writeRequestInfoWithBody(
 	w io.Writer,
+	redactor *Redactor,
 	url, method string,
 	headers map[string][]string,
 	body []byte,
@@ -547,7 +601,10 @@ func writeRequestInfoWithBody(
 	}
 	for key, values := range headers {
 		for _, value := range values {
-			masked := util.MaskSensitiveHeaderValue(key, value)
+			masked, redacted := redactor.Header(key, value)
+			if !redacted {
+				masked = util.MaskSensitiveHeaderValue(key, value)
+			}
 			if _, errWrite := io.WriteString(w, fmt.Sprintf("%s: %s\n", key, masked)); errWrite != nil {
 				return errWrite
 			}
@@ -615,7 +672,9 @@ func writeAPISection(w io.Writer, sectionHeader string, sectionPrefix string, pa
 	return nil
 }
 
-func writeAPIErrorResponses(w io.Writer, apiResponseErrors []*interfaces.ErrorMessage) error {
+// writeAPIErrorResponses writes the upstream errors; their text often echoes the upstream
+// response body, so it is redacted like the other API sections.
+func writeAPIErrorResponses(w io.Writer, redactor *Redactor, apiResponseErrors []*interfaces.ErrorMessage) error {
 	for i := 0; i < len(apiResponseErrors); i++ {
 		if apiResponseErrors[i] == nil {
 			continue
@@ -627,7 +686,7 @@ func writeAPIErrorResponses(w io.Writer, apiResponseErrors []*interfaces.ErrorMe
 			return errWrite
 		}
 		if apiResponseErrors[i].Error != nil {
-			if _, errWrite := io.WriteString(w, apiResponseErrors[i].Error.Error()); errWrite != nil {
+			if _, errWrite := w.Write(redactor.Redact([]byte(apiResponseErrors[i].Error.Error()))); errWrite != nil {
 				return errWrite
 			}
 		}
@@ -638,7 +697,7 @@ func writeAPIErrorResponses(w io.Writer, apiResponseErrors []*interfaces.ErrorMe
 	return nil
 }
 
-func writeResponseSection(w io.Writer, statusCode int, statusWritten bool, responseHeaders map[string][]string, responseReader io.Reader, decompressErr error, trailingNewline bool) error {
+func writeResponseSection(w io.Writer, redactor *Redactor, statusCode int, statusWritten bool, responseHeaders map[string][]string, responseReader io.Reader, decompressErr error, trailingNewline bool) error {
 	if _, errWrite := io.WriteString(w, "=== RESPONSE ===\n"); errWrite != nil {
 		return errWrite
 	}
@@ -651,6 +710,7 @@ func writeResponseSection(w io.Writer, statusCode int, statusWritten bool, respo
 	if responseHeaders != nil {
 		for key, values := range responseHeaders {
 			for _, value := range values {
+				value, _ = redactor.Header(key, value)
 				if _, errWrite := io.WriteString(w, fmt.Sprintf("%s: %s\n", key, value)); errWrite != nil {
 					return errWrite
 				}
@@ -930,6 +990,9 @@ type FileStreamingLogWriter struct {
 	// logFilePath is the final log file path.
 	logFilePath string
 
+	// redactor scrubs configured secrets when the final log is assembled.
+	redactor *Redactor
+
 	// url is the request URL (masked upstream in middleware).
 	url string
 
@@ -1137,13 +1200,13 @@ func (w *FileStreamingLogWriter) asyncWriter() {
 }
 
 func (w *FileStreamingLogWriter) writeFinalLog(logFile *os.File) error {
-	if errWrite := writeRequestInfoWithBody(logFile, w.url, w.method, w.requestHeaders, nil, w.requestBodyPath, w.timestamp); errWrite != nil {
+	if errWrite := writeRequestInfoWithBody(logFile, w.redactor, w.url, w.method, w.requestHeaders, nil, w.requestBodyPath, w.timestamp); errWrite != nil {
 		return errWrite
 	}
-	if errWrite := writeAPISection(logFile, "=== API REQUEST ===\n", "=== API REQUEST", w.apiRequest); errWrite != nil {
+	if errWrite := writeAPISection(logFile, "=== API REQUEST ===\n", "=== API REQUEST", w.redactor.Redact(w.apiRequest)); errWrite != nil {
 		return errWrite
 	}
-	if errWrite := writeAPISection(logFile, "=== API RESPONSE ===\n", "=== API RESPONSE", w.apiResponse); errWrite != nil {
+	if errWrite := writeAPISection(logFile, "=== API RESPONSE ===\n", "=== API RESPONSE", w.redactor.Redact(w.apiResponse)); errWrite != nil {
 		return errWrite
 	}
 
@@ -1157,7 +1220,15 @@ func (w *FileStreamingLogWriter) writeFinalLog(logFile *os.File) error {
 		}
 	}()
 
-	return writeResponseSection(logFile, w.responseStatus, w.statusWritten, w.responseHeaders, responseBodyFile, nil, false)
+	var responseReader io.Reader = responseBodyFile
+	if w.redactor != nil {
+		// Redact line by line while copying so large streams are never held in memory.
+		pipeReader, pipeWriter := io.Pipe()
+		defer func() { _ = pipeReader.Close() }()
+		go func() { _ = pipeWriter.CloseWithError(w.redactor.Copy(pipeWriter, responseBodyFile)) }()
+		responseReader = pipeReader
+	}
+	return writeResponseSection(logFile, w.redactor, w.responseStatus, w.statusWritten, w.responseHeaders, responseReader, nil, false)
 }
 
 func (w *FileStreamingLogWriter) cleanupTempFiles() {
@@ -0,0 +1,285 @@
+// Package metrics exposes Prometheus metrics for proxied requests, token usage,
+// credential availability, retries and websocket relay sessions.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+const namespace = "cliproxy"
+
+// AuthSource lists the credentials whose availability is exported.
+type AuthSource interface {
+	List() []*coreauth.Auth
+}
+
+// AffinitySource reports session affinity counters. The auth source is exported through it
+// when it implements the interface.
+type AffinitySource interface {
+	SessionAffinityStats() coreauth.SessionAffinityStats
+}
+
+// SessionSource reports the number of connected websocket relay sessions.
+type SessionSource interface {
+	SessionCount() int
+}
+
+var (
+	enabled atomic.Bool
+
+	registry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Upstream executor invocations by provider, model, source format and status.",
+	}, []string{"provider", "model", "format", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Total upstream request latency, including the full stream.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model", "format"})
+
+	timeToFirstByte = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_first_byte_seconds",
+		Help:      "Latency until the first upstream chunk (or the full response for non-streaming calls).",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"provider", "model", "format"})
+
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens reported by upstream usage, by provider, model and token type.",
+	}, []string{"provider", "model", "type"})
+
+	retriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Requests retried after waiting for a credential cooldown.",
+	}, []string{"model"})
+
+	authAvailableDesc = prometheus.NewDesc(namespace+"_auth_available",
+		"Whether a credential can currently serve a model (1) or is disabled or cooling down (0).",
+		[]string{"provider", "auth_index", "model"}, nil)
+
+	authCooldownDesc = prometheus.NewDesc(namespace+"_auth_cooldown_seconds",
+		"Seconds until a cooling down credential becomes available again.",
+		[]string{"provider", "auth_index", "model"}, nil)
+
+	relaySessionsDesc = prometheus.NewDesc(namespace+"_wsrelay_sessions",
+		"Connected websocket relay sessions.", nil, nil)
+
+	affinitySessionsDesc = prometheus.NewDesc(namespace+"_session_affinity_sessions",
+		"Conversations currently pinned to a credential.", nil, nil)
+
+	affinityEventsDesc = prometheus.NewDesc(namespace+"_session_affinity_events_total",
+		"Session affinity pin table events: hit, pin, break and evict.", []string{"event"}, nil)
+
+	sources    sync.RWMutex
+	authSource AuthSource
+	relay      SessionSource
+
+	defaultHook = &hook{}
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal, requestDuration, timeToFirstByte, tokensTotal, retriesTotal,
+		stateCollector{},
+	)
+	coreusage.RegisterPlugin(usagePlugin{})
+}
+
+// SetEnabled toggles metric collection. Collection is skipped entirely while disabled.
+func SetEnabled(v bool) { enabled.Store(v) }
+
+// Enabled reports whether metrics are collected.
+func Enabled() bool { return enabled.Load() }
+
+// SetAuthSource registers the credential source exported as availability gauges.
+func SetAuthSource(src AuthSource) {
+	sources.Lock()
+	authSource = src
+	sources.Unlock()
+}
+
+// SetSessionSource registers the websocket relay whose sessions are counted.
+func SetSessionSource(src SessionSource) {
+	sources.Lock()
+	relay = src
+	sources.Unlock()
+}
+
+// Hook returns the execution hook recording request, latency and retry metrics.
+func Hook() coreauth.ExecutionHook { return defaultHook }
+
+// Handler serves the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+type attempt struct {
+	start      time.Time
+	firstChunk atomic.Bool
+}
+
+// hook implements coreauth.ExecutionHook and coreauth.RetryObserver.
+type hook struct {
+	attempts sync.Map // *coreauth.ExecutionState -> *attempt
+}
+
+func (h *hook) BeforeExecute(_ context.Context, state *coreauth.ExecutionState) {
+	if !enabled.Load() || state == nil {
+		return
+	}
+	h.attempts.Store(state, &attempt{start: time.Now()})
+}
+
+func (h *hook) OnStreamChunk(_ context.Context, state *coreauth.ExecutionState, _ cliproxyexecutor.StreamChunk) {
+	value, ok := h.attempts.Load(state)
+	if !ok {
+		return
+	}
+	a := value.(*attempt)
+	if a.firstChunk.CompareAndSwap(false, true) {
+		provider, model, format := labels(state)
+		timeToFirstByte.WithLabelValues(provider, model, format).Observe(time.Since(a.start).Seconds())
+	}
+}
+
+func (h *hook) AfterExecute(_ context.Context, state *coreauth.ExecutionState, _ cliproxyexecutor.Response, err error) {
+	value, ok := h.attempts.LoadAndDelete(state)
+	if !ok {
+		return
+	}
+	a := value.(*attempt)
+	elapsed := time.Since(a.start).Seconds()
+	provider, model, format := labels(state)
+	if !state.Options.Stream && err == nil {
+		timeToFirstByte.WithLabelValues(provider, model, format).Observe(elapsed)
+	}
+	requestDuration.WithLabelValues(provider, model, format).Observe(elapsed)
+	requestsTotal.WithLabelValues(provider, model, format, statusLabel(err)).Inc()
+}
+
+func (h *hook) OnRetry(_ context.Context, model string, _ int, _ time.Duration) {
+	if !enabled.Load() {
+		return
+	}
+	retriesTotal.WithLabelValues(model).Inc()
+}
+
+func labels(state *coreauth.ExecutionState) (provider, model, format string) {
+	return state.Provider, state.Request.Model, string(state.Options.SourceFormat)
+}
+
+// statusLabel reports "success", the upstream HTTP status code, or "error" when unknown.
+func statusLabel(err error) string {
+	if err == nil {
+		return "success"
+	}
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) && sc != nil && sc.StatusCode() > 0 {
+		return strconv.Itoa(sc.StatusCode())
+	}
+	return "error"
+}
+
+// usagePlugin feeds token counters from the usage pipeline.
+type usagePlugin struct{}
+
+func (usagePlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	if !enabled.Load() {
+		return
+	}
+	detail := record.Detail
+	for _, item := range []struct {
+		kind  string
+		value int64
+	}{
+		{"input", detail.InputTokens},
+		{"output", detail.OutputTokens},
+		{"reasoning", detail.ReasoningTokens},
+		{"cached", detail.CachedTokens},
+		{"total", detail.TotalTokens},
+	} {
+		if item.value > 0 {
+			tokensTotal.WithLabelValues(record.Provider, record.Model, item.kind).Add(float64(item.value))
+		}
+	}
+}
+
+// stateCollector reads credential and relay state at scrape time.
+type stateCollector struct{}
+
+func (stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- authAvailableDesc
+	ch <- authCooldownDesc
+	ch <- relaySessionsDesc
+	ch <- affinitySessionsDesc
+	ch <- affinityEventsDesc
+}
+
+func (stateCollector) Collect(ch chan<- prometheus.Metric) {
+	sources.RLock()
+	auths, sessions := authSource, relay
+	sources.RUnlock()
+
+	if sessions != nil {
+		ch <- prometheus.MustNewConstMetric(relaySessionsDesc, prometheus.GaugeValue, float64(sessions.SessionCount()))
+	}
+	if auths == nil {
+		return
+	}
+	if affinity, ok := auths.(AffinitySource); ok {
+		stats := affinity.SessionAffinityStats()
+		ch <- prometheus.MustNewConstMetric(affinitySessionsDesc, prometheus.GaugeValue, float64(stats.Sessions))
+		for event, value := range map[string]uint64{"hit": stats.Hits, "pin": stats.Pins, "break": stats.Breaks, "evict": stats.Evicted} {
+			ch <- prometheus.MustNewConstMetric(affinityEventsDesc, prometheus.CounterValue, float64(value), event)
+		}
+	}
+	now := time.Now()
+	for _, auth := range auths.List() {
+		if auth == nil {
+			continue
+		}
+		index := auth.EnsureIndex()
+		emit := func(model string, unavailable bool, next time.Time) {
+			available := 1.0
+			if auth.Disabled || (unavailable && (next.IsZero() || next.After(now))) {
+				available = 0
+			}
+			cooldown := 0.0
+			if next.After(now) {
+				cooldown = next.Sub(now).Seconds()
+			}
+			ch <- prometheus.MustNewConstMetric(authAvailableDesc, prometheus.GaugeValue, available, auth.Provider, index, model)
+			ch <- prometheus.MustNewConstMetric(authCooldownDesc, prometheus.GaugeValue, cooldown, auth.Provider, index, model)
+		}
+		emit("", auth.Unavailable, auth.NextRetryAfter)
+		for model, state := range auth.ModelStates {
+			if state == nil {
+				continue
+			}
+			emit(model, state.Unavailable, state.NextRetryAfter)
+		}
+	}
+}
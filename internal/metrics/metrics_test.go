@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+type staticAuths []*coreauth.Auth
+
+func (s staticAuths) List() []*coreauth.Auth { return s }
+
+type statusErr struct{ code int }
+
+func (e statusErr) Error() string   { return "upstream failed" }
+func (e statusErr) StatusCode() int { return e.code }
+
+func TestHookRecordsRequestsAndRetries(t *testing.T) {
+	SetEnabled(true)
+	t.Cleanup(func() { SetEnabled(false) })
+	ctx := context.Background()
+
+	state := &coreauth.ExecutionState{Provider: "gemini", Request: cliproxyexecutor.Request{Model: "metrics-test-model"}, Options: cliproxyexecutor.Options{Stream: true, SourceFormat: "openai"}}
+	Hook().BeforeExecute(ctx, state)
+	Hook().OnStreamChunk(ctx, state, cliproxyexecutor.StreamChunk{})
+	Hook().OnStreamChunk(ctx, state, cliproxyexecutor.StreamChunk{})
+	Hook().AfterExecute(ctx, state, cliproxyexecutor.Response{}, nil)
+
+	failed := &coreauth.ExecutionState{Provider: "gemini", Request: cliproxyexecutor.Request{Model: "metrics-test-model"}, Options: cliproxyexecutor.Options{SourceFormat: "openai"}}
+	Hook().BeforeExecute(ctx, failed)
+	Hook().AfterExecute(ctx, failed, cliproxyexecutor.Response{}, statusErr{code: 429})
+	Hook().(*hook).OnRetry(ctx, "metrics-test-model", 1, time.Second)
+
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues("gemini", "metrics-test-model", "openai", "success")); got != 1 {
+		t.Fatalf("requests_total{status=success} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues("gemini", "metrics-test-model", "openai", "429")); got != 1 {
+		t.Fatalf("requests_total{status=429} = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(timeToFirstByte, namespace+"_time_to_first_byte_seconds"); got != 1 {
+		t.Fatalf("time_to_first_byte series = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(retriesTotal.WithLabelValues("metrics-test-model")); got != 1 {
+		t.Fatalf("retries_total = %v, want 1", got)
+	}
+}
+
+func TestHandlerExposesTokensAndAuthState(t *testing.T) {
+	SetEnabled(true)
+	t.Cleanup(func() {
+		SetEnabled(false)
+		SetAuthSource(nil)
+	})
+
+	usagePlugin{}.HandleUsage(context.Background(), coreusage.Record{Provider: "claude", Model: "metrics-tokens", Detail: coreusage.Detail{InputTokens: 12, OutputTokens: 3}})
+	SetAuthSource(staticAuths{{
+		ID:       "a1",
+		Provider: "claude",
+		ModelStates: map[string]*coreauth.ModelState{
+			"metrics-tokens": {Unavailable: true, NextRetryAfter: time.Now().Add(time.Minute)},
+		},
+	}})
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`cliproxy_tokens_total{model="metrics-tokens",provider="claude",type="input"} 12`,
+		`cliproxy_auth_available{auth_index=`,
+		`model="metrics-tokens",provider="claude"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics output missing %q", want)
+		}
+	}
+}
+
+type affinityAuths struct{ staticAuths }
+
+func (affinityAuths) SessionAffinityStats() coreauth.SessionAffinityStats {
+	return coreauth.SessionAffinityStats{Enabled: true, Sessions: 2, Hits: 5, Pins: 2}
+}
+
+func TestHandlerExposesSessionAffinity(t *testing.T) {
+	SetEnabled(true)
+	t.Cleanup(func() {
+		SetEnabled(false)
+		SetAuthSource(nil)
+	})
+	SetAuthSource(affinityAuths{})
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`cliproxy_session_affinity_sessions 2`,
+		`cliproxy_session_affinity_events_total{event="hit"} 5`,
+		`cliproxy_session_affinity_events_total{event="pin"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics output missing %q", want)
+		}
+	}
+}
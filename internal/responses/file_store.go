@@ -0,0 +1,153 @@
+package responses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pruneInterval limits how often the file store scans its directory for expired records.
+const pruneInterval = time.Hour
+
+// FileStore persists response records as one JSON document per response inside a directory.
+type FileStore struct {
+	dir       string
+	ttl       time.Duration
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewFileStore prepares a directory-backed response store. A non-positive ttl uses DefaultTTL.
+func NewFileStore(dir string, ttl time.Duration) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("response store: directory is required")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("response store: resolve directory: %w", err)
+	}
+	if err = os.MkdirAll(absDir, 0o700); err != nil {
+		return nil, fmt.Errorf("response store: create directory: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &FileStore{dir: absDir, ttl: ttl}, nil
+}
+
+// Name implements Store.
+func (s *FileStore) Name() string { return "file" }
+
+// Dir returns the directory holding response documents.
+func (s *FileStore) Dir() string { return s.dir }
+
+func (s *FileStore) recordPath(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func (s *FileStore) readRecord(path string) (Record, error) {
+	var record Record
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, err
+}
+
+// Get implements Store.
+func (s *FileStore) Get(id string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.recordPath(id)
+	record, err := s.readRecord(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("response store: read %s: %v", path, err)
+		}
+		return Record{}, false
+	}
+	if record.ID != id {
+		return Record{}, false
+	}
+	if time.Since(record.CreatedAt) > s.ttl {
+		_ = os.Remove(path)
+		return Record{}, false
+	}
+	return record, true
+}
+
+// Put implements Store.
+func (s *FileStore) Put(record Record) {
+	if record.ID == "" {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Warnf("response store: encode %s: %v", record.ID, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.recordPath(record.ID)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Warnf("response store: write %s: %v", path, err)
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		log.Warnf("response store: rename %s: %v", path, err)
+		_ = os.Remove(tmp)
+		return
+	}
+	s.pruneLocked(time.Now())
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.recordPath(id)
+	if _, err := s.readRecord(path); err != nil {
+		return false
+	}
+	if err := os.Remove(path); err != nil {
+		log.Warnf("response store: delete %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// pruneLocked removes expired documents at most once per pruneInterval.
+func (s *FileStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Warnf("response store: list %s: %v", s.dir, err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil || now.Sub(info.ModTime()) <= s.ttl {
+			continue
+		}
+		_ = os.Remove(filepath.Join(s.dir, entry.Name()))
+	}
+}
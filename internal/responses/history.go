@@ -0,0 +1,169 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxChainDepth bounds how many previous responses are followed when rebuilding history.
+const maxChainDepth = 512
+
+// NotFoundError reports a response ID that is unknown or has expired.
+type NotFoundError struct {
+	ID string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Previous response with id '%s' not found.", e.ID)
+}
+
+// InputItems returns the request input as a JSON array of items.
+// A plain string input becomes a single user message.
+func InputItems(rawJSON []byte) []byte {
+	input := gjson.GetBytes(rawJSON, "input")
+	switch {
+	case input.IsArray():
+		return []byte(input.Raw)
+	case input.Type == gjson.String:
+		item := []byte(`{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`)
+		item, _ = sjson.SetBytes(item, "content.0.text", input.String())
+		return append(append([]byte{'['}, item...), ']')
+	default:
+		return []byte("[]")
+	}
+}
+
+// ShouldStore reports whether the request asks for its response to be stored.
+// As with the OpenAI API, responses are stored unless store is explicitly false.
+func ShouldStore(rawJSON []byte) bool {
+	store := gjson.GetBytes(rawJSON, "store")
+	return !store.Exists() || store.Type != gjson.False
+}
+
+// History returns the conversation items up to and including the response with the given ID:
+// the input and output items of every response in the previous_response_id chain, oldest first.
+// Only responses created with apiKey are followed.
+// Item IDs are dropped so backends that do not persist items accept the replayed history.
+func History(store Store, id, apiKey string) ([]string, error) {
+	chain := make([]Record, 0, 4)
+	seen := make(map[string]struct{})
+	for next := id; next != ""; {
+		if _, loop := seen[next]; loop || len(chain) >= maxChainDepth {
+			break
+		}
+		seen[next] = struct{}{}
+		record, ok := Lookup(store, next, apiKey)
+		if !ok {
+			return nil, &NotFoundError{ID: next}
+		}
+		chain = append(chain, record)
+		next = record.PreviousResponseID
+	}
+	items := make([]string, 0, len(chain)*2)
+	for i := len(chain) - 1; i >= 0; i-- {
+		appendItems := func(node gjson.Result) {
+			node.ForEach(func(_, item gjson.Result) bool {
+				raw := item.Raw
+				if item.Get("id").Exists() {
+					raw, _ = sjson.Delete(raw, "id")
+				}
+				items = append(items, raw)
+				return true
+			})
+		}
+		appendItems(gjson.ParseBytes(chain[i].Input))
+		appendItems(gjson.GetBytes(chain[i].Response, "output"))
+	}
+	return items, nil
+}
+
+// Expand resolves previous_response_id in a Responses request made with apiKey. It returns the
+// request with the stored history prepended to its input, plus the request's own input items
+// for storing. Requests without previous_response_id are returned unchanged.
+func Expand(store Store, rawJSON []byte, apiKey string) (expanded []byte, own []byte, err error) {
+	own = InputItems(rawJSON)
+	previousID := strings.TrimSpace(gjson.GetBytes(rawJSON, "previous_response_id").String())
+	if previousID == "" {
+		return rawJSON, own, nil
+	}
+	history, err := History(store, previousID, apiKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	gjson.ParseBytes(own).ForEach(func(_, item gjson.Result) bool {
+		history = append(history, item.Raw)
+		return true
+	})
+	expanded, err = sjson.SetRawBytes(rawJSON, "input", []byte("["+strings.Join(history, ",")+"]"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return expanded, own, nil
+}
+
+// NewRecord builds the record for a completed response owned by apiKey. It returns false when
+// the response carries no ID.
+func NewRecord(rawJSON, own, response []byte, apiKey string) (Record, bool) {
+	id := gjson.GetBytes(response, "id").String()
+	if id == "" {
+		return Record{}, false
+	}
+	record := Record{
+		ID:                 id,
+		PreviousResponseID: strings.TrimSpace(gjson.GetBytes(rawJSON, "previous_response_id").String()),
+		Model:              gjson.GetBytes(rawJSON, "model").String(),
+		Input:              json.RawMessage(own),
+		Response:           json.RawMessage(response),
+		CreatedAt:          time.Now(),
+		Owner:              util.HashAPIKey(apiKey),
+	}
+	return record, true
+}
+
+// InputItemsList renders the input_items listing for a stored response. Items without an ID
+// receive a stable synthetic one so pagination cursors work.
+func InputItemsList(record Record, order string, after string, limit int) []byte {
+	items := make([]string, 0)
+	gjson.ParseBytes(record.Input).ForEach(func(key, item gjson.Result) bool {
+		raw := item.Raw
+		if item.Get("id").String() == "" {
+			raw, _ = sjson.Set(raw, "id", fmt.Sprintf("msg_%s_%d", strings.TrimPrefix(record.ID, "resp_"), key.Int()))
+		}
+		items = append(items, raw)
+		return true
+	})
+	if order != "asc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if after != "" {
+		for i, item := range items {
+			if gjson.Get(item, "id").String() == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	out := []byte(`{"object":"list","data":[],"first_id":null,"last_id":null,"has_more":false}`)
+	out, _ = sjson.SetRawBytes(out, "data", []byte("["+strings.Join(items, ",")+"]"))
+	if len(items) > 0 {
+		out, _ = sjson.SetBytes(out, "first_id", gjson.Get(items[0], "id").String())
+		out, _ = sjson.SetBytes(out, "last_id", gjson.Get(items[len(items)-1], "id").String())
+	}
+	out, _ = sjson.SetBytes(out, "has_more", hasMore)
+	return out
+}
@@ -0,0 +1,144 @@
+package responses
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestExpandChainsPreviousResponses(t *testing.T) {
+	store := NewMemoryStore(time.Hour, 1<<20)
+
+	first := []byte(`{"model":"m","input":"hi"}`)
+	expanded, own, err := Expand(store, first, "k1")
+	if err != nil {
+		t.Fatalf("Expand(first): %v", err)
+	}
+	if string(expanded) != string(first) {
+		t.Fatalf("request without previous_response_id was rewritten: %s", expanded)
+	}
+	record, ok := NewRecord(expanded, own, []byte(`{"id":"resp_1","output":[{"id":"msg_a","type":"message","role":"assistant","content":[{"type":"output_text","text":"hello"}]}]}`), "k1")
+	if !ok {
+		t.Fatal("NewRecord(first) returned false")
+	}
+	store.Put(record)
+
+	second := []byte(`{"model":"m","previous_response_id":"resp_1","input":[{"type":"message","role":"user","content":"again"}]}`)
+	expanded, own, err = Expand(store, second, "k1")
+	if err != nil {
+		t.Fatalf("Expand(second): %v", err)
+	}
+	record, _ = NewRecord(expanded, own, []byte(`{"id":"resp_2","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"sure"}]}]}`), "k1")
+	store.Put(record)
+
+	third := []byte(`{"model":"m","previous_response_id":"resp_2","input":"last"}`)
+	expanded, _, err = Expand(store, third, "k1")
+	if err != nil {
+		t.Fatalf("Expand(third): %v", err)
+	}
+	input := gjson.GetBytes(expanded, "input").Array()
+	if len(input) != 5 {
+		t.Fatalf("expanded input has %d items, want 5: %s", len(input), expanded)
+	}
+	if got := input[0].Get("content.0.text").String(); got != "hi" {
+		t.Fatalf("input[0] text = %q", got)
+	}
+	if input[1].Get("id").Exists() {
+		t.Fatalf("replayed output item kept its id: %s", input[1].Raw)
+	}
+	if got := input[3].Get("content.0.text").String(); got != "sure" {
+		t.Fatalf("input[3] text = %q", got)
+	}
+	if got := input[4].Get("content.0.text").String(); got != "last" {
+		t.Fatalf("input[4] text = %q", got)
+	}
+}
+
+func TestExpandUnknownPreviousResponse(t *testing.T) {
+	_, _, err := Expand(NewMemoryStore(time.Hour, 1<<20), []byte(`{"previous_response_id":"resp_missing","input":"x"}`), "k1")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || notFound.ID != "resp_missing" {
+		t.Fatalf("Expand() error = %v, want NotFoundError", err)
+	}
+}
+
+func TestExpandRejectsOtherOwners(t *testing.T) {
+	store := NewMemoryStore(time.Hour, 1<<20)
+	record, _ := NewRecord([]byte(`{"input":"hi"}`), []byte(`[]`), []byte(`{"id":"resp_owned","output":[]}`), "k1")
+	store.Put(record)
+
+	_, _, err := Expand(store, []byte(`{"previous_response_id":"resp_owned","input":"x"}`), "k2")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expand() with another key error = %v, want NotFoundError", err)
+	}
+	if _, ok := Lookup(store, "resp_owned", "k2"); ok {
+		t.Fatal("Lookup() returned a record owned by another key")
+	}
+	if got, ok := Lookup(store, "resp_owned", "k1"); !ok || got.Owner == "k1" {
+		t.Fatalf("Lookup() = %+v, %v, want the record with a hashed owner", got, ok)
+	}
+}
+
+func TestInputItemsListPagination(t *testing.T) {
+	record := Record{ID: "resp_x", Input: []byte(`[{"type":"message","role":"user","content":"a"},{"id":"msg_b","type":"message","role":"user","content":"b"}]`)}
+
+	list := InputItemsList(record, "", "", 1)
+	if got := gjson.GetBytes(list, "data.0.id").String(); got != "msg_b" {
+		t.Fatalf("desc first id = %q", got)
+	}
+	if !gjson.GetBytes(list, "has_more").Bool() {
+		t.Fatalf("has_more = false: %s", list)
+	}
+	next := InputItemsList(record, "", "msg_b", 1)
+	if got := gjson.GetBytes(next, "data.0.id").String(); got != "msg_x_0" {
+		t.Fatalf("next page id = %q", got)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	store.Put(Record{ID: "resp_f", Input: []byte(`[]`), Response: []byte(`{"id":"resp_f"}`), CreatedAt: time.Now()})
+	record, ok := store.Get("resp_f")
+	if !ok || gjson.GetBytes(record.Response, "id").String() != "resp_f" {
+		t.Fatalf("Get() = %+v, %v", record, ok)
+	}
+	if !store.Delete("resp_f") {
+		t.Fatal("Delete() = false")
+	}
+	if _, ok = store.Get("resp_f"); ok {
+		t.Fatal("record still present after Delete")
+	}
+}
+
+func TestMemoryStoreEvictsOldestBeyondMaxBytes(t *testing.T) {
+	store := NewMemoryStore(time.Hour, 100)
+	now := time.Now()
+	put := func(id string, age time.Duration) {
+		store.Put(Record{ID: id, Response: []byte(strings.Repeat("x", 36)), CreatedAt: now.Add(-age)})
+	}
+	put("r1", 3*time.Minute)
+	put("r2", 2*time.Minute)
+	put("r3", time.Minute)
+	if _, ok := store.Get("r1"); ok {
+		t.Fatal("oldest record survived exceeding max bytes")
+	}
+	for _, id := range []string{"r2", "r3"} {
+		if _, ok := store.Get(id); !ok {
+			t.Fatalf("record %s was evicted", id)
+		}
+	}
+	store.Put(Record{ID: "huge", Response: []byte(strings.Repeat("x", 200)), CreatedAt: now})
+	if _, ok := store.Get("huge"); ok {
+		t.Fatal("record larger than the store was kept")
+	}
+	if _, ok := store.Get("r3"); !ok {
+		t.Fatal("oversized record evicted existing records")
+	}
+}
@@ -0,0 +1,192 @@
+// Package responses implements the proxy-side store behind the stateful OpenAI Responses API.
+// Completed responses are kept with the input items that produced them so later requests can
+// reference them through previous_response_id regardless of which backend serves the model.
+package responses
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+const (
+	// DefaultTTL is how long stored responses are kept when no TTL is configured.
+	DefaultTTL = 30 * 24 * time.Hour
+	// DefaultMaxBytes bounds the total size of the in-memory store when no limit is configured.
+	DefaultMaxBytes = 256 << 20
+)
+
+// Record is a stored response together with the input items of the request that produced it.
+type Record struct {
+	ID                 string `json:"id"`
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+	Model              string `json:"model,omitempty"`
+	// Input holds the request's own input items; earlier turns are reached through PreviousResponseID.
+	Input json.RawMessage `json:"input"`
+	// Response is the response object as returned to the client.
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+	// Owner is the hash of the client API key that created the response (see util.HashAPIKey).
+	Owner string `json:"owner,omitempty"`
+}
+
+// OwnedBy reports whether the record was created with the given client API key.
+func (r Record) OwnedBy(apiKey string) bool {
+	return r.Owner == util.HashAPIKey(apiKey)
+}
+
+// Store persists response records keyed by response ID.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Name identifies the backend (e.g. "memory", "file", "postgres").
+	Name() string
+	// Get returns the record stored under id.
+	Get(id string) (Record, bool)
+	// Put stores or replaces a record.
+	Put(record Record)
+	// Delete removes a record and reports whether it existed.
+	Delete(id string) bool
+}
+
+type storeHolder struct {
+	store Store
+}
+
+var (
+	storeValue   atomic.Value
+	defaultStore = NewMemoryStore(DefaultTTL, DefaultMaxBytes)
+)
+
+// SetStore replaces the active response store; nil restores the default in-memory store.
+func SetStore(store Store) {
+	if store == nil {
+		store = defaultStore
+	}
+	storeValue.Store(storeHolder{store: store})
+}
+
+// ActiveStore returns the response store currently in use.
+func ActiveStore() Store {
+	if holder, ok := storeValue.Load().(storeHolder); ok && holder.store != nil {
+		return holder.store
+	}
+	return defaultStore
+}
+
+// Lookup returns the record stored under id when it belongs to apiKey. Records owned by
+// another key are reported as missing so their existence is not revealed.
+func Lookup(store Store, id, apiKey string) (Record, bool) {
+	record, ok := store.Get(id)
+	if !ok || !record.OwnedBy(apiKey) {
+		return Record{}, false
+	}
+	return record, true
+}
+
+// MemoryStore keeps response records in process memory, bounded by their total size.
+type MemoryStore struct {
+	mu       sync.Mutex
+	records  map[string]Record
+	bytes    int64
+	ttl      time.Duration
+	maxBytes int64
+}
+
+// NewMemoryStore constructs an in-memory store. Non-positive limits use the defaults.
+func NewMemoryStore(ttl time.Duration, maxBytes int64) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &MemoryStore{records: make(map[string]Record), ttl: ttl, maxBytes: maxBytes}
+}
+
+// Name implements Store.
+func (s *MemoryStore) Name() string { return "memory" }
+
+// Get implements Store.
+func (s *MemoryStore) Get(id string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	if time.Since(record.CreatedAt) > s.ttl {
+		s.removeLocked(id)
+		return Record{}, false
+	}
+	return record, true
+}
+
+// Put implements Store. Records larger than the whole store are not kept.
+func (s *MemoryStore) Put(record Record) {
+	if record.ID == "" {
+		return
+	}
+	size := recordSize(record)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(record.ID)
+	if size > s.maxBytes {
+		return
+	}
+	if s.bytes+size > s.maxBytes {
+		s.evictLocked(time.Now(), s.maxBytes-size)
+	}
+	s.records[record.ID] = record
+	s.bytes += size
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeLocked(id)
+}
+
+func (s *MemoryStore) removeLocked(id string) bool {
+	record, ok := s.records[id]
+	if ok {
+		delete(s.records, id)
+		s.bytes -= recordSize(record)
+	}
+	return ok
+}
+
+// evictLocked drops expired records, then the oldest ones until at most limit bytes remain.
+func (s *MemoryStore) evictLocked(now time.Time, limit int64) {
+	for id, record := range s.records {
+		if now.Sub(record.CreatedAt) > s.ttl {
+			s.removeLocked(id)
+		}
+	}
+	if s.bytes <= limit {
+		return
+	}
+	ids := make([]string, 0, len(s.records))
+	for id := range s.records {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.records[ids[i]].CreatedAt.Before(s.records[ids[j]].CreatedAt)
+	})
+	for _, id := range ids {
+		if s.bytes <= limit {
+			return
+		}
+		s.removeLocked(id)
+	}
+}
+
+// recordSize approximates the memory held by record.
+func recordSize(record Record) int64 {
+	return int64(len(record.ID) + len(record.PreviousResponseID) + len(record.Model) + len(record.Owner) +
+		len(record.Input) + len(record.Response))
+}
@@ -28,6 +28,10 @@ import (
 
 var dataTag = []byte("data:")
 
+// codexClientVersion is the Codex CLI version the executor presents upstream, both in the
+// User-Agent of requests and as client_version when listing models.
+const codexClientVersion = "0.50.0"
+
 // CodexExecutor is a stateless executor for Codex (OpenAI Responses API entrypoint).
 // If api_key is unavailable on auth, it falls back to legacy via ClientAdapter.
 type CodexExecutor struct {
@@ -60,8 +64,8 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, false)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, false)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 	body = ApplyReasoningEffortMetadata(body, req.Metadata, model, "reasoning.effort", false)
 	body = NormalizeThinkingConfig(body, model, false)
 	if errValidate := ValidateThinkingConfig(body, model); errValidate != nil {
@@ -165,8 +169,8 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, true)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), true)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, true)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), true)
 
 	body = ApplyReasoningEffortMetadata(body, req.Metadata, model, "reasoning.effort", false)
 	body = NormalizeThinkingConfig(body, model, false)
@@ -269,7 +273,7 @@ func (e *CodexExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth
 
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("codex")
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 
 	body = ApplyReasoningEffortMetadata(body, req.Metadata, model, "reasoning.effort", false)
 	body, _ = sjson.SetBytes(body, "model", model)
@@ -452,6 +456,40 @@ func (e *CodexExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*
 	return auth, nil
 }
 
+// Probe implements cliproxyauth.HealthProber by listing models, which any valid Codex
+// credential may do without spending quota.
+func (e *CodexExecutor) Probe(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	apiKey, baseURL := codexCreds(auth)
+	if baseURL == "" {
+		baseURL = "https://chatgpt.com/backend-api/codex"
+	}
+	if apiKey == "" {
+		return nil, statusErr{code: http.StatusUnauthorized, msg: "codex executor: missing access token"}
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/models?client_version=" + codexClientVersion
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyCodexHeaders(httpReq, auth, apiKey)
+	httpReq.Header.Del("Content-Type")
+	httpReq.Header.Set("Accept", "application/json")
+	httpResp, err := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0).Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("codex executor: close response body error: %v", errClose)
+		}
+	}()
+	b, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(b)}
+	}
+	return nil, nil
+}
+
 func (e *CodexExecutor) cacheHelper(ctx context.Context, from sdktranslator.Format, url string, req cliproxyexecutor.Request, rawJSON []byte) (*http.Request, error) {
 	var cache codexCache
 	if from == "claude" {
@@ -496,7 +534,7 @@ func applyCodexHeaders(r *http.Request, auth *cliproxyauth.Auth, token string) {
 	misc.EnsureHeader(r.Header, ginHeaders, "Version", "0.21.0")
 	misc.EnsureHeader(r.Header, ginHeaders, "Openai-Beta", "responses=experimental")
 	misc.EnsureHeader(r.Header, ginHeaders, "Session_id", uuid.NewString())
-	misc.EnsureHeader(r.Header, ginHeaders, "User-Agent", "codex_cli_rs/0.50.0 (Mac OS 26.0.1; arm64) Apple_Terminal/464")
+	misc.EnsureHeader(r.Header, ginHeaders, "User-Agent", "codex_cli_rs/"+codexClientVersion+" (Mac OS 26.0.1; arm64) Apple_Terminal/464")
 
 	r.Header.Set("Accept", "text/event-stream")
 	r.Header.Set("Connection", "Keep-Alive")
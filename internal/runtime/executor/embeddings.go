@@ -0,0 +1,370 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Embedding actions carried in Request.Metadata["action"].
+const (
+	// EmbeddingActionOpenAI marks an OpenAI /v1/embeddings request.
+	EmbeddingActionOpenAI = "embeddings"
+	// EmbeddingActionEmbedContent marks a Gemini models/{model}:embedContent request.
+	EmbeddingActionEmbedContent = "embedContent"
+	// EmbeddingActionBatchEmbedContents marks a Gemini models/{model}:batchEmbedContents request.
+	EmbeddingActionBatchEmbedContents = "batchEmbedContents"
+)
+
+// embeddingAction returns the embedding action requested through metadata, or "" for
+// generation requests.
+func embeddingAction(req cliproxyexecutor.Request) string {
+	if req.Metadata == nil {
+		return ""
+	}
+	action, _ := req.Metadata["action"].(string)
+	switch action {
+	case EmbeddingActionOpenAI, EmbeddingActionEmbedContent, EmbeddingActionBatchEmbedContents:
+		return action
+	default:
+		return ""
+	}
+}
+
+// embeddingInput is the provider neutral view of an embedding request.
+type embeddingInput struct {
+	Texts          []string
+	Dimensions     int64
+	TaskType       string
+	Title          string
+	EncodingFormat string
+	// Single reports a Gemini embedContent request, answered with a single embedding object.
+	Single bool
+}
+
+// embeddingOutput carries vectors and the prompt token count reported upstream.
+type embeddingOutput struct {
+	Vectors [][]float64
+	Tokens  int64
+}
+
+// parseEmbeddingRequest reads an OpenAI or Gemini embedding request. Gemini requests are
+// only parsed when they must be converted for a non-Gemini upstream, so parts must be text
+// and per-request options must agree across a batch.
+func parseEmbeddingRequest(format sdktranslator.Format, action string, body []byte) (embeddingInput, error) {
+	var in embeddingInput
+	if !gjson.ValidBytes(body) {
+		return in, statusErr{code: http.StatusBadRequest, msg: "invalid JSON body"}
+	}
+	root := gjson.ParseBytes(body)
+	if format == sdktranslator.FormatGemini {
+		first := true
+		parseRequest := func(node gjson.Result) error {
+			var parts []string
+			var errPart error
+			node.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+				text := part.Get("text")
+				if !text.Exists() {
+					errPart = statusErr{code: http.StatusBadRequest, msg: "only text parts are supported for embeddings on this model"}
+					return false
+				}
+				parts = append(parts, text.String())
+				return true
+			})
+			if errPart != nil {
+				return errPart
+			}
+			in.Texts = append(in.Texts, strings.Join(parts, "\n"))
+			taskType, title, dims := node.Get("taskType").String(), node.Get("title").String(), node.Get("outputDimensionality").Int()
+			if !first && (taskType != in.TaskType || title != in.Title || dims != in.Dimensions) {
+				return statusErr{code: http.StatusBadRequest, msg: "taskType, title and outputDimensionality must match across batch requests on this model"}
+			}
+			first = false
+			in.TaskType, in.Title, in.Dimensions = taskType, title, dims
+			return nil
+		}
+		if action == EmbeddingActionBatchEmbedContents {
+			var errParse error
+			root.Get("requests").ForEach(func(_, node gjson.Result) bool {
+				errParse = parseRequest(node)
+				return errParse == nil
+			})
+			if errParse != nil {
+				return in, errParse
+			}
+		} else {
+			in.Single = true
+			if err := parseRequest(root); err != nil {
+				return in, err
+			}
+		}
+	} else {
+		input := root.Get("input")
+		switch {
+		case input.Type == gjson.String:
+			in.Texts = []string{input.String()}
+		case input.IsArray():
+			for _, item := range input.Array() {
+				if item.Type != gjson.String {
+					return in, statusErr{code: http.StatusBadRequest, msg: "only string inputs are supported for embeddings"}
+				}
+				in.Texts = append(in.Texts, item.String())
+			}
+		}
+		in.Dimensions = root.Get("dimensions").Int()
+		in.EncodingFormat = root.Get("encoding_format").String()
+	}
+	if len(in.Texts) == 0 {
+		return in, statusErr{code: http.StatusBadRequest, msg: "embedding input is empty"}
+	}
+	return in, nil
+}
+
+// isNativeGeminiEmbedding reports whether a Gemini client's embedContent or batchEmbedContents
+// request can be forwarded to a Gemini-family upstream without conversion.
+func isNativeGeminiEmbedding(format sdktranslator.Format, action string) bool {
+	return format == sdktranslator.FormatGemini && (action == EmbeddingActionEmbedContent || action == EmbeddingActionBatchEmbedContents)
+}
+
+// nativeGeminiEmbedBody returns the client's Gemini embedding body with only the per-request
+// model names pointed at the upstream model; everything else is forwarded unchanged.
+func nativeGeminiEmbedBody(action, model string, body []byte) []byte {
+	out := bytes.Clone(body)
+	if action == EmbeddingActionBatchEmbedContents {
+		for i, node := range gjson.GetBytes(out, "requests").Array() {
+			if node.Get("model").Exists() {
+				out, _ = sjson.SetBytes(out, fmt.Sprintf("requests.%d.model", i), "models/"+model)
+			}
+		}
+		return out
+	}
+	if gjson.GetBytes(out, "model").Exists() {
+		out, _ = sjson.SetBytes(out, "model", "models/"+model)
+	}
+	return out
+}
+
+// buildGeminiBatchEmbedRequest renders a Gemini batchEmbedContents body.
+func buildGeminiBatchEmbedRequest(model string, in embeddingInput) []byte {
+	out := []byte(`{"requests":[]}`)
+	for i, text := range in.Texts {
+		item := []byte(`{}`)
+		item, _ = sjson.SetBytes(item, "model", "models/"+model)
+		item, _ = sjson.SetBytes(item, "content.parts.0.text", text)
+		if in.TaskType != "" {
+			item, _ = sjson.SetBytes(item, "taskType", in.TaskType)
+		}
+		if in.Title != "" {
+			item, _ = sjson.SetBytes(item, "title", in.Title)
+		}
+		if in.Dimensions > 0 {
+			item, _ = sjson.SetBytes(item, "outputDimensionality", in.Dimensions)
+		}
+		out, _ = sjson.SetRawBytes(out, fmt.Sprintf("requests.%d", i), item)
+	}
+	return out
+}
+
+// parseGeminiEmbedResponse reads embedContent and batchEmbedContents responses.
+func parseGeminiEmbedResponse(data []byte) embeddingOutput {
+	var out embeddingOutput
+	root := gjson.ParseBytes(data)
+	appendValues := func(node gjson.Result) {
+		values := node.Get("values").Array()
+		vector := make([]float64, len(values))
+		for i, v := range values {
+			vector[i] = v.Float()
+		}
+		out.Vectors = append(out.Vectors, vector)
+	}
+	if single := root.Get("embedding"); single.Exists() {
+		appendValues(single)
+	}
+	root.Get("embeddings").ForEach(func(_, node gjson.Result) bool {
+		appendValues(node)
+		return true
+	})
+	out.Tokens = root.Get("usageMetadata.promptTokenCount").Int()
+	return out
+}
+
+// buildVertexPredictRequest renders a Vertex AI text embedding predict body.
+func buildVertexPredictRequest(in embeddingInput) []byte {
+	out := []byte(`{"instances":[]}`)
+	for i, text := range in.Texts {
+		item := []byte(`{}`)
+		item, _ = sjson.SetBytes(item, "content", text)
+		if in.TaskType != "" {
+			item, _ = sjson.SetBytes(item, "task_type", in.TaskType)
+		}
+		if in.Title != "" {
+			item, _ = sjson.SetBytes(item, "title", in.Title)
+		}
+		out, _ = sjson.SetRawBytes(out, fmt.Sprintf("instances.%d", i), item)
+	}
+	if in.Dimensions > 0 {
+		out, _ = sjson.SetBytes(out, "parameters.outputDimensionality", in.Dimensions)
+	}
+	return out
+}
+
+// parseVertexPredictResponse reads a Vertex AI text embedding predict response.
+func parseVertexPredictResponse(data []byte) embeddingOutput {
+	var out embeddingOutput
+	gjson.GetBytes(data, "predictions").ForEach(func(_, node gjson.Result) bool {
+		values := node.Get("embeddings.values").Array()
+		vector := make([]float64, len(values))
+		for i, v := range values {
+			vector[i] = v.Float()
+		}
+		out.Vectors = append(out.Vectors, vector)
+		out.Tokens += node.Get("embeddings.statistics.token_count").Int()
+		return true
+	})
+	return out
+}
+
+// buildOpenAIEmbeddingRequest renders an OpenAI embeddings body. Vectors are always requested
+// as floats so responses can be re-encoded for any client format.
+func buildOpenAIEmbeddingRequest(model string, in embeddingInput) []byte {
+	out := []byte(`{}`)
+	out, _ = sjson.SetBytes(out, "model", model)
+	out, _ = sjson.SetBytes(out, "input", in.Texts)
+	if in.Dimensions > 0 {
+		out, _ = sjson.SetBytes(out, "dimensions", in.Dimensions)
+	}
+	return out
+}
+
+// parseOpenAIEmbeddingResponse reads an OpenAI embeddings response with float vectors.
+func parseOpenAIEmbeddingResponse(data []byte) embeddingOutput {
+	var out embeddingOutput
+	gjson.GetBytes(data, "data").ForEach(func(_, node gjson.Result) bool {
+		values := node.Get("embedding").Array()
+		vector := make([]float64, len(values))
+		for i, v := range values {
+			vector[i] = v.Float()
+		}
+		out.Vectors = append(out.Vectors, vector)
+		return true
+	})
+	out.Tokens = gjson.GetBytes(data, "usage.prompt_tokens").Int()
+	return out
+}
+
+// renderEmbeddingResponse converts vectors into the client's response format.
+func renderEmbeddingResponse(format sdktranslator.Format, model string, in embeddingInput, out embeddingOutput) []byte {
+	if format == sdktranslator.FormatGemini {
+		if in.Single && len(out.Vectors) > 0 {
+			resp := []byte(`{"embedding":{"values":[]}}`)
+			resp, _ = sjson.SetBytes(resp, "embedding.values", out.Vectors[0])
+			return resp
+		}
+		resp := []byte(`{"embeddings":[]}`)
+		for i, vector := range out.Vectors {
+			resp, _ = sjson.SetBytes(resp, fmt.Sprintf("embeddings.%d.values", i), vector)
+		}
+		return resp
+	}
+	resp := []byte(`{"object":"list","data":[]}`)
+	for i, vector := range out.Vectors {
+		item := []byte(`{"object":"embedding"}`)
+		item, _ = sjson.SetBytes(item, "index", i)
+		if in.EncodingFormat == "base64" {
+			item, _ = sjson.SetBytes(item, "embedding", encodeEmbeddingBase64(vector))
+		} else {
+			item, _ = sjson.SetBytes(item, "embedding", vector)
+		}
+		resp, _ = sjson.SetRawBytes(resp, fmt.Sprintf("data.%d", i), item)
+	}
+	resp, _ = sjson.SetBytes(resp, "model", model)
+	resp, _ = sjson.SetBytes(resp, "usage.prompt_tokens", out.Tokens)
+	resp, _ = sjson.SetBytes(resp, "usage.total_tokens", out.Tokens)
+	return resp
+}
+
+// encodeEmbeddingBase64 matches OpenAI's base64 encoding of little-endian float32 values.
+func encodeEmbeddingBase64(vector []float64) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// postEmbeddingRequest performs the upstream embedding call with request logging and
+// returns the raw response body.
+func postEmbeddingRequest(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, provider, url string, body []byte, prepare func(*http.Request)) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if prepare != nil {
+		prepare(httpReq)
+	}
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    http.MethodPost,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  provider,
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("%s executor: close embedding response body error: %v", provider, errClose)
+		}
+	}()
+	recordAPIResponseMetadata(ctx, cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	appendAPIResponseChunk(ctx, cfg, data)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		log.Debugf("embedding request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(data)}
+	}
+	return data, nil
+}
+
+// publishEmbeddingUsage reports prompt tokens consumed by an embedding call.
+func publishEmbeddingUsage(ctx context.Context, reporter *usageReporter, tokens int64) {
+	if tokens > 0 {
+		reporter.publish(ctx, usage.Detail{InputTokens: tokens, TotalTokens: tokens})
+	}
+	reporter.ensurePublished(ctx)
+}
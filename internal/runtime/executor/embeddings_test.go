@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseEmbeddingRequestOpenAI(t *testing.T) {
+	in, err := parseEmbeddingRequest(sdktranslator.FormatOpenAI, EmbeddingActionOpenAI, []byte(`{"model":"m","input":["a","b"],"dimensions":8,"encoding_format":"base64"}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(in.Texts) != 2 || in.Texts[1] != "b" || in.Dimensions != 8 || in.EncodingFormat != "base64" {
+		t.Fatalf("unexpected input: %+v", in)
+	}
+
+	if _, err = parseEmbeddingRequest(sdktranslator.FormatOpenAI, EmbeddingActionOpenAI, []byte(`{"input":[[1,2,3]]}`)); err == nil {
+		t.Fatal("expected token array input to be rejected")
+	}
+	if _, err = parseEmbeddingRequest(sdktranslator.FormatOpenAI, EmbeddingActionOpenAI, []byte(`{"input":[]}`)); err == nil {
+		t.Fatal("expected empty input to be rejected")
+	}
+}
+
+func TestGeminiBatchEmbedRoundTrip(t *testing.T) {
+	in, err := parseEmbeddingRequest(sdktranslator.FormatOpenAI, EmbeddingActionOpenAI, []byte(`{"input":"hello","dimensions":3}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	body := buildGeminiBatchEmbedRequest("gemini-embedding-001", in)
+	if got := gjson.GetBytes(body, "requests.0.model").String(); got != "models/gemini-embedding-001" {
+		t.Fatalf("model = %q", got)
+	}
+	if got := gjson.GetBytes(body, "requests.0.content.parts.0.text").String(); got != "hello" {
+		t.Fatalf("text = %q", got)
+	}
+	if got := gjson.GetBytes(body, "requests.0.outputDimensionality").Int(); got != 3 {
+		t.Fatalf("outputDimensionality = %d", got)
+	}
+
+	out := parseGeminiEmbedResponse([]byte(`{"embeddings":[{"values":[0.5,-1,0.25]}]}`))
+	resp := renderEmbeddingResponse(sdktranslator.FormatOpenAI, "gemini-embedding-001", in, out)
+	if got := gjson.GetBytes(resp, "data.0.embedding.1").Float(); got != -1 {
+		t.Fatalf("embedding[1] = %v", got)
+	}
+	if got := gjson.GetBytes(resp, "model").String(); got != "gemini-embedding-001" {
+		t.Fatalf("model = %q", got)
+	}
+}
+
+func TestRenderEmbeddingResponseFormats(t *testing.T) {
+	out := embeddingOutput{Vectors: [][]float64{{1.5, -2}}}
+
+	resp := renderEmbeddingResponse(sdktranslator.FormatOpenAI, "m", embeddingInput{EncodingFormat: "base64"}, out)
+	raw, err := base64.StdEncoding.DecodeString(gjson.GetBytes(resp, "data.0.embedding").String())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(raw) != 8 || math.Float32frombits(binary.LittleEndian.Uint32(raw[4:])) != -2 {
+		t.Fatalf("unexpected base64 payload: %v", raw)
+	}
+
+	single := renderEmbeddingResponse(sdktranslator.FormatGemini, "m", embeddingInput{Single: true}, out)
+	if got := gjson.GetBytes(single, "embedding.values.0").Float(); got != 1.5 {
+		t.Fatalf("embedding.values[0] = %v", got)
+	}
+	batch := renderEmbeddingResponse(sdktranslator.FormatGemini, "m", embeddingInput{}, out)
+	if got := gjson.GetBytes(batch, "embeddings.0.values.1").Float(); got != -2 {
+		t.Fatalf("embeddings[0].values[1] = %v", got)
+	}
+}
+
+func TestOpenAICompatExecutorEmbeddingsPassthrough(t *testing.T) {
+	var gotPath, gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotModel = gjson.GetBytes(body, "model").String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":"AAAAAA=="}],"model":"text-embedding-3-small","usage":{"prompt_tokens":2,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{OpenAICompatibility: []config.OpenAICompatibility{{
+		Name:   "compat",
+		Models: []config.OpenAICompatibilityModel{{Name: "text-embedding-3-small", Alias: "embed"}},
+	}}}
+	exec := NewOpenAICompatExecutor("compat", cfg)
+	auth := &cliproxyauth.Auth{Provider: "compat", Attributes: map[string]string{"base_url": server.URL + "/v1", "compat_name": "compat"}}
+	req := cliproxyexecutor.Request{
+		Model:    "embed",
+		Payload:  []byte(`{"model":"embed","input":"hi","encoding_format":"base64"}`),
+		Metadata: map[string]any{"action": EmbeddingActionOpenAI},
+	}
+	resp, err := exec.Execute(context.Background(), auth, req, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if gotPath != "/v1/embeddings" {
+		t.Fatalf("upstream path = %q", gotPath)
+	}
+	if gotModel != "text-embedding-3-small" {
+		t.Fatalf("upstream model = %q", gotModel)
+	}
+	if got := gjson.GetBytes(resp.Payload, "data.0.embedding").String(); got != "AAAAAA==" {
+		t.Fatalf("embedding not passed through: %s", resp.Payload)
+	}
+	if got := gjson.GetBytes(resp.Payload, "model").String(); got != "embed" {
+		t.Fatalf("model = %q", got)
+	}
+}
+
+func TestParseEmbeddingRequestGeminiRejectsLossyConversions(t *testing.T) {
+	if _, err := parseEmbeddingRequest(sdktranslator.FormatGemini, EmbeddingActionEmbedContent, []byte(`{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":"AA=="}}]}}`)); err == nil {
+		t.Fatal("expected non-text parts to be rejected")
+	}
+	batch := []byte(`{"requests":[{"content":{"parts":[{"text":"a"}]},"taskType":"RETRIEVAL_QUERY"},{"content":{"parts":[{"text":"b"}]},"taskType":"RETRIEVAL_DOCUMENT"}]}`)
+	if _, err := parseEmbeddingRequest(sdktranslator.FormatGemini, EmbeddingActionBatchEmbedContents, batch); err == nil {
+		t.Fatal("expected mixed taskType values to be rejected")
+	}
+}
+
+func TestGeminiExecutorEmbeddingNativePassthrough(t *testing.T) {
+	var gotPath string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.1]},{"values":[0.2]}],"usageMetadata":{"promptTokenCount":4}}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "gemini", Attributes: map[string]string{"api_key": "k", "base_url": server.URL}}
+	payload := []byte(`{"requests":[{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"a"},{"inlineData":{"mimeType":"image/png","data":"AA=="}}]},"taskType":"RETRIEVAL_QUERY"},{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"b"}]},"taskType":"RETRIEVAL_DOCUMENT","title":"doc","outputDimensionality":8}]}`)
+	req := cliproxyexecutor.Request{
+		Model:    "gemini-embedding-001",
+		Payload:  payload,
+		Metadata: map[string]any{"action": EmbeddingActionBatchEmbedContents},
+	}
+	resp, err := exec.Execute(context.Background(), auth, req, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if gotPath != "/v1beta/models/gemini-embedding-001:batchEmbedContents" {
+		t.Fatalf("upstream path = %q", gotPath)
+	}
+	if string(gotBody) != string(payload) {
+		t.Fatalf("body was rewritten: %s", gotBody)
+	}
+	if got := gjson.GetBytes(resp.Payload, "embeddings.1.values.0").Float(); got != 0.2 {
+		t.Fatalf("response not forwarded: %s", resp.Payload)
+	}
+}
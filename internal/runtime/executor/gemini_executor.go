@@ -72,6 +72,9 @@ func (e *GeminiExecutor) PrepareRequest(_ *http.Request, _ *cliproxyauth.Auth) e
 //   - cliproxyexecutor.Response: The response from the API
 //   - error: An error if the request fails
 func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	if action := embeddingAction(req); action != "" {
+		return e.executeEmbedding(ctx, auth, req, opts, action)
+	}
 	apiKey, bearer := geminiCreds(auth)
 
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
@@ -89,8 +92,8 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, false)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, false)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 	body = ApplyThinkingMetadata(body, req.Metadata, model)
 	body = util.ApplyDefaultThinkingIfNeeded(model, body)
 	body = util.NormalizeGeminiThinkingBudget(model, body)
@@ -174,6 +177,54 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	return resp, nil
 }
 
+// executeEmbedding forwards Gemini embedding requests unchanged and serves OpenAI embedding
+// requests through batchEmbedContents.
+func (e *GeminiExecutor) executeEmbedding(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, action string) (resp cliproxyexecutor.Response, err error) {
+	apiKey, bearer := geminiCreds(auth)
+
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	model := req.Model
+	if override := e.resolveUpstreamModel(model, auth); override != "" {
+		model = override
+	}
+
+	prepare := func(httpReq *http.Request) {
+		if apiKey != "" {
+			httpReq.Header.Set("x-goog-api-key", apiKey)
+		} else if bearer != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		applyGeminiHeaders(httpReq, auth)
+	}
+	baseURL := resolveGeminiBaseURL(auth)
+
+	if isNativeGeminiEmbedding(opts.SourceFormat, action) {
+		url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, model, action)
+		data, errPost := postEmbeddingRequest(ctx, e.cfg, auth, e.Identifier(), url, nativeGeminiEmbedBody(action, model, req.Payload), prepare)
+		if errPost != nil {
+			return resp, errPost
+		}
+		publishEmbeddingUsage(ctx, reporter, parseGeminiEmbedResponse(data).Tokens)
+		return cliproxyexecutor.Response{Payload: data}, nil
+	}
+
+	in, err := parseEmbeddingRequest(opts.SourceFormat, action, req.Payload)
+	if err != nil {
+		return resp, err
+	}
+	body := buildGeminiBatchEmbedRequest(model, in)
+	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, model, EmbeddingActionBatchEmbedContents)
+	data, err := postEmbeddingRequest(ctx, e.cfg, auth, e.Identifier(), url, body, prepare)
+	if err != nil {
+		return resp, err
+	}
+	out := parseGeminiEmbedResponse(data)
+	publishEmbeddingUsage(ctx, reporter, out.Tokens)
+	return cliproxyexecutor.Response{Payload: renderEmbeddingResponse(opts.SourceFormat, req.Model, in, out)}, nil
+}
+
 // ExecuteStream performs a streaming request to the Gemini API.
 func (e *GeminiExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
 	apiKey, bearer := geminiCreds(auth)
@@ -192,8 +243,8 @@ func (e *GeminiExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, true)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), true)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, true)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), true)
 	body = ApplyThinkingMetadata(body, req.Metadata, model)
 	body = util.ApplyDefaultThinkingIfNeeded(model, body)
 	body = util.NormalizeGeminiThinkingBudget(model, body)
@@ -310,15 +361,13 @@ func (e *GeminiExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("gemini")
-	translatedReq := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	translatedReq := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 	translatedReq = ApplyThinkingMetadata(translatedReq, req.Metadata, model)
 	translatedReq = util.StripThinkingConfigIfUnsupported(model, translatedReq)
 	translatedReq = fixGeminiImageAspectRatio(model, translatedReq)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
-	translatedReq, _ = sjson.DeleteBytes(translatedReq, "tools")
-	translatedReq, _ = sjson.DeleteBytes(translatedReq, "generationConfig")
-	translatedReq, _ = sjson.DeleteBytes(translatedReq, "safetySettings")
 	translatedReq, _ = sjson.SetBytes(translatedReq, "model", model)
+	translatedReq = geminiCountTokensBody(model, translatedReq)
 
 	baseURL := resolveGeminiBaseURL(auth)
 	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, model, "countTokens")
@@ -66,10 +66,16 @@ func (e *GeminiVertexExecutor) Execute(ctx context.Context, auth *cliproxyauth.A
 		if errCreds != nil {
 			return resp, errCreds
 		}
+		if action := embeddingAction(req); action != "" {
+			return e.executeEmbedding(ctx, auth, req, opts, action, "", "", projectID, location, saJSON)
+		}
 		return e.executeWithServiceAccount(ctx, auth, req, opts, projectID, location, saJSON)
 	}
 
 	// Use API key authentication
+	if action := embeddingAction(req); action != "" {
+		return e.executeEmbedding(ctx, auth, req, opts, action, apiKey, baseURL, "", "", nil)
+	}
 	return e.executeWithAPIKey(ctx, auth, req, opts, apiKey, baseURL)
 }
 
@@ -114,6 +120,68 @@ func (e *GeminiVertexExecutor) Refresh(_ context.Context, auth *cliproxyauth.Aut
 	return auth, nil
 }
 
+// executeEmbedding forwards Gemini embedding requests to the native embedContent or
+// batchEmbedContents method and serves OpenAI requests through the predict endpoint.
+// Service account credentials are used when apiKey is empty.
+func (e *GeminiVertexExecutor) executeEmbedding(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, action, apiKey, baseURL, projectID, location string, saJSON []byte) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	model := req.Model
+	if override := e.resolveUpstreamModel(req.Model, auth); override != "" {
+		model = override
+	}
+
+	native := isNativeGeminiEmbedding(opts.SourceFormat, action)
+	method := "predict"
+	var in embeddingInput
+	var body []byte
+	if native {
+		method = action
+		body = nativeGeminiEmbedBody(action, model, req.Payload)
+	} else {
+		if in, err = parseEmbeddingRequest(opts.SourceFormat, action, req.Payload); err != nil {
+			return resp, err
+		}
+		body = buildVertexPredictRequest(in)
+	}
+
+	var url, token string
+	if apiKey != "" {
+		if baseURL == "" {
+			baseURL = "https://generativelanguage.googleapis.com"
+		}
+		url = fmt.Sprintf("%s/%s/publishers/google/models/%s:%s", baseURL, vertexAPIVersion, model, method)
+	} else {
+		url = fmt.Sprintf("%s/%s/projects/%s/locations/%s/publishers/google/models/%s:%s", vertexBaseURL(location), vertexAPIVersion, projectID, location, model, method)
+		var errTok error
+		token, errTok = vertexAccessToken(ctx, e.cfg, auth, saJSON)
+		if errTok != nil {
+			log.Errorf("vertex executor: access token error: %v", errTok)
+			return resp, statusErr{code: 500, msg: "internal server error"}
+		}
+	}
+
+	data, err := postEmbeddingRequest(ctx, e.cfg, auth, e.Identifier(), url, body, func(httpReq *http.Request) {
+		if apiKey != "" {
+			httpReq.Header.Set("x-goog-api-key", apiKey)
+		} else if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+		applyGeminiHeaders(httpReq, auth)
+	})
+	if err != nil {
+		return resp, err
+	}
+	if native {
+		publishEmbeddingUsage(ctx, reporter, parseGeminiEmbedResponse(data).Tokens)
+		return cliproxyexecutor.Response{Payload: data}, nil
+	}
+	out := parseVertexPredictResponse(data)
+	publishEmbeddingUsage(ctx, reporter, out.Tokens)
+	return cliproxyexecutor.Response{Payload: renderEmbeddingResponse(opts.SourceFormat, req.Model, in, out)}, nil
+}
+
 // executeWithServiceAccount handles authentication using service account credentials.
 // This method contains the original service account authentication logic.
 func (e *GeminiVertexExecutor) executeWithServiceAccount(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, projectID, location string, saJSON []byte) (resp cliproxyexecutor.Response, err error) {
@@ -126,8 +194,8 @@ func (e *GeminiVertexExecutor) executeWithServiceAccount(ctx context.Context, au
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, req.Model, originalPayload, false)
-	body := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, originalPayload, false)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, bytes.Clone(req.Payload), false)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(req.Model, req.Metadata); ok && util.ModelSupportsThinking(req.Model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(req.Model, *budgetOverride)
@@ -234,8 +302,8 @@ func (e *GeminiVertexExecutor) executeWithAPIKey(ctx context.Context, auth *clip
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, false)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, false)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(model, req.Metadata); ok && util.ModelSupportsThinking(model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(model, *budgetOverride)
@@ -338,8 +406,8 @@ func (e *GeminiVertexExecutor) executeStreamWithServiceAccount(ctx context.Conte
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, req.Model, originalPayload, true)
-	body := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), true)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, originalPayload, true)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, bytes.Clone(req.Payload), true)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(req.Model, req.Metadata); ok && util.ModelSupportsThinking(req.Model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(req.Model, *budgetOverride)
@@ -463,8 +531,8 @@ func (e *GeminiVertexExecutor) executeStreamWithAPIKey(ctx context.Context, auth
 	if len(opts.OriginalRequest) > 0 {
 		originalPayload = bytes.Clone(opts.OriginalRequest)
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, true)
-	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), true)
+	originalTranslated := sdktranslator.TranslateRequestContext(ctx, from, to, model, originalPayload, true)
+	body := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), true)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(model, req.Metadata); ok && util.ModelSupportsThinking(model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(model, *budgetOverride)
@@ -576,7 +644,7 @@ func (e *GeminiVertexExecutor) executeStreamWithAPIKey(ctx context.Context, auth
 func (e *GeminiVertexExecutor) countTokensWithServiceAccount(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, projectID, location string, saJSON []byte) (cliproxyexecutor.Response, error) {
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("gemini")
-	translatedReq := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+	translatedReq := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, bytes.Clone(req.Payload), false)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(req.Model, req.Metadata); ok && util.ModelSupportsThinking(req.Model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(req.Model, *budgetOverride)
@@ -668,7 +736,7 @@ func (e *GeminiVertexExecutor) countTokensWithAPIKey(ctx context.Context, auth *
 
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("gemini")
-	translatedReq := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), false)
+	translatedReq := sdktranslator.TranslateRequestContext(ctx, from, to, model, bytes.Clone(req.Payload), false)
 	if budgetOverride, includeOverride, ok := util.ResolveThinkingConfigFromMetadata(model, req.Metadata); ok && util.ModelSupportsThinking(model) {
 		if budgetOverride != nil {
 			norm := util.NormalizeThinkingBudget(model, *budgetOverride)
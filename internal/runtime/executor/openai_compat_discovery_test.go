@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestOpenAICompatExecutorDiscoveredModels(t *testing.T) {
+	cfg := &config.Config{OpenAICompatibility: []config.OpenAICompatibility{{
+		Name:           "relay",
+		BaseURL:        "https://relay.example.com/v1",
+		ModelDiscovery: config.OpenAICompatibilityModelDiscovery{Enabled: true},
+	}}}
+	auth := &cliproxyauth.Auth{Provider: "relay", Attributes: map[string]string{
+		"base_url":    "https://relay.example.com/v1",
+		"compat_name": "relay",
+		"api_key":     "sk-relay",
+	}}
+	exec := NewOpenAICompatExecutor("relay", cfg)
+
+	if name := exec.resolveUpstreamModel("qwen3", auth); name != "" {
+		t.Fatalf("resolveUpstreamModel before discovery = %q", name)
+	}
+	if exec.allowCompatReasoningEffort("qwen3", auth) {
+		t.Fatal("reasoning effort allowed for a model that was never discovered")
+	}
+
+	exec.SetDiscoveredModels(func(name string) []config.OpenAICompatibilityModel {
+		if name != "relay" {
+			return nil
+		}
+		return []config.OpenAICompatibilityModel{{Name: "qwen/qwen3", Alias: "relay-qwen/qwen3"}, {Name: "gpt-4o"}}
+	})
+	if name := exec.resolveUpstreamModel("Relay-Qwen/Qwen3", auth); name != "qwen/qwen3" {
+		t.Fatalf("resolveUpstreamModel(alias) = %q", name)
+	}
+	if name := exec.resolveUpstreamModel("gpt-4o", auth); name != "gpt-4o" {
+		t.Fatalf("resolveUpstreamModel(plain) = %q", name)
+	}
+	if !exec.allowCompatReasoningEffort("gpt-4o", auth) {
+		t.Fatal("reasoning effort refused for a discovered model")
+	}
+	for _, model := range []string{"qwen/qwen3", "claude-3-opus"} {
+		if exec.allowCompatReasoningEffort(model, auth) {
+			t.Fatalf("reasoning effort allowed for %q, which discovery did not expose", model)
+		}
+	}
+}
@@ -10,12 +10,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
@@ -23,8 +26,9 @@ import (
 // It performs request/response translation and executes against the provider base URL
 // using per-auth credentials (API key) and per-auth HTTP transport (proxy) from context.
 type OpenAICompatExecutor struct {
-	provider string
-	cfg      *config.Config
+	provider   string
+	cfg        *config.Config
+	discovered func(name string) []config.OpenAICompatibilityModel
 }
 
 // NewOpenAICompatExecutor creates an executor bound to a provider key (e.g., "openrouter").
@@ -32,6 +36,13 @@ func NewOpenAICompatExecutor(provider string, cfg *config.Config) *OpenAICompatE
 	return &OpenAICompatExecutor{provider: provider, cfg: cfg}
 }
 
+// SetDiscoveredModels installs the lookup of the models last discovered for a provider name, so
+// that discovered aliases resolve to their upstream IDs. Without it only configured models resolve.
+func (e *OpenAICompatExecutor) SetDiscoveredModels(lookup func(name string) []config.OpenAICompatibilityModel) *OpenAICompatExecutor {
+	e.discovered = lookup
+	return e
+}
+
 // Identifier implements cliproxyauth.ProviderExecutor.
 func (e *OpenAICompatExecutor) Identifier() string { return e.provider }
 
@@ -41,6 +52,9 @@ func (e *OpenAICompatExecutor) PrepareRequest(_ *http.Request, _ *cliproxyauth.A
 }
 
 func (e *OpenAICompatExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	if action := embeddingAction(req); action != "" {
+		return e.executeEmbedding(ctx, auth, req, opts, action)
+	}
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
 
@@ -50,42 +64,29 @@ func (e *OpenAICompatExecutor) Execute(ctx context.Context, auth *cliproxyauth.A
 		return
 	}
 
-	// Translate inbound request to OpenAI format
+	// Translate inbound request to the upstream protocol format
+	protocol := e.resolveProtocol(auth)
 	from := opts.SourceFormat
-	to := sdktranslator.FromString("openai")
-	originalPayload := bytes.Clone(req.Payload)
-	if len(opts.OriginalRequest) > 0 {
-		originalPayload = bytes.Clone(opts.OriginalRequest)
-	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, req.Model, originalPayload, opts.Stream)
-	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), opts.Stream)
-	modelOverride := e.resolveUpstreamModel(req.Model, auth)
-	if modelOverride != "" {
-		translated = e.overrideModel(translated, modelOverride)
-	}
-	translated = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", translated, originalTranslated)
-	allowCompat := e.allowCompatReasoningEffort(req.Model, auth)
-	translated = ApplyReasoningEffortMetadata(translated, req.Metadata, req.Model, "reasoning_effort", allowCompat)
-	translated = NormalizeThinkingConfig(translated, req.Model, allowCompat)
-	if errValidate := ValidateThinkingConfig(translated, req.Model); errValidate != nil {
-		return resp, errValidate
+	to := compatTargetFormat(protocol)
+	// Responses upstreams always stream; Anthropic upstreams stream unless the client speaks
+	// Claude, which preserves tool calls through the translators.
+	stream := opts.Stream
+	switch protocol {
+	case config.OpenAICompatProtocolResponses:
+		stream = true
+	case config.OpenAICompatProtocolMessages:
+		stream = from != to
+	}
+	translated, err := e.translateRequest(ctx, auth, req, opts, protocol, stream)
+	if err != nil {
+		return resp, err
 	}
 
-	url := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(translated))
+	url := strings.TrimSuffix(baseURL, "/") + compatEndpoint(protocol)
+	httpReq, translated, err := e.newUpstreamRequest(ctx, auth, apiKey, protocol, url, translated, stream && protocol != config.OpenAICompatProtocolChat)
 	if err != nil {
 		return resp, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-	}
-	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
-	var attrs map[string]string
-	if auth != nil {
-		attrs = auth.Attributes
-	}
-	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
 		authID = auth.ID
@@ -129,11 +130,43 @@ func (e *OpenAICompatExecutor) Execute(ctx context.Context, auth *cliproxyauth.A
 		return resp, err
 	}
 	appendAPIResponseChunk(ctx, e.cfg, body)
-	reporter.publish(ctx, parseOpenAIUsage(body))
+
+	var param any
+	switch protocol {
+	case config.OpenAICompatProtocolResponses:
+		// Only the final response.completed event carries the full response.
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			if !bytes.HasPrefix(line, dataTag) {
+				continue
+			}
+			line = bytes.TrimSpace(line[5:])
+			if gjson.GetBytes(line, "type").String() != "response.completed" {
+				continue
+			}
+			if detail, ok := parseCodexUsage(line); ok {
+				reporter.publish(ctx, detail)
+			}
+			out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), translated, line, &param)
+			return cliproxyexecutor.Response{Payload: []byte(out)}, nil
+		}
+		err = statusErr{code: 408, msg: "stream error: stream disconnected before completion: stream closed before response.completed"}
+		return resp, err
+	case config.OpenAICompatProtocolMessages:
+		if stream {
+			for _, line := range bytes.Split(body, []byte("\n")) {
+				if detail, ok := parseClaudeStreamUsage(line); ok {
+					reporter.publish(ctx, detail)
+				}
+			}
+		} else {
+			reporter.publish(ctx, parseClaudeUsage(body))
+		}
+	default:
+		reporter.publish(ctx, parseOpenAIUsage(body))
+	}
 	// Ensure we at least record the request even if upstream doesn't return usage
 	reporter.ensurePublished(ctx)
 	// Translate response back to source format when needed
-	var param any
 	out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), translated, body, &param)
 	resp = cliproxyexecutor.Response{Payload: []byte(out)}
 	return resp, nil
@@ -148,43 +181,19 @@ func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxy
 		err = statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
 		return nil, err
 	}
+	protocol := e.resolveProtocol(auth)
 	from := opts.SourceFormat
-	to := sdktranslator.FromString("openai")
-	originalPayload := bytes.Clone(req.Payload)
-	if len(opts.OriginalRequest) > 0 {
-		originalPayload = bytes.Clone(opts.OriginalRequest)
-	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, req.Model, originalPayload, true)
-	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), true)
-	modelOverride := e.resolveUpstreamModel(req.Model, auth)
-	if modelOverride != "" {
-		translated = e.overrideModel(translated, modelOverride)
-	}
-	translated = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", translated, originalTranslated)
-	allowCompat := e.allowCompatReasoningEffort(req.Model, auth)
-	translated = ApplyReasoningEffortMetadata(translated, req.Metadata, req.Model, "reasoning_effort", allowCompat)
-	translated = NormalizeThinkingConfig(translated, req.Model, allowCompat)
-	if errValidate := ValidateThinkingConfig(translated, req.Model); errValidate != nil {
-		return nil, errValidate
+	to := compatTargetFormat(protocol)
+	translated, err := e.translateRequest(ctx, auth, req, opts, protocol, true)
+	if err != nil {
+		return nil, err
 	}
 
-	url := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(translated))
+	url := strings.TrimSuffix(baseURL, "/") + compatEndpoint(protocol)
+	httpReq, translated, err := e.newUpstreamRequest(ctx, auth, apiKey, protocol, url, translated, true)
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-	}
-	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
-	var attrs map[string]string
-	if auth != nil {
-		attrs = auth.Attributes
-	}
-	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
-	httpReq.Header.Set("Accept", "text/event-stream")
-	httpReq.Header.Set("Cache-Control", "no-cache")
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
 		authID = auth.ID
@@ -220,6 +229,8 @@ func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxy
 		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
 		return nil, err
 	}
+	// Claude clients talking to an Anthropic upstream get the SSE stream forwarded verbatim.
+	passthrough := protocol == config.OpenAICompatProtocolMessages && from == to
 	out := make(chan cliproxyexecutor.StreamChunk)
 	stream = out
 	go func() {
@@ -235,13 +246,37 @@ func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxy
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			appendAPIResponseChunk(ctx, e.cfg, line)
-			if detail, ok := parseOpenAIStreamUsage(line); ok {
-				reporter.publish(ctx, detail)
+			switch protocol {
+			case config.OpenAICompatProtocolResponses:
+				if bytes.HasPrefix(line, dataTag) {
+					data := bytes.TrimSpace(line[5:])
+					if gjson.GetBytes(data, "type").String() == "response.completed" {
+						if detail, ok := parseCodexUsage(data); ok {
+							reporter.publish(ctx, detail)
+						}
+					}
+				}
+			case config.OpenAICompatProtocolMessages:
+				if detail, ok := parseClaudeStreamUsage(line); ok {
+					reporter.publish(ctx, detail)
+				}
+			default:
+				if detail, ok := parseOpenAIStreamUsage(line); ok {
+					reporter.publish(ctx, detail)
+				}
+				if len(line) == 0 {
+					continue
+				}
 			}
-			if len(line) == 0 {
+			if passthrough {
+				// Forward the line as-is to preserve SSE format
+				cloned := make([]byte, len(line)+1)
+				copy(cloned, line)
+				cloned[len(line)] = '\n'
+				out <- cliproxyexecutor.StreamChunk{Payload: cloned}
 				continue
 			}
-			// OpenAI-compatible streams are SSE: lines typically prefixed with "data: ".
+			// Upstream streams are SSE: lines typically prefixed with "data: ".
 			// Pass through translator; it yields one or more chunks for the target schema.
 			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), translated, bytes.Clone(line), &param)
 			for i := range chunks {
@@ -259,10 +294,211 @@ func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxy
 	return stream, nil
 }
 
+// resolveProtocol returns the upstream protocol configured for the auth's provider.
+func (e *OpenAICompatExecutor) resolveProtocol(auth *cliproxyauth.Auth) string {
+	compat := e.resolveCompatConfig(auth)
+	if compat == nil {
+		return config.OpenAICompatProtocolChat
+	}
+	return config.NormalizeOpenAICompatProtocol(compat.Protocol)
+}
+
+// compatTargetFormat returns the translator target for an upstream protocol. Responses
+// upstreams share the Codex translators, which emit and parse the Responses schema.
+func compatTargetFormat(protocol string) sdktranslator.Format {
+	switch protocol {
+	case config.OpenAICompatProtocolResponses:
+		return sdktranslator.FormatCodex
+	case config.OpenAICompatProtocolMessages:
+		return sdktranslator.FormatClaude
+	default:
+		return sdktranslator.FormatOpenAI
+	}
+}
+
+// compatEndpoint returns the path appended to the provider base URL for an upstream protocol.
+func compatEndpoint(protocol string) string {
+	switch protocol {
+	case config.OpenAICompatProtocolResponses:
+		return "/responses"
+	case config.OpenAICompatProtocolMessages:
+		return "/messages"
+	default:
+		return "/chat/completions"
+	}
+}
+
+// codexPreambleText is the message the Claude to Codex translator prepends to the input.
+const codexPreambleText = "EXECUTE ACCORDING TO THE FOLLOWING INSTRUCTIONS!!!"
+
+// translateToPlainResponses converts a non-Responses payload for a plain Responses upstream. It
+// reuses the Codex request translators but drops what only the Codex backend expects: the Codex
+// CLI prompt in instructions and the preamble message. The client's own system prompt becomes
+// instructions instead of a user message.
+func translateToPlainResponses(ctx context.Context, from sdktranslator.Format, model string, payload []byte, stream bool) []byte {
+	instructions, payload := extractSystemPrompt(from, payload)
+	out := sdktranslator.TranslateRequestContext(ctx, from, sdktranslator.FormatCodex, model, payload, stream)
+	if first := gjson.GetBytes(out, "input.0"); first.Get("content.#").Int() == 1 && first.Get("content.0.text").String() == codexPreambleText {
+		out, _ = sjson.DeleteBytes(out, "input.0")
+	}
+	if instructions == "" {
+		out, _ = sjson.DeleteBytes(out, "instructions")
+		return out
+	}
+	out, _ = sjson.SetBytes(out, "instructions", instructions)
+	return out
+}
+
+// extractSystemPrompt removes the system prompt from a Chat Completions or Claude payload and
+// returns its text. Other formats are returned unchanged.
+func extractSystemPrompt(from sdktranslator.Format, payload []byte) (string, []byte) {
+	var parts []string
+	collect := func(content gjson.Result) {
+		if content.Type == gjson.String {
+			if text := content.String(); text != "" {
+				parts = append(parts, text)
+			}
+			return
+		}
+		content.ForEach(func(_, part gjson.Result) bool {
+			if part.Get("type").String() == "text" && part.Get("text").String() != "" {
+				parts = append(parts, part.Get("text").String())
+			}
+			return true
+		})
+	}
+	switch from {
+	case sdktranslator.FormatOpenAI:
+		messages := gjson.GetBytes(payload, "messages")
+		if !messages.IsArray() {
+			return "", payload
+		}
+		kept := []byte(`[]`)
+		messages.ForEach(func(_, message gjson.Result) bool {
+			switch message.Get("role").String() {
+			case "system", "developer":
+				collect(message.Get("content"))
+			default:
+				kept, _ = sjson.SetRawBytes(kept, "-1", []byte(message.Raw))
+			}
+			return true
+		})
+		payload, _ = sjson.SetRawBytes(payload, "messages", kept)
+	case sdktranslator.FormatClaude:
+		system := gjson.GetBytes(payload, "system")
+		if !system.Exists() {
+			return "", payload
+		}
+		collect(system)
+		payload, _ = sjson.DeleteBytes(payload, "system")
+	}
+	return strings.Join(parts, "\n\n"), payload
+}
+
+// translateRequest converts the inbound payload to the upstream protocol and applies the
+// model override, payload rules and reasoning settings.
+func (e *OpenAICompatExecutor) translateRequest(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, protocol string, stream bool) ([]byte, error) {
+	from := opts.SourceFormat
+	to := compatTargetFormat(protocol)
+	originalPayload := bytes.Clone(req.Payload)
+	if len(opts.OriginalRequest) > 0 {
+		originalPayload = bytes.Clone(opts.OriginalRequest)
+	}
+	var originalTranslated, translated []byte
+	if protocol == config.OpenAICompatProtocolResponses && from == sdktranslator.FormatOpenAIResponse {
+		// Responses clients are forwarded as sent; the Codex request translator would replace
+		// their instructions with the Codex CLI prompt.
+		originalTranslated = originalPayload
+		translated = bytes.Clone(req.Payload)
+	} else if protocol == config.OpenAICompatProtocolResponses {
+		originalTranslated = translateToPlainResponses(ctx, from, req.Model, originalPayload, stream)
+		translated = translateToPlainResponses(ctx, from, req.Model, bytes.Clone(req.Payload), stream)
+	} else {
+		originalTranslated = sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, originalPayload, stream)
+		translated = sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, bytes.Clone(req.Payload), stream)
+	}
+	modelOverride := e.resolveUpstreamModel(req.Model, auth)
+	if modelOverride != "" {
+		translated = e.overrideModel(translated, modelOverride)
+	}
+	translated = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", translated, originalTranslated)
+
+	switch protocol {
+	case config.OpenAICompatProtocolMessages:
+		// Anthropic upstreams receive the client's thinking settings unchanged.
+		return translated, nil
+	case config.OpenAICompatProtocolResponses:
+		allowCompat := e.allowCompatReasoningEffort(req.Model, auth)
+		translated = ApplyReasoningEffortMetadata(translated, req.Metadata, req.Model, "reasoning.effort", allowCompat)
+		translated = NormalizeThinkingConfig(translated, req.Model, allowCompat)
+		if errValidate := ValidateThinkingConfig(translated, req.Model); errValidate != nil {
+			return nil, errValidate
+		}
+		translated, _ = sjson.SetBytes(translated, "stream", true)
+		// The Responses handler already expanded previous_response_id from the proxy's response
+		// store; upstream IDs are not shared across credentials, so never forward it.
+		translated, _ = sjson.DeleteBytes(translated, "previous_response_id")
+		return translated, nil
+	default:
+		allowCompat := e.allowCompatReasoningEffort(req.Model, auth)
+		translated = ApplyReasoningEffortMetadata(translated, req.Metadata, req.Model, "reasoning_effort", allowCompat)
+		translated = NormalizeThinkingConfig(translated, req.Model, allowCompat)
+		if errValidate := ValidateThinkingConfig(translated, req.Model); errValidate != nil {
+			return nil, errValidate
+		}
+		return translated, nil
+	}
+}
+
+// newUpstreamRequest builds the upstream POST with the headers the protocol expects. It
+// returns the body actually sent, which differs from body when Anthropic betas are moved
+// into the Anthropic-Beta header.
+func (e *OpenAICompatExecutor) newUpstreamRequest(ctx context.Context, auth *cliproxyauth.Auth, apiKey, protocol, url string, body []byte, stream bool) (*http.Request, []byte, error) {
+	var betas []string
+	if protocol == config.OpenAICompatProtocolMessages {
+		betas, body = extractAndRemoveBetas(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+	if protocol == config.OpenAICompatProtocolMessages {
+		var ginHeaders http.Header
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+			ginHeaders = ginCtx.Request.Header
+		}
+		if apiKey != "" {
+			httpReq.Header.Set("x-api-key", apiKey)
+		}
+		misc.EnsureHeader(httpReq.Header, ginHeaders, "Anthropic-Version", "2023-06-01")
+		if clientBetas := strings.TrimSpace(ginHeaders.Get("Anthropic-Beta")); clientBetas != "" {
+			betas = append([]string{clientBetas}, betas...)
+		}
+		if len(betas) > 0 {
+			httpReq.Header.Set("Anthropic-Beta", strings.Join(betas, ","))
+		}
+	}
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("Cache-Control", "no-cache")
+	}
+	return httpReq, body, nil
+}
+
 func (e *OpenAICompatExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("openai")
-	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+	translated := sdktranslator.TranslateRequestContext(ctx, from, to, req.Model, bytes.Clone(req.Payload), false)
 
 	modelForCounting := req.Model
 	if modelOverride := e.resolveUpstreamModel(req.Model, auth); modelOverride != "" {
@@ -292,6 +528,122 @@ func (e *OpenAICompatExecutor) Refresh(ctx context.Context, auth *cliproxyauth.A
 	return auth, nil
 }
 
+// FetchOpenAICompatModels lists the model IDs served by an OpenAI-compatible provider through
+// its /models endpoint, using the base URL, API key, headers and proxy of the supplied auth.
+func FetchOpenAICompatModels(ctx context.Context, auth *cliproxyauth.Auth, cfg *config.Config) ([]string, error) {
+	exec := &OpenAICompatExecutor{provider: "openai-compatibility", cfg: cfg}
+	baseURL, apiKey := exec.resolveCredentials(auth)
+	if baseURL == "" {
+		return nil, statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+	}
+	modelsURL := strings.TrimSuffix(baseURL, "/") + "/models"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, modelsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+	if exec.resolveProtocol(auth) == config.OpenAICompatProtocolMessages {
+		// Anthropic-style relays list models under the same path but expect their own auth headers.
+		if apiKey != "" {
+			httpReq.Header.Set("x-api-key", apiKey)
+		}
+		httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, auth.Attributes)
+
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("openai compat executor: close models response body error: %v", errClose)
+		}
+	}()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(body)}
+	}
+	// Most relays answer with {"data":[{"id":...}]}; a few return the bare array.
+	list := gjson.GetBytes(body, "data")
+	if !list.Exists() && gjson.ParseBytes(body).IsArray() {
+		list = gjson.ParseBytes(body)
+	}
+	if !list.IsArray() {
+		return nil, fmt.Errorf("openai compat executor: unexpected models response from %s", modelsURL)
+	}
+	ids := make([]string, 0, len(list.Array()))
+	list.ForEach(func(_, item gjson.Result) bool {
+		if id := strings.TrimSpace(item.Get("id").String()); id != "" {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	return ids, nil
+}
+
+// executeEmbedding forwards embedding requests to the upstream /embeddings endpoint.
+// OpenAI requests and responses pass through unchanged apart from the model name;
+// Gemini requests are converted in both directions.
+func (e *OpenAICompatExecutor) executeEmbedding(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, action string) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	baseURL, apiKey := e.resolveCredentials(auth)
+	if baseURL == "" {
+		err = statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+		return
+	}
+	model := req.Model
+	if override := e.resolveUpstreamModel(req.Model, auth); override != "" {
+		model = override
+	}
+
+	native := opts.SourceFormat != sdktranslator.FormatGemini
+	var in embeddingInput
+	var body []byte
+	if native {
+		body = e.overrideModel(bytes.Clone(req.Payload), model)
+	} else {
+		if in, err = parseEmbeddingRequest(opts.SourceFormat, action, req.Payload); err != nil {
+			return resp, err
+		}
+		body = buildOpenAIEmbeddingRequest(model, in)
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/embeddings"
+	data, err := postEmbeddingRequest(ctx, e.cfg, auth, e.Identifier(), url, body, func(httpReq *http.Request) {
+		if apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+		var attrs map[string]string
+		if auth != nil {
+			attrs = auth.Attributes
+		}
+		util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	})
+	if err != nil {
+		return resp, err
+	}
+	reporter.publish(ctx, parseOpenAIUsage(data))
+	reporter.ensurePublished(ctx)
+	if native {
+		data, _ = sjson.SetBytes(data, "model", req.Model)
+		return cliproxyexecutor.Response{Payload: data}, nil
+	}
+	out := parseOpenAIEmbeddingResponse(data)
+	return cliproxyexecutor.Response{Payload: renderEmbeddingResponse(opts.SourceFormat, req.Model, in, out)}, nil
+}
+
 func (e *OpenAICompatExecutor) resolveCredentials(auth *cliproxyauth.Auth) (baseURL, apiKey string) {
 	if auth == nil {
 		return "", ""
@@ -326,18 +678,43 @@ func (e *OpenAICompatExecutor) resolveUpstreamModel(alias string, auth *cliproxy
 			return model.Name
 		}
 	}
+	if name, ok := e.discoveredUpstreamModel(compat, alias); ok {
+		return name
+	}
 	return ""
 }
 
+// discoveredUpstreamModel looks alias up among the models discovered for the provider, which
+// already honour the include/exclude patterns and alias template, and returns the upstream ID.
+func (e *OpenAICompatExecutor) discoveredUpstreamModel(compat *config.OpenAICompatibility, alias string) (string, bool) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || compat == nil || !compat.ModelDiscovery.Enabled || e.discovered == nil {
+		return "", false
+	}
+	for _, model := range e.discovered(compat.Name) {
+		id := model.Alias
+		if id == "" {
+			id = model.Name
+		}
+		if strings.EqualFold(id, alias) {
+			return model.Name, true
+		}
+	}
+	return "", false
+}
+
 func (e *OpenAICompatExecutor) allowCompatReasoningEffort(model string, auth *cliproxyauth.Auth) bool {
 	trimmed := strings.TrimSpace(model)
 	if trimmed == "" || e == nil || e.cfg == nil {
 		return false
 	}
 	compat := e.resolveCompatConfig(auth)
-	if compat == nil || len(compat.Models) == 0 {
+	if compat == nil {
 		return false
 	}
+	if _, ok := e.discoveredUpstreamModel(compat, trimmed); ok {
+		return true
+	}
 	for i := range compat.Models {
 		entry := compat.Models[i]
 		if strings.EqualFold(strings.TrimSpace(entry.Alias), trimmed) {
@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newProtocolTestUpstream(t *testing.T, response string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.path = r.URL.Path
+		captured.header = r.Header.Clone()
+		captured.body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func newProtocolTestExecutor(protocol, baseURL string) (*OpenAICompatExecutor, *cliproxyauth.Auth) {
+	cfg := &config.Config{OpenAICompatibility: []config.OpenAICompatibility{{
+		Name:     "relay",
+		BaseURL:  baseURL,
+		Protocol: protocol,
+		Models:   []config.OpenAICompatibilityModel{{Name: "upstream-model", Alias: "relay-model"}},
+	}}}
+	auth := &cliproxyauth.Auth{Provider: "relay", Attributes: map[string]string{
+		"base_url":    baseURL,
+		"compat_name": "relay",
+		"api_key":     "sk-relay",
+	}}
+	return NewOpenAICompatExecutor("relay", cfg), auth
+}
+
+func TestOpenAICompatExecutorMessagesProtocolPassthrough(t *testing.T) {
+	sse := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":3,\"output_tokens\":0}}}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n"
+	server, captured := newProtocolTestUpstream(t, sse)
+	exec, auth := newProtocolTestExecutor("messages", server.URL+"/v1")
+
+	payload := []byte(`{"model":"relay-model","max_tokens":64,"stream":true,"system":[{"type":"text","text":"be brief","cache_control":{"type":"ephemeral"}}],"messages":[{"role":"user","content":"hi"}]}`)
+	stream, err := exec.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, Stream: true})
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	var got strings.Builder
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		got.Write(chunk.Payload)
+	}
+
+	if captured.path != "/v1/messages" {
+		t.Fatalf("upstream path = %q", captured.path)
+	}
+	if captured.header.Get("x-api-key") != "sk-relay" || captured.header.Get("Anthropic-Version") == "" {
+		t.Fatalf("missing Anthropic headers: %v", captured.header)
+	}
+	if model := gjson.GetBytes(captured.body, "model").String(); model != "upstream-model" {
+		t.Fatalf("upstream model = %q", model)
+	}
+	if !gjson.GetBytes(captured.body, "system.0.cache_control").Exists() {
+		t.Fatalf("cache_control dropped: %s", captured.body)
+	}
+	if got.String() != sse {
+		t.Fatalf("stream not forwarded verbatim:\n%q\nwant\n%q", got.String(), sse)
+	}
+}
+
+func TestOpenAICompatExecutorMessagesProtocolTranslatesChat(t *testing.T) {
+	sse := strings.Join([]string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"upstream-model","usage":{"input_tokens":3,"output_tokens":0}}}`,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hello"}}`,
+		`event: content_block_stop`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`event: message_delta`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+		``,
+	}, "\n")
+	server, captured := newProtocolTestUpstream(t, sse)
+	exec, auth := newProtocolTestExecutor("messages", server.URL+"/v1")
+
+	payload := []byte(`{"model":"relay-model","messages":[{"role":"user","content":"hi"}]}`)
+	resp, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if captured.path != "/v1/messages" || !gjson.GetBytes(captured.body, "max_tokens").Exists() {
+		t.Fatalf("upstream did not receive a Messages request: %s %s", captured.path, captured.body)
+	}
+	if text := gjson.GetBytes(resp.Payload, "choices.0.message.content").String(); text != "hello" {
+		t.Fatalf("response not translated to chat completions: %s", resp.Payload)
+	}
+}
+
+func TestOpenAICompatExecutorResponsesProtocol(t *testing.T) {
+	sse := "event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_1\"}}\n\nevent: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\",\"output\":[{\"type\":\"reasoning\",\"encrypted_content\":\"abc\"}],\"usage\":{\"input_tokens\":5,\"output_tokens\":2,\"total_tokens\":7}}}\n\n"
+	server, captured := newProtocolTestUpstream(t, sse)
+	exec, auth := newProtocolTestExecutor("responses", server.URL+"/v1")
+
+	payload := []byte(`{"model":"relay-model","instructions":"be brief","previous_response_id":"resp_0","input":"hi"}`)
+	resp, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAIResponse, OriginalRequest: payload})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if captured.path != "/v1/responses" {
+		t.Fatalf("upstream path = %q", captured.path)
+	}
+	body := gjson.ParseBytes(captured.body)
+	if !body.Get("stream").Bool() || body.Get("previous_response_id").Exists() {
+		t.Fatalf("unexpected upstream body: %s", captured.body)
+	}
+	if body.Get("instructions").String() != "be brief" || body.Get("model").String() != "upstream-model" {
+		t.Fatalf("request not forwarded as sent: %s", captured.body)
+	}
+	if got := gjson.GetBytes(resp.Payload, "output.0.encrypted_content").String(); got != "abc" {
+		t.Fatalf("reasoning item lost: %s", resp.Payload)
+	}
+}
+
+func TestOpenAICompatExecutorResponsesProtocolTranslatesChat(t *testing.T) {
+	sse := "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"hello\"}]}]}}\n\n"
+	server, captured := newProtocolTestUpstream(t, sse)
+	exec, auth := newProtocolTestExecutor("responses", server.URL+"/v1")
+
+	payload := []byte(`{"model":"relay-model","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}]}`)
+	if _, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	body := gjson.ParseBytes(captured.body)
+	if got := body.Get("instructions").String(); got != "be brief" {
+		t.Fatalf("instructions = %q, want the client's system prompt", got)
+	}
+	input := body.Get("input").Array()
+	if len(input) != 1 || input[0].Get("role").String() != "user" || input[0].Get("content.0.text").String() != "hi" {
+		t.Fatalf("unexpected input: %s", body.Get("input").Raw)
+	}
+}
+
+func TestOpenAICompatExecutorResponsesProtocolTranslatesClaude(t *testing.T) {
+	sse := "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"hello\"}]}]}}\n\n"
+	server, captured := newProtocolTestUpstream(t, sse)
+	exec, auth := newProtocolTestExecutor("responses", server.URL+"/v1")
+
+	payload := []byte(`{"model":"relay-model","max_tokens":64,"system":[{"type":"text","text":"be brief"},{"type":"text","text":"answer in English"}],"messages":[{"role":"user","content":"hi"}]}`)
+	if _, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	body := gjson.ParseBytes(captured.body)
+	if got := body.Get("instructions").String(); got != "be brief\n\nanswer in English" {
+		t.Fatalf("instructions = %q, want the client's system prompt", got)
+	}
+	if strings.Contains(string(captured.body), codexPreambleText) {
+		t.Fatalf("Codex preamble forwarded: %s", captured.body)
+	}
+	input := body.Get("input").Array()
+	if len(input) != 1 || input[0].Get("role").String() != "user" || input[0].Get("content.0.text").String() != "hi" {
+		t.Fatalf("unexpected input: %s", body.Get("input").Raw)
+	}
+
+	captured.body = nil
+	payload = []byte(`{"model":"relay-model","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	if _, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "relay-model", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gjson.GetBytes(captured.body, "instructions").Exists() {
+		t.Fatalf("instructions set without a system prompt: %s", captured.body)
+	}
+}
@@ -2,20 +2,26 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/proxy"
 )
 
 // newProxyAwareHTTPClient creates an HTTP client with proper proxy configuration priority:
-// 1. Use auth.ProxyURL if configured (highest priority)
+// 0. Use the HTTP client supplied by an execution hook via context (highest priority)
+// 1. Use auth.ProxyURL if configured
 // 2. Use cfg.ProxyURL if auth proxy is not configured
 // 3. Use RoundTripper from context if neither are configured
 //
@@ -28,6 +34,87 @@ import (
 // Returns:
 //   - *http.Client: An HTTP client with configured proxy or transport
 func newProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
+	httpClient := buildProxyAwareHTTPClient(ctx, cfg, auth, timeout)
+	connectTimeout, _ := ctx.Value(cliproxyauth.ConnectTimeoutContextKey).(time.Duration)
+	headerTimeout, _ := ctx.Value(cliproxyauth.ResponseHeaderTimeoutContextKey).(time.Duration)
+	if connectTimeout > 0 || headerTimeout > 0 {
+		httpClient.Transport = &upstreamTimeoutTransport{base: httpClient.Transport, connect: connectTimeout, header: headerTimeout}
+	}
+	// Record upstream calls as trace spans; the wrapper is a pass-through while tracing is disabled.
+	httpClient.Transport = tracing.Transport(httpClient.Transport)
+	return httpClient
+}
+
+// Cancellation causes used when the upstream misses a timeout.
+var (
+	errConnectTimeout        = errors.New("upstream connect timeout")
+	errResponseHeaderTimeout = errors.New("upstream response header timeout")
+)
+
+// upstreamTimeoutTransport cancels a request when no upstream connection is obtained within
+// connect, or when no response headers arrive within header. Zero disables a check.
+// It works with any underlying transport, including proxies and host supplied RoundTrippers.
+type upstreamTimeoutTransport struct {
+	base    http.RoundTripper
+	connect time.Duration
+	header  time.Duration
+}
+
+func (t *upstreamTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	stop := func() {}
+	if t.header > 0 {
+		headerTimer := time.AfterFunc(t.header, func() { cancel(errResponseHeaderTimeout) })
+		stop = func() { headerTimer.Stop() }
+	}
+	if t.connect > 0 {
+		connectTimer := time.AfterFunc(t.connect, func() { cancel(errConnectTimeout) })
+		defer connectTimer.Stop()
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { connectTimer.Stop() }})
+	}
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	stop()
+	if err != nil {
+		cause := context.Cause(ctx)
+		cancel(nil)
+		switch {
+		case errors.Is(cause, errConnectTimeout):
+			return nil, statusErr{code: http.StatusGatewayTimeout, msg: fmt.Sprintf("%s after %s", errConnectTimeout, t.connect)}
+		case errors.Is(cause, errResponseHeaderTimeout):
+			return nil, statusErr{code: http.StatusGatewayTimeout, msg: fmt.Sprintf("%s after %s", errResponseHeaderTimeout, t.header)}
+		}
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: func() { cancel(nil) }}
+	return resp, nil
+}
+
+// cancelOnCloseBody releases the request context once the response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func buildProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
+	// Priority 0: Use the client injected by an execution hook
+	if override, ok := ctx.Value("cliproxy.httpclient").(*http.Client); ok && override != nil {
+		clientCopy := *override
+		if timeout > 0 && clientCopy.Timeout == 0 {
+			clientCopy.Timeout = timeout
+		}
+		return &clientCopy
+	}
+
 	httpClient := &http.Client{}
 	if timeout > 0 {
 		httpClient.Timeout = timeout
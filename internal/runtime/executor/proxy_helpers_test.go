@@ -0,0 +1,28 @@
+package executor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUpstreamTimeoutTransport_ResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &http.Client{Transport: &upstreamTimeoutTransport{base: http.DefaultTransport, header: 50 * time.Millisecond}}
+	_, err := client.Get(server.URL)
+	var se statusErr
+	if !errors.As(err, &se) || se.code != http.StatusGatewayTimeout {
+		t.Fatalf("Get() error = %v, want a 504 status error", err)
+	}
+}
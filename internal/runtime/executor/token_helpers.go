@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 	"github.com/tiktoken-go/tokenizer"
 )
 
@@ -57,16 +58,53 @@ func countOpenAIChatTokens(enc tokenizer.Codec, payload []byte) (int64, error) {
 	addIfNotEmpty(&segments, root.Get("input").String())
 	addIfNotEmpty(&segments, root.Get("prompt").String())
 
+	imageTokens := countOpenAIInlineImages(root.Get("messages")) * openAIImageTokens
 	joined := strings.TrimSpace(strings.Join(segments, "\n"))
 	if joined == "" {
-		return 0, nil
+		return imageTokens, nil
 	}
 
 	count, err := enc.Count(joined)
 	if err != nil {
 		return 0, err
 	}
-	return int64(count), nil
+	return int64(count) + imageTokens, nil
+}
+
+// openAIImageTokens approximates a high-detail image of about 1024x1024 pixels.
+const openAIImageTokens = 765
+
+// countOpenAIInlineImages counts base64 image parts, which are not tokenized as text.
+func countOpenAIInlineImages(messages gjson.Result) int64 {
+	var images int64
+	messages.ForEach(func(_, message gjson.Result) bool {
+		message.Get("content").ForEach(func(_, part gjson.Result) bool {
+			if part.Get("type").String() == "image_url" && strings.HasPrefix(part.Get("image_url.url").String(), "data:") {
+				images++
+			}
+			return true
+		})
+		return true
+	})
+	return images
+}
+
+// geminiCountTokensBody prepares a Gemini API countTokens body. The plain form only counts
+// contents, so requests with tools or a system instruction are wrapped in generateContentRequest
+// to have those counted as well.
+func geminiCountTokensBody(model string, payload []byte) []byte {
+	payload, _ = sjson.DeleteBytes(payload, "generationConfig")
+	payload, _ = sjson.DeleteBytes(payload, "safetySettings")
+	root := gjson.ParseBytes(payload)
+	if !root.Get("tools").Exists() && !root.Get("systemInstruction").Exists() && !root.Get("system_instruction").Exists() {
+		return payload
+	}
+	payload, _ = sjson.SetBytes(payload, "model", "models/"+model)
+	wrapped, err := sjson.SetRawBytes([]byte(`{}`), "generateContentRequest", payload)
+	if err != nil {
+		return payload
+	}
+	return wrapped
 }
 
 // buildOpenAIUsageJSON returns a minimal usage structure understood by downstream translators.
@@ -103,7 +141,10 @@ func collectOpenAIContent(content gjson.Result, segments *[]string) {
 			case "text", "input_text", "output_text":
 				addIfNotEmpty(segments, part.Get("text").String())
 			case "image_url":
-				addIfNotEmpty(segments, part.Get("image_url.url").String())
+				// Inline images are billed per image, not per base64 character.
+				if url := part.Get("image_url.url").String(); !strings.HasPrefix(url, "data:") {
+					addIfNotEmpty(segments, url)
+				}
 			case "input_audio", "output_audio", "audio":
 				addIfNotEmpty(segments, part.Get("id").String())
 			case "tool_result":
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responses"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultResponseTable = "response_store"
+	responseQueryTimeout = 5 * time.Second
+)
+
+// PostgresResponseStore implements responses.Store on top of a PostgresStore connection,
+// so every proxy replica can resolve previous_response_id.
+type PostgresResponseStore struct {
+	db    *sql.DB
+	table string
+	ttl   time.Duration
+}
+
+// ResponseStore returns a response store sharing this store's database connection.
+// The backing table is created on first use; a non-positive ttl uses responses.DefaultTTL.
+func (s *PostgresStore) ResponseStore(ctx context.Context, ttl time.Duration) (*PostgresResponseStore, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("postgres store: not initialized")
+	}
+	table := s.cfg.ResponseTable
+	if table == "" {
+		table = defaultResponseTable
+	}
+	if ttl <= 0 {
+		ttl = responses.DefaultTTL
+	}
+	store := &PostgresResponseStore{db: s.db, table: s.fullTableName(table), ttl: ttl}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			previous_response_id TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			input JSONB NOT NULL,
+			response JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			owner TEXT NOT NULL DEFAULT ''
+		)
+	`, store.table)); err != nil {
+		return nil, fmt.Errorf("postgres store: create response table: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT ''", store.table)); err != nil {
+		return nil, fmt.Errorf("postgres store: migrate response table: %w", err)
+	}
+	return store, nil
+}
+
+// Name implements responses.Store.
+func (s *PostgresResponseStore) Name() string { return "postgres" }
+
+// Get implements responses.Store.
+func (s *PostgresResponseStore) Get(id string) (responses.Record, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), responseQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf(`
+		SELECT id, previous_response_id, model, input, response, created_at, owner
+		FROM %s WHERE id = $1 AND created_at >= $2
+	`, s.table)
+	var record responses.Record
+	var input, response []byte
+	err := s.db.QueryRowContext(ctx, query, id, time.Now().Add(-s.ttl)).
+		Scan(&record.ID, &record.PreviousResponseID, &record.Model, &input, &response, &record.CreatedAt, &record.Owner)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Warnf("postgres response store: get: %v", err)
+		}
+		return responses.Record{}, false
+	}
+	record.Input = input
+	record.Response = response
+	return record, true
+}
+
+// Put implements responses.Store.
+func (s *PostgresResponseStore) Put(record responses.Record) {
+	if record.ID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), responseQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, previous_response_id, model, input, response, created_at, owner)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			previous_response_id = EXCLUDED.previous_response_id,
+			model = EXCLUDED.model,
+			input = EXCLUDED.input,
+			response = EXCLUDED.response,
+			created_at = EXCLUDED.created_at,
+			owner = EXCLUDED.owner
+	`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, record.ID, record.PreviousResponseID, record.Model, []byte(record.Input), []byte(record.Response), record.CreatedAt, record.Owner); err != nil {
+		log.Warnf("postgres response store: put: %v", err)
+		return
+	}
+	expire := fmt.Sprintf("DELETE FROM %s WHERE created_at < $1", s.table)
+	if _, err := s.db.ExecContext(ctx, expire, time.Now().Add(-s.ttl)); err != nil {
+		log.Warnf("postgres response store: expire entries: %v", err)
+	}
+}
+
+// Delete implements responses.Store.
+func (s *PostgresResponseStore) Delete(id string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), responseQueryTimeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.table), id)
+	if err != nil {
+		log.Warnf("postgres response store: delete: %v", err)
+		return false
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0
+}
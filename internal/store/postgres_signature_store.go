@@ -0,0 +1,380 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSignatureTable = "signature_cache"
+	signatureQueryTimeout = 5 * time.Second
+
+	// signatureLocalTTL bounds how long a signature read from or written to the database is
+	// served from process memory before it is read again.
+	signatureLocalTTL = time.Minute
+	// signatureLocalMaxEntries caps the process-local read cache.
+	signatureLocalMaxEntries = 10000
+
+	// signaturePurgeChannel carries purges between replicas through LISTEN/NOTIFY.
+	signaturePurgeChannel = "cliproxy_signature_purge"
+	// signatureListenMaxBackoff bounds the wait between attempts to re-establish the listener.
+	signatureListenMaxBackoff = time.Minute
+)
+
+// PostgresSignatureStore implements cache.SignatureStore on top of a PostgresStore connection,
+// allowing several proxy replicas to share thinking signatures. Lookups go through a short-lived
+// process-local cache so the request path does not hit the database for every signature. Purges
+// are broadcast with NOTIFY and evict the entries from every replica's local cache; while the
+// listener is down the local cache is bypassed so purged signatures are never served.
+type PostgresSignatureStore struct {
+	db    *sql.DB
+	table string
+
+	localMu sync.Mutex
+	local   map[signatureKey]localSignature
+	// generation advances on every purge so reads racing a purge are not cached.
+	generation uint64
+	listening  atomic.Bool
+}
+
+// signaturePurge is the NOTIFY payload describing a purge. Empty fields match everything.
+type signaturePurge struct {
+	Table     string `json:"table"`
+	SessionID string `json:"session_id,omitempty"`
+	TextHash  string `json:"text_hash,omitempty"`
+}
+
+type signatureKey struct {
+	sessionID string
+	textHash  string
+}
+
+type localSignature struct {
+	entry   cache.SignatureEntry
+	expires time.Time
+}
+
+// SignatureStore returns a signature store sharing this store's database connection.
+// The backing table is created on first use.
+func (s *PostgresStore) SignatureStore(ctx context.Context) (*PostgresSignatureStore, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("postgres store: not initialized")
+	}
+	table := s.cfg.SignatureTable
+	if table == "" {
+		table = defaultSignatureTable
+	}
+	store := &PostgresSignatureStore{db: s.db, table: s.fullTableName(table), local: make(map[signatureKey]localSignature)}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			session_id TEXT NOT NULL,
+			text_hash TEXT NOT NULL,
+			signature TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (session_id, text_hash)
+		)
+	`, store.table)); err != nil {
+		return nil, fmt.Errorf("postgres store: create signature table: %w", err)
+	}
+	listenCtx := s.ctx
+	if listenCtx == nil {
+		listenCtx = context.Background()
+	}
+	go store.listen(listenCtx)
+	return store, nil
+}
+
+// Name implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Name() string { return "postgres" }
+
+// Get implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Get(sessionID, textHash string) (cache.SignatureEntry, bool) {
+	if entry, ok := s.localGet(sessionID, textHash); ok {
+		return entry, true
+	}
+	generation := s.localGeneration()
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf("SELECT signature, created_at FROM %s WHERE session_id = $1 AND text_hash = $2", s.table)
+	var entry cache.SignatureEntry
+	err := s.db.QueryRowContext(ctx, query, sessionID, textHash).Scan(&entry.Signature, &entry.Timestamp)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Warnf("postgres signature store: get: %v", err)
+		}
+		return cache.SignatureEntry{}, false
+	}
+	s.localPut(sessionID, textHash, entry, generation)
+	return entry, true
+}
+
+// Put implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Put(sessionID, textHash string, entry cache.SignatureEntry, ttl time.Duration, maxEntries int) {
+	generation := s.localGeneration()
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf(`
+		INSERT INTO %s (session_id, text_hash, signature, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (session_id, text_hash)
+		DO UPDATE SET signature = EXCLUDED.signature, created_at = EXCLUDED.created_at
+	`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID, textHash, entry.Signature, entry.Timestamp); err != nil {
+		log.Warnf("postgres signature store: put: %v", err)
+		return
+	}
+	s.localPut(sessionID, textHash, entry, generation)
+	s.trimSession(ctx, sessionID, ttl, maxEntries)
+}
+
+// trimSession removes expired rows and keeps at most maxEntries rows for the session.
+func (s *PostgresSignatureStore) trimSession(ctx context.Context, sessionID string, ttl time.Duration, maxEntries int) {
+	if ttl > 0 {
+		query := fmt.Sprintf("DELETE FROM %s WHERE session_id = $1 AND created_at < $2", s.table)
+		if _, err := s.db.ExecContext(ctx, query, sessionID, time.Now().Add(-ttl)); err != nil {
+			log.Warnf("postgres signature store: expire entries: %v", err)
+		}
+	}
+	if maxEntries <= 0 {
+		return
+	}
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE session_id = $1 AND text_hash IN (
+			SELECT text_hash FROM %[1]s WHERE session_id = $1
+			ORDER BY created_at DESC OFFSET $2
+		)
+	`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID, maxEntries); err != nil {
+		log.Warnf("postgres signature store: trim session: %v", err)
+	}
+}
+
+// Delete implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Delete(sessionID, textHash string) {
+	purge := signaturePurge{Table: s.table, SessionID: sessionID, TextHash: textHash}
+	s.applyPurge(purge)
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf("DELETE FROM %s WHERE session_id = $1 AND text_hash = $2", s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID, textHash); err != nil {
+		log.Warnf("postgres signature store: delete: %v", err)
+		return
+	}
+	s.notifyPurge(ctx, purge)
+}
+
+// DeleteSession implements cache.SignatureStore.
+func (s *PostgresSignatureStore) DeleteSession(sessionID string) {
+	purge := signaturePurge{Table: s.table, SessionID: sessionID}
+	s.applyPurge(purge)
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf("DELETE FROM %s WHERE session_id = $1", s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID); err != nil {
+		log.Warnf("postgres signature store: delete session: %v", err)
+		return
+	}
+	s.notifyPurge(ctx, purge)
+}
+
+// Clear implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Clear() {
+	purge := signaturePurge{Table: s.table}
+	s.applyPurge(purge)
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", s.table)); err != nil {
+		log.Warnf("postgres signature store: clear: %v", err)
+		return
+	}
+	s.notifyPurge(ctx, purge)
+}
+
+// notifyPurge tells the other replicas to evict purged entries from their local caches.
+func (s *PostgresSignatureStore) notifyPurge(ctx context.Context, purge signaturePurge) {
+	payload, err := json.Marshal(purge)
+	if err != nil {
+		return
+	}
+	if _, err = s.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", signaturePurgeChannel, string(payload)); err != nil {
+		log.Warnf("postgres signature store: notify purge: %v", err)
+	}
+}
+
+// listen applies purges broadcast by other replicas until ctx ends, reconnecting with backoff.
+// The local cache is only used while the listener is connected.
+func (s *PostgresSignatureStore) listen(ctx context.Context) {
+	backoff := time.Second
+	for {
+		established, err := s.listenOnce(ctx)
+		s.listening.Store(false)
+		s.applyPurge(signaturePurge{Table: s.table})
+		if ctx.Err() != nil {
+			return
+		}
+		if established {
+			backoff = time.Second
+		}
+		log.Warnf("postgres signature store: purge listener disconnected, reading signatures from the database: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > signatureListenMaxBackoff {
+			backoff = signatureListenMaxBackoff
+		}
+	}
+}
+
+func (s *PostgresSignatureStore) listenOnce(ctx context.Context) (bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = conn.Close() }()
+	established := false
+	err = conn.Raw(func(driverConn any) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		if _, errListen := pgConn.Conn().Exec(ctx, "LISTEN "+signaturePurgeChannel); errListen != nil {
+			return errListen
+		}
+		established = true
+		s.listening.Store(true)
+		for {
+			notification, errWait := pgConn.Conn().WaitForNotification(ctx)
+			if errWait != nil {
+				return errWait
+			}
+			var purge signaturePurge
+			if errDecode := json.Unmarshal([]byte(notification.Payload), &purge); errDecode != nil || purge.Table != s.table {
+				continue
+			}
+			s.applyPurge(purge)
+		}
+	})
+	return established, err
+}
+
+// Sessions implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Sessions() []cache.SignatureSession {
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf(`
+		SELECT session_id, COUNT(*), MIN(created_at), MAX(created_at)
+		FROM %s GROUP BY session_id ORDER BY MAX(created_at) DESC
+	`, s.table)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		log.Warnf("postgres signature store: list sessions: %v", err)
+		return nil
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]cache.SignatureSession, 0)
+	for rows.Next() {
+		var session cache.SignatureSession
+		if err = rows.Scan(&session.SessionID, &session.Entries, &session.Oldest, &session.Newest); err != nil {
+			log.Warnf("postgres signature store: scan session: %v", err)
+			return out
+		}
+		out = append(out, session)
+	}
+	return out
+}
+
+// Entries implements cache.SignatureStore.
+func (s *PostgresSignatureStore) Entries(sessionID string) []cache.SignatureRecord {
+	ctx, cancel := context.WithTimeout(context.Background(), signatureQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf("SELECT text_hash, signature, created_at FROM %s WHERE session_id = $1 ORDER BY created_at DESC", s.table)
+	rows, err := s.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		log.Warnf("postgres signature store: list entries: %v", err)
+		return nil
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]cache.SignatureRecord, 0)
+	for rows.Next() {
+		var record cache.SignatureRecord
+		if err = rows.Scan(&record.TextHash, &record.Signature, &record.Timestamp); err != nil {
+			log.Warnf("postgres signature store: scan entry: %v", err)
+			return out
+		}
+		out = append(out, record)
+	}
+	return out
+}
+
+func (s *PostgresSignatureStore) localGet(sessionID, textHash string) (cache.SignatureEntry, bool) {
+	if !s.listening.Load() {
+		return cache.SignatureEntry{}, false
+	}
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	key := signatureKey{sessionID: sessionID, textHash: textHash}
+	cached, ok := s.local[key]
+	if !ok {
+		return cache.SignatureEntry{}, false
+	}
+	if time.Now().After(cached.expires) {
+		delete(s.local, key)
+		return cache.SignatureEntry{}, false
+	}
+	return cached.entry, true
+}
+
+func (s *PostgresSignatureStore) localGeneration() uint64 {
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	return s.generation
+}
+
+// localPut caches entry unless a purge happened since generation was read or purges cannot be
+// received, in which case the entry may already be gone from the database.
+func (s *PostgresSignatureStore) localPut(sessionID, textHash string, entry cache.SignatureEntry, generation uint64) {
+	if !s.listening.Load() {
+		return
+	}
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	if generation != s.generation {
+		return
+	}
+	now := time.Now()
+	if len(s.local) >= signatureLocalMaxEntries {
+		for key, cached := range s.local {
+			if now.After(cached.expires) {
+				delete(s.local, key)
+			}
+		}
+		if len(s.local) >= signatureLocalMaxEntries {
+			s.local = make(map[signatureKey]localSignature)
+		}
+	}
+	s.local[signatureKey{sessionID: sessionID, textHash: textHash}] = localSignature{entry: entry, expires: now.Add(signatureLocalTTL)}
+}
+
+// applyPurge evicts the local entries matched by purge.
+func (s *PostgresSignatureStore) applyPurge(purge signaturePurge) {
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	s.generation++
+	for key := range s.local {
+		if (purge.SessionID == "" || key.sessionID == purge.SessionID) && (purge.TextHash == "" || key.textHash == purge.TextHash) {
+			delete(s.local, key)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+)
+
+// UsageStore returns a persistent usage store sharing this store's database connection.
+// The backing table is created on first use.
+func (s *PostgresStore) UsageStore(ctx context.Context) (*usage.SQLStore, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("postgres store: not initialized")
+	}
+	table := s.cfg.UsageTable
+	if table == "" {
+		table = usage.DefaultUsageTable
+	}
+	return usage.NewSQLStore(ctx, s.db, usage.DialectPostgres, s.fullTableName(table), false)
+}
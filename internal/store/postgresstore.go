@@ -15,6 +15,7 @@ import (
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -32,6 +33,12 @@ type PostgresStoreConfig struct {
 	ConfigTable string
 	AuthTable   string
 	SpoolDir    string
+	// SignatureTable names the table used for shared thinking signatures.
+	SignatureTable string
+	// UsageTable names the table used for persisted usage records.
+	UsageTable string
+	// ResponseTable names the table used for stored Responses API outputs.
+	ResponseTable string
 }
 
 // PostgresStore persists configuration and authentication metadata using PostgreSQL as backend
@@ -43,6 +50,9 @@ type PostgresStore struct {
 	configPath string
 	authDir    string
 	mu         sync.Mutex
+	// ctx ends background listeners when the store is closed.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewPostgresStore establishes a connection to PostgreSQL and prepares the local workspace.
@@ -89,12 +99,15 @@ func NewPostgresStore(ctx context.Context, cfg PostgresStoreConfig) (*PostgresSt
 		return nil, fmt.Errorf("postgres store: ping database: %w", err)
 	}
 
+	storeCtx, cancel := context.WithCancel(context.Background())
 	store := &PostgresStore{
 		db:         db,
 		cfg:        cfg,
 		spoolRoot:  absSpool,
 		configPath: filepath.Join(configDir, "config.yaml"),
 		authDir:    authDir,
+		ctx:        storeCtx,
+		cancel:     cancel,
 	}
 	return store, nil
 }
@@ -104,6 +117,9 @@ func (s *PostgresStore) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
+	if s.cancel != nil {
+		s.cancel()
+	}
 	return s.db.Close()
 }
 
@@ -217,18 +233,26 @@ func (s *PostgresStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (stri
 		if err = auth.Storage.SaveTokenToFile(path); err != nil {
 			return "", err
 		}
+		// Built-in storages encrypt before writing; this only catches external TokenStorage
+		// implementations that still write plaintext.
+		if _, err = tokencrypt.EncryptFile(path); err != nil {
+			return "", fmt.Errorf("postgres store: encrypt auth file: %w", err)
+		}
 	case auth.Metadata != nil:
 		raw, errMarshal := json.Marshal(auth.Metadata)
 		if errMarshal != nil {
 			return "", fmt.Errorf("postgres store: marshal metadata: %w", errMarshal)
 		}
 		if existing, errRead := os.ReadFile(path); errRead == nil {
-			if jsonEqual(existing, raw) {
+			if plain, errDecrypt := tokencrypt.Decrypt(existing); errDecrypt == nil && !tokencrypt.NeedsRewrite(existing) && jsonEqual(plain, raw) {
 				return path, nil
 			}
 		} else if errRead != nil && !errors.Is(errRead, fs.ErrNotExist) {
 			return "", fmt.Errorf("postgres store: read existing metadata: %w", errRead)
 		}
+		if raw, err = tokencrypt.Encrypt(raw); err != nil {
+			return "", fmt.Errorf("postgres store: encrypt auth file: %w", err)
+		}
 		tmp := path + ".tmp"
 		if errWrite := os.WriteFile(tmp, raw, 0o600); errWrite != nil {
 			return "", fmt.Errorf("postgres store: write temp auth file: %w", errWrite)
@@ -284,8 +308,13 @@ func (s *PostgresStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 			log.WithError(errPath).Warnf("postgres store: skipping auth %s outside spool", id)
 			continue
 		}
+		data, errDecrypt := tokencrypt.Decrypt([]byte(payload))
+		if errDecrypt != nil {
+			log.WithError(errDecrypt).Warnf("postgres store: skipping auth %s that cannot be decrypted", id)
+			continue
+		}
 		metadata := make(map[string]any)
-		if err = json.Unmarshal([]byte(payload), &metadata); err != nil {
+		if err = json.Unmarshal(data, &metadata); err != nil {
 			log.WithError(err).Warnf("postgres store: skipping auth %s with invalid json", id)
 			continue
 		}
@@ -310,6 +339,10 @@ func (s *PostgresStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 			LastRefreshedAt:  time.Time{},
 			NextRefreshAfter: time.Time{},
 		}
+		if disabled, ok := metadata["disabled"].(bool); ok && disabled {
+			auth.Disabled = true
+			auth.Status = cliproxyauth.StatusDisabled
+		}
 		auths = append(auths, auth)
 	}
 	if err = rows.Err(); err != nil {
@@ -0,0 +1,335 @@
+// Package tokencount estimates prompt token counts locally for Claude and Gemini request
+// payloads. It is used when no upstream countTokens endpoint can answer, so the estimate errs on
+// the high side: text is tokenized with o200k_base and the fixed costs providers document for
+// images, documents and tool definitions are added on top.
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const (
+	// claudeMessageOverhead covers role markers and separators around each Claude message.
+	claudeMessageOverhead = 4
+	// claudeToolsOverhead is the tool-use system prompt Anthropic adds when tools are present.
+	claudeToolsOverhead = 346
+	// claudeToolOverhead covers the framing of each tool definition.
+	claudeToolOverhead = 10
+	// claudeMaxImageTokens is the cost of an image of unknown size; Claude downsizes larger
+	// images to about 1.15 megapixels, which costs roughly this much.
+	claudeMaxImageTokens = 1600
+	// claudeDocumentPageTokens approximates the text plus page image of one PDF page.
+	claudeDocumentPageTokens = 2000
+
+	// geminiMessageOverhead covers role markers around each Gemini content entry.
+	geminiMessageOverhead = 4
+	// geminiTileTokens is the cost of one 768x768 image tile, and of an image of at most 384px.
+	geminiTileTokens = 258
+	// geminiToolOverhead covers the framing of each function declaration.
+	geminiToolOverhead = 8
+)
+
+var (
+	codecOnce sync.Once
+	codec     tokenizer.Codec
+	codecErr  error
+)
+
+func textCodec() (tokenizer.Codec, error) {
+	codecOnce.Do(func() {
+		codec, codecErr = tokenizer.Get(tokenizer.O200kBase)
+	})
+	return codec, codecErr
+}
+
+// Supported reports whether Estimate understands payloads of the given source format.
+func Supported(format string) bool {
+	switch format {
+	case "claude", "gemini", "gemini-cli":
+		return true
+	default:
+		return false
+	}
+}
+
+// Estimate approximates the prompt tokens of a Claude ("claude") or Gemini ("gemini",
+// "gemini-cli") request payload.
+func Estimate(format string, payload []byte) (int64, error) {
+	enc, err := textCodec()
+	if err != nil {
+		return 0, fmt.Errorf("tokencount: tokenizer init failed: %w", err)
+	}
+	c := &counter{enc: enc}
+	root := gjson.ParseBytes(payload)
+	switch format {
+	case "claude":
+		c.claude(root)
+	case "gemini", "gemini-cli":
+		for _, wrapper := range []string{"request", "generateContentRequest"} {
+			if inner := root.Get(wrapper); inner.IsObject() {
+				root = inner
+			}
+		}
+		c.gemini(root)
+	default:
+		return 0, fmt.Errorf("tokencount: unsupported format %q", format)
+	}
+	return c.total, nil
+}
+
+// Response renders count as the count-tokens response body of the given source format.
+func Response(format string, count int64) []byte {
+	switch format {
+	case "claude":
+		return []byte(fmt.Sprintf(`{"input_tokens":%d}`, count))
+	default:
+		return []byte(fmt.Sprintf(`{"totalTokens":%d}`, count))
+	}
+}
+
+type counter struct {
+	enc   tokenizer.Codec
+	total int64
+}
+
+func (c *counter) text(value string) {
+	if value = strings.TrimSpace(value); value == "" {
+		return
+	}
+	if n, err := c.enc.Count(value); err == nil {
+		c.total += int64(n)
+	} else {
+		// Fall back to the common four characters per token.
+		c.total += int64(len(value)+3) / 4
+	}
+}
+
+func (c *counter) json(value gjson.Result) {
+	if value.Exists() {
+		c.text(value.Raw)
+	}
+}
+
+func (c *counter) claude(root gjson.Result) {
+	system := root.Get("system")
+	if system.Type == gjson.String {
+		c.text(system.String())
+	} else {
+		system.ForEach(func(_, block gjson.Result) bool {
+			c.claudeBlock(block)
+			return true
+		})
+	}
+	root.Get("messages").ForEach(func(_, message gjson.Result) bool {
+		c.total += claudeMessageOverhead
+		content := message.Get("content")
+		if content.Type == gjson.String {
+			c.text(content.String())
+			return true
+		}
+		content.ForEach(func(_, block gjson.Result) bool {
+			c.claudeBlock(block)
+			return true
+		})
+		return true
+	})
+	tools := root.Get("tools")
+	if len(tools.Array()) > 0 {
+		c.total += claudeToolsOverhead
+	}
+	tools.ForEach(func(_, tool gjson.Result) bool {
+		c.total += claudeToolOverhead
+		c.text(tool.Get("name").String())
+		c.text(tool.Get("description").String())
+		c.json(tool.Get("input_schema"))
+		return true
+	})
+}
+
+func (c *counter) claudeBlock(block gjson.Result) {
+	switch block.Get("type").String() {
+	case "text":
+		c.text(block.Get("text").String())
+	case "thinking":
+		c.text(block.Get("thinking").String())
+	case "image":
+		c.total += claudeImageTokens(block.Get("source"))
+	case "document":
+		c.claudeDocument(block.Get("source"))
+	case "tool_use", "server_tool_use":
+		c.text(block.Get("name").String())
+		c.json(block.Get("input"))
+	case "tool_result":
+		content := block.Get("content")
+		if content.Type == gjson.String {
+			c.text(content.String())
+			return
+		}
+		content.ForEach(func(_, inner gjson.Result) bool {
+			c.claudeBlock(inner)
+			return true
+		})
+	case "redacted_thinking":
+	default:
+		c.json(block)
+	}
+}
+
+func (c *counter) claudeDocument(source gjson.Result) {
+	switch source.Get("type").String() {
+	case "text":
+		c.text(source.Get("data").String())
+	case "content":
+		source.Get("content").ForEach(func(_, block gjson.Result) bool {
+			c.claudeBlock(block)
+			return true
+		})
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(source.Get("data").String())
+		pages := 1
+		if err == nil {
+			pages = max(pdfPageCount(data), 1)
+		}
+		c.total += int64(pages) * claudeDocumentPageTokens
+	default:
+		c.total += claudeDocumentPageTokens
+	}
+}
+
+// claudeImageTokens applies Anthropic's width*height/750 rule after the downscaling Claude
+// performs: the long edge is capped at 1568px and the area at about 1.15 megapixels.
+func claudeImageTokens(source gjson.Result) int64 {
+	width, height, ok := imageSize(source.Get("data").String())
+	if !ok {
+		return claudeMaxImageTokens
+	}
+	w, h := float64(width), float64(height)
+	if long := math.Max(w, h); long > 1568 {
+		w, h = w*1568/long, h*1568/long
+	}
+	if area := w * h; area > 1_150_000 {
+		scale := math.Sqrt(1_150_000 / area)
+		w, h = w*scale, h*scale
+	}
+	return int64(math.Ceil(w * h / 750))
+}
+
+func (c *counter) gemini(root gjson.Result) {
+	system := root.Get("systemInstruction")
+	if !system.Exists() {
+		system = root.Get("system_instruction")
+	}
+	system.Get("parts").ForEach(func(_, part gjson.Result) bool {
+		c.geminiPart(part)
+		return true
+	})
+	root.Get("contents").ForEach(func(_, content gjson.Result) bool {
+		c.total += geminiMessageOverhead
+		content.Get("parts").ForEach(func(_, part gjson.Result) bool {
+			c.geminiPart(part)
+			return true
+		})
+		return true
+	})
+	root.Get("tools").ForEach(func(_, tool gjson.Result) bool {
+		declarations := tool.Get("functionDeclarations")
+		if !declarations.Exists() {
+			declarations = tool.Get("function_declarations")
+		}
+		declarations.ForEach(func(_, declaration gjson.Result) bool {
+			c.total += geminiToolOverhead
+			c.text(declaration.Get("name").String())
+			c.text(declaration.Get("description").String())
+			c.json(declaration.Get("parameters"))
+			c.json(declaration.Get("parametersJsonSchema"))
+			return true
+		})
+		return true
+	})
+}
+
+func (c *counter) geminiPart(part gjson.Result) {
+	switch {
+	case part.Get("text").Exists():
+		c.text(part.Get("text").String())
+	case part.Get("inlineData").Exists(), part.Get("inline_data").Exists():
+		data := part.Get("inlineData")
+		if !data.Exists() {
+			data = part.Get("inline_data")
+		}
+		c.total += geminiImageTokens(data.Get("data").String())
+	case part.Get("fileData").Exists(), part.Get("file_data").Exists():
+		c.total += geminiTileTokens
+	case part.Get("functionCall").Exists():
+		c.text(part.Get("functionCall.name").String())
+		c.json(part.Get("functionCall.args"))
+	case part.Get("functionResponse").Exists():
+		c.text(part.Get("functionResponse.name").String())
+		c.json(part.Get("functionResponse.response"))
+	default:
+		c.json(part)
+	}
+}
+
+// geminiImageTokens applies Gemini's image rule: images of at most 384px on both sides cost one
+// tile, larger images are cut into 768x768 tiles of 258 tokens each.
+func geminiImageTokens(data string) int64 {
+	width, height, ok := imageSize(data)
+	if !ok || (width <= 384 && height <= 384) {
+		return geminiTileTokens
+	}
+	tiles := int64(math.Ceil(float64(width)/768)) * int64(math.Ceil(float64(height)/768))
+	return tiles * geminiTileTokens
+}
+
+// imageSize decodes the dimensions of a base64 PNG, JPEG or GIF image.
+func imageSize(data string) (int, int, bool) {
+	if data == "" {
+		return 0, 0, false
+	}
+	if _, encoded, ok := strings.Cut(data, ";base64,"); ok {
+		data = encoded
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(decoded))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// pdfPageCount counts page objects in a PDF without parsing it.
+func pdfPageCount(data []byte) int {
+	count := 0
+	for _, marker := range [][]byte{[]byte("/Type /Page"), []byte("/Type/Page")} {
+		rest := data
+		for {
+			idx := bytes.Index(rest, marker)
+			if idx < 0 {
+				break
+			}
+			rest = rest[idx+len(marker):]
+			// Skip the /Pages tree nodes.
+			if len(rest) > 0 && rest[0] == 's' {
+				continue
+			}
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,79 @@
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func pngBase64(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestEstimateClaudeCountsSystemToolsAndImages(t *testing.T) {
+	plain := []byte(`{"messages":[{"role":"user","content":"hello there"}]}`)
+	base, err := Estimate("claude", plain)
+	if err != nil || base <= claudeMessageOverhead {
+		t.Fatalf("Estimate(plain) = %d, %v", base, err)
+	}
+
+	withSystem, _ := Estimate("claude", []byte(`{"system":[{"type":"text","text":"You are a careful assistant."}],"messages":[{"role":"user","content":"hello there"}]}`))
+	if withSystem <= base {
+		t.Fatalf("system prompt not counted: %d <= %d", withSystem, base)
+	}
+
+	withTools, _ := Estimate("claude", []byte(`{"messages":[{"role":"user","content":"hello there"}],"tools":[{"name":"read","description":"Read a file","input_schema":{"type":"object","properties":{"path":{"type":"string"}}}}]}`))
+	if withTools < base+claudeToolsOverhead {
+		t.Fatalf("tools not counted: %d < %d", withTools, base+claudeToolsOverhead)
+	}
+
+	image := pngBase64(t, 750, 100)
+	withImage, _ := Estimate("claude", []byte(`{"messages":[{"role":"user","content":[{"type":"text","text":"hello there"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"`+image+`"}}]}]}`))
+	if got := withImage - base; got != 100 {
+		t.Fatalf("750x100 image cost %d tokens, want 100", got)
+	}
+}
+
+func TestEstimateGeminiUnwrapsAndTilesImages(t *testing.T) {
+	small := pngBase64(t, 300, 300)
+	large := pngBase64(t, 1000, 800)
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"` + small + `"}},{"inlineData":{"mimeType":"image/png","data":"` + large + `"}}]}]}`)
+	got, err := Estimate("gemini", payload)
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+	// One tile for the small image, 2x2 tiles for the large one.
+	if want := int64(geminiMessageOverhead + 5*geminiTileTokens); got != want {
+		t.Fatalf("Estimate() = %d, want %d", got, want)
+	}
+
+	wrapped, _ := Estimate("gemini-cli", []byte(`{"request":`+string(payload)+`}`))
+	if wrapped != got {
+		t.Fatalf("wrapped payload = %d, want %d", wrapped, got)
+	}
+}
+
+func TestEstimateRejectsUnsupportedFormat(t *testing.T) {
+	if Supported("openai") {
+		t.Fatal("openai reported as supported")
+	}
+	if _, err := Estimate("openai", []byte(`{}`)); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
+
+func TestResponseFormats(t *testing.T) {
+	if got := string(Response("claude", 12)); got != `{"input_tokens":12}` {
+		t.Fatalf("claude response = %s", got)
+	}
+	if got := string(Response("gemini", 12)); got != `{"totalTokens":12}` {
+		t.Fatalf("gemini response = %s", got)
+	}
+}
@@ -0,0 +1,387 @@
+// Package tokencrypt encrypts auth files at rest. Every file is sealed with its own random data
+// key using AES-256-GCM, and the data key is wrapped with a key encryption key loaded from the
+// environment or a key file. The sealed envelope is itself JSON, so the Git, object storage and
+// Postgres backends store it unchanged.
+//
+// The first configured key encrypts new writes; further keys are kept to decrypt files written
+// before a rotation. Files without an envelope are read as plaintext, so enabling encryption does
+// not break existing auth directories.
+package tokencrypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// envelopeVersion is stored under envelopeMarker and identifies the envelope layout.
+	envelopeVersion = 1
+	envelopeMarker  = "cliproxy_encrypted"
+	algorithm       = "AES-256-GCM"
+	keySize         = 32
+)
+
+// ErrUnknownKey is returned when a file was encrypted with a key that is not configured.
+var ErrUnknownKey = errors.New("tokencrypt: file was encrypted with an unknown key")
+
+type envelope struct {
+	Version    int    `json:"cliproxy_encrypted"`
+	Algorithm  string `json:"alg"`
+	KeyID      string `json:"key_id"`
+	WrappedKey string `json:"wrapped_key"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Keyring holds the key encryption keys. The primary key encrypts; every key decrypts.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring builds a keyring from 32-byte keys. The first key becomes the primary key.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("tokencrypt: no keys")
+	}
+	ring := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+	for i, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("tokencrypt: key %d must be %d bytes, got %d", i+1, keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		id := KeyID(key)
+		if i == 0 {
+			ring.primary = id
+		}
+		ring.keys[id] = aead
+	}
+	return ring, nil
+}
+
+// ParseKeys decodes keys separated by commas or newlines. Each key is 32 bytes encoded as
+// base64 or hex; blank entries and lines starting with '#' are ignored.
+func ParseKeys(raw string) ([][]byte, error) {
+	var keys [][]byte
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(raw, ",", "\n")))
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		key, err := decodeKey(entry)
+		if err != nil {
+			return nil, fmt.Errorf("tokencrypt: key %d: %w", len(keys)+1, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tokencrypt: read keys: %w", err)
+	}
+	return keys, nil
+}
+
+// LoadKeyring builds a keyring from inline keys followed by the keys in keyFile. It returns nil
+// when neither provides a key.
+func LoadKeyring(inline, keyFile string) (*Keyring, error) {
+	keys, err := ParseKeys(inline)
+	if err != nil {
+		return nil, err
+	}
+	if keyFile = strings.TrimSpace(keyFile); keyFile != "" {
+		data, errRead := os.ReadFile(keyFile)
+		if errRead != nil {
+			return nil, fmt.Errorf("tokencrypt: read key file: %w", errRead)
+		}
+		fileKeys, errParse := ParseKeys(string(data))
+		if errParse != nil {
+			return nil, errParse
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return NewKeyring(keys...)
+}
+
+// KeyID returns the identifier recorded in envelopes sealed with key.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Encrypt seals plain in an envelope with a fresh data key wrapped by the primary key.
+func (k *Keyring) Encrypt(plain []byte) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate data key: %w", err)
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, dataAEAD.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate nonce: %w", err)
+	}
+	env := envelope{
+		Version:    envelopeVersion,
+		Algorithm:  algorithm,
+		KeyID:      k.primary,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(dataAEAD.Seal(nil, nonce, plain, []byte(k.primary))),
+	}
+	return json.Marshal(env)
+}
+
+// Decrypt opens an envelope produced by Encrypt with any key of the ring. Data that is not an
+// envelope is returned unchanged.
+func (k *Keyring) Decrypt(data []byte) ([]byte, error) {
+	env, ok := parseEnvelope(data)
+	if !ok {
+		return data, nil
+	}
+	if k == nil {
+		return nil, fmt.Errorf("%w %s: no encryption key configured", ErrUnknownKey, env.KeyID)
+	}
+	if env.Version != envelopeVersion || env.Algorithm != algorithm {
+		return nil, fmt.Errorf("tokencrypt: unsupported envelope version %d (%s)", env.Version, env.Algorithm)
+	}
+	kek, ok := k.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, env.KeyID)
+	}
+	wrapped, errWrapped := base64.StdEncoding.DecodeString(env.WrappedKey)
+	nonce, errNonce := base64.StdEncoding.DecodeString(env.Nonce)
+	ciphertext, errCiphertext := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err := errors.Join(errWrapped, errNonce, errCiphertext); err != nil {
+		return nil, fmt.Errorf("tokencrypt: malformed envelope: %w", err)
+	}
+	dataKey, err := open(kek, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != dataAEAD.NonceSize() {
+		return nil, errors.New("tokencrypt: malformed envelope: bad nonce size")
+	}
+	plain, err := dataAEAD.Open(nil, nonce, ciphertext, []byte(env.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+var (
+	activeMu sync.RWMutex
+	active   *Keyring
+)
+
+// SetKeyring installs the process-wide keyring used by the package-level helpers. A nil keyring
+// disables encryption; envelopes can then no longer be read.
+func SetKeyring(ring *Keyring) {
+	activeMu.Lock()
+	active = ring
+	activeMu.Unlock()
+}
+
+func current() *Keyring {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
+
+// Enabled reports whether a keyring is installed.
+func Enabled() bool { return current() != nil }
+
+// PrimaryKeyID returns the identifier of the installed primary key, or "" when encryption is off.
+func PrimaryKeyID() string {
+	if ring := current(); ring != nil {
+		return ring.primary
+	}
+	return ""
+}
+
+// Encrypt seals plain with the installed keyring, or returns it unchanged when encryption is off.
+func Encrypt(plain []byte) ([]byte, error) {
+	ring := current()
+	if ring == nil {
+		return plain, nil
+	}
+	return ring.Encrypt(plain)
+}
+
+// Decrypt opens data with the installed keyring. Plaintext passes through unchanged.
+func Decrypt(data []byte) ([]byte, error) {
+	return current().Decrypt(data)
+}
+
+// IsEncrypted reports whether data is an envelope.
+func IsEncrypted(data []byte) bool {
+	_, ok := parseEnvelope(data)
+	return ok
+}
+
+// NeedsRewrite reports whether stored data differs from what a write would produce now: it is
+// plaintext while encryption is on, or it was sealed with a key other than the primary key.
+func NeedsRewrite(data []byte) bool {
+	ring := current()
+	if ring == nil {
+		return false
+	}
+	env, ok := parseEnvelope(data)
+	return !ok || env.KeyID != ring.primary
+}
+
+// ReadFile reads and decrypts an auth file.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return data, nil
+	}
+	return Decrypt(data)
+}
+
+// EncryptFile re-encrypts the file at path with the primary key when NeedsRewrite reports it is
+// stale, e.g. after a TokenStorage outside this module wrote plaintext JSON directly. It reports
+// whether the file was rewritten.
+func EncryptFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if len(data) == 0 || !NeedsRewrite(data) {
+		return false, nil
+	}
+	plain, err := Decrypt(data)
+	if err != nil {
+		return false, err
+	}
+	sealed, err := Encrypt(plain)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err = replaceFile(path, sealed, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WriteFile seals plain in memory and replaces path with the result through a temporary file,
+// so plaintext credentials never reach the disk while encryption is on.
+func WriteFile(path string, plain []byte, perm os.FileMode) error {
+	sealed, err := Encrypt(plain)
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, sealed, perm)
+}
+
+// replaceFile atomically replaces path with data.
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("tokencrypt: create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	_, errWrite := tmp.Write(data)
+	errClose := tmp.Close()
+	if err = errors.Join(errWrite, errClose, os.Chmod(tmpName, perm)); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("tokencrypt: write temp file: %w", err)
+	}
+	if err = os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("tokencrypt: replace file: %w", err)
+	}
+	return nil
+}
+
+func parseEnvelope(data []byte) (envelope, bool) {
+	var env envelope
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' || !bytes.Contains(trimmed, []byte(envelopeMarker)) {
+		return env, false
+	}
+	if err := json.Unmarshal(trimmed, &env); err != nil || env.Version == 0 || env.Ciphertext == "" {
+		return env, false
+	}
+	return env, true
+}
+
+func decodeKey(entry string) ([]byte, error) {
+	if len(entry) == hex.EncodedLen(keySize) {
+		if key, err := hex.DecodeString(entry); err == nil {
+			return key, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(entry); err == nil {
+			if len(key) != keySize {
+				return nil, fmt.Errorf("must decode to %d bytes, got %d", keySize, len(key))
+			}
+			return key, nil
+		}
+	}
+	return nil, errors.New("must be base64 or hex encoded")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: init cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: init gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts plain and prefixes the random nonce.
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
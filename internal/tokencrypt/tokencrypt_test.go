@@ -0,0 +1,145 @@
+package tokencrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, keySize)
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	ring, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	plain := []byte(`{"type":"claude","refresh_token":"secret"}`)
+	sealed, err := ring.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed data contains plaintext: %s", sealed)
+	}
+	if !IsEncrypted(sealed) {
+		t.Fatalf("IsEncrypted = false for %s", sealed)
+	}
+	opened, err := ring.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(opened, plain) {
+		t.Fatalf("Decrypt = %s, want %s", opened, plain)
+	}
+}
+
+func TestKeyringDecryptPlaintextPassthrough(t *testing.T) {
+	plain := []byte(`{"type":"codex"}`)
+	var ring *Keyring
+	got, err := ring.Decrypt(plain)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("Decrypt(plain) = %s, %v", got, err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldRing, _ := NewKeyring(testKey(1))
+	sealed, err := oldRing.Encrypt([]byte(`{"access_token":"a"}`))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated, _ := NewKeyring(testKey(2), testKey(1))
+	if _, err = rotated.Decrypt(sealed); err != nil {
+		t.Fatalf("rotated keyring cannot read old file: %v", err)
+	}
+	newOnly, _ := NewKeyring(testKey(2))
+	if _, err = newOnly.Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Decrypt without old key error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString(testKey(3))
+	hexKey := "0404040404040404040404040404040404040404040404040404040404040404"
+	keys, err := ParseKeys(b64 + ",\n# comment\n" + hexKey)
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0], testKey(3)) || !bytes.Equal(keys[1], testKey(4)) {
+		t.Fatalf("ParseKeys = %v", keys)
+	}
+	if _, err = ParseKeys(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Fatal("ParseKeys accepted a short key")
+	}
+}
+
+func TestEncryptFileRewritesStaleFiles(t *testing.T) {
+	t.Cleanup(func() { SetKeyring(nil) })
+	path := filepath.Join(t.TempDir(), "claude.json")
+	plain := []byte(`{"type":"claude"}`)
+	if err := os.WriteFile(path, plain, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	oldRing, _ := NewKeyring(testKey(1))
+	SetKeyring(oldRing)
+	if rewritten, err := EncryptFile(path); err != nil || !rewritten {
+		t.Fatalf("EncryptFile(plaintext) = %v, %v", rewritten, err)
+	}
+	if rewritten, err := EncryptFile(path); err != nil || rewritten {
+		t.Fatalf("EncryptFile(current) = %v, %v", rewritten, err)
+	}
+
+	rotated, _ := NewKeyring(testKey(2), testKey(1))
+	SetKeyring(rotated)
+	if rewritten, err := EncryptFile(path); err != nil || !rewritten {
+		t.Fatalf("EncryptFile(old key) = %v, %v", rewritten, err)
+	}
+	data, _ := os.ReadFile(path)
+	if NeedsRewrite(data) {
+		t.Fatal("file still sealed with the old key")
+	}
+	got, err := ReadFile(path)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("ReadFile = %s, %v", got, err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileNeverStoresPlaintext(t *testing.T) {
+	t.Cleanup(func() { SetKeyring(nil) })
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codex.json")
+	plain := []byte(`{"type":"codex","refresh_token":"secret"}`)
+
+	ring, _ := NewKeyring(testKey(3))
+	SetKeyring(ring)
+	if err := WriteFile(path, plain, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !IsEncrypted(data) || bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("file is not sealed: %s", data)
+	}
+	got, err := ReadFile(path)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("ReadFile = %s, %v", got, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %d entries", len(entries))
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
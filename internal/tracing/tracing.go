@@ -0,0 +1,290 @@
+// Package tracing wires OpenTelemetry trace export for the proxy. Tracing is disabled by
+// default; while disabled spans are no-ops and cost nothing. The proxy keeps its own tracer
+// provider and never touches the global one, so embedders keep theirs.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// InstrumentationName identifies spans produced by the proxy.
+const InstrumentationName = "github.com/router-for-me/CLIProxyAPI/v6"
+
+// RequestIDKey is the span attribute carrying the proxy request ID from internal/logging.
+const RequestIDKey = attribute.Key("cliproxy.request_id")
+
+const (
+	defaultServiceName = "cli-proxy-api"
+	defaultEndpoint    = "http://localhost:4318"
+	shutdownTimeout    = 5 * time.Second
+)
+
+var (
+	mu       sync.Mutex
+	provider atomic.Pointer[sdktrace.TracerProvider]
+	current  config.TracingConfig
+	enabled  atomic.Bool
+
+	disabledTracer = noop.NewTracerProvider().Tracer(InstrumentationName)
+
+	// inbound extracts W3C trace context sent by callers so proxy spans join their traces.
+	inbound = propagation.TraceContext{}
+)
+
+func init() {
+	sdktranslator.SetSpanStarter(Start)
+}
+
+// Configure installs (or removes) the OTLP exporter described by cfg. Calling it again with an
+// unchanged configuration is a no-op; a changed configuration replaces the previous provider.
+func Configure(cfg config.TracingConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider.Load() != nil && sameConfig(current, cfg) {
+		return nil
+	}
+	if !cfg.Enable {
+		shutdownLocked()
+		current = cfg
+		return nil
+	}
+
+	endpoint := strings.TrimSpace(cfg.Endpoint)
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("tracing: create otlp exporter: %w", err)
+	}
+
+	serviceName := strings.TrimSpace(cfg.ServiceName)
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	next := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	shutdownLocked()
+	provider.Store(next)
+	current = cfg
+	enabled.Store(true)
+	log.Infof("opentelemetry tracing enabled, exporting to %s", endpoint)
+	return nil
+}
+
+// Shutdown flushes pending spans and disables tracing.
+func Shutdown() {
+	mu.Lock()
+	defer mu.Unlock()
+	shutdownLocked()
+}
+
+func shutdownLocked() {
+	enabled.Store(false)
+	previous := provider.Swap(nil)
+	if previous == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := previous.Shutdown(ctx); err != nil {
+		log.Warnf("tracing: shutdown exporter: %v", err)
+	}
+}
+
+func sameConfig(a, b config.TracingConfig) bool {
+	if a.Enable != b.Enable || a.Endpoint != b.Endpoint || a.ServiceName != b.ServiceName ||
+		a.SampleRatio != b.SampleRatio || len(a.Headers) != len(b.Headers) {
+		return false
+	}
+	for k, v := range a.Headers {
+		if b.Headers[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool { return enabled.Load() }
+
+// Tracer returns the proxy tracer, or a no-op tracer while tracing is disabled.
+func Tracer() trace.Tracer {
+	if p := provider.Load(); p != nil {
+		return p.Tracer(InstrumentationName)
+	}
+	return disabledTracer
+}
+
+// Start opens a span tagged with the request ID carried by ctx. While tracing is disabled it
+// returns ctx unchanged with a no-op span, never the caller's ambient span, so End leaves
+// spans owned by embedders or other middleware alone.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !enabled.Load() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	if requestID := logging.GetRequestID(ctx); requestID != "" {
+		attrs = append(attrs, RequestIDKey.String(requestID))
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span (when non-nil) and ends it.
+func End(span trace.Span, err error) {
+	if span == nil || !span.IsRecording() {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GinMiddleware opens a server span per inbound request and stores it on the request context.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled.Load() {
+			c.Next()
+			return
+		}
+		ctx := inbound.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		}
+		if requestID := logging.GetGinRequestID(c); requestID != "" {
+			attrs = append(attrs, RequestIDKey.String(requestID))
+		}
+		ctx, span := Tracer().Start(ctx, c.Request.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		c.Request = c.Request.WithContext(ctx)
+		defer span.End()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// Transport wraps base so every upstream HTTP call is recorded as a client span. Query strings
+// are omitted from span attributes because some providers carry API keys there.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if _, ok := base.(*transport); ok {
+		return base
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !enabled.Load() {
+		return base.RoundTrip(req)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.path", req.URL.Path),
+	}
+	if requestID := logging.GetRequestID(req.Context()); requestID != "" {
+		attrs = append(attrs, RequestIDKey.String(requestID))
+	}
+	ctx, span := Tracer().Start(req.Context(), "HTTP "+req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	if resp.Body == nil || resp.Body == http.NoBody {
+		span.End()
+		return resp, nil
+	}
+	// Keep the span open until the body is drained so streamed responses are timed fully.
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *spanBody) finish(err error) {
+	b.once.Do(func() {
+		if err != nil && err != io.EOF {
+			b.span.RecordError(err)
+		}
+		b.span.End()
+	})
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTracingExportsToCollector(t *testing.T) {
+	var exports atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			_, _ = io.Copy(io.Discard, r.Body)
+			exports.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	if err := Configure(config.TracingConfig{Enable: true, Endpoint: collector.URL}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if !Enabled() {
+		t.Fatal("Enabled() = false after Configure")
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		logging.SetGinRequestID(c, "req-1")
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), "req-1"))
+	}, GinMiddleware())
+	engine.GET("/v1/models", func(c *gin.Context) {
+		client := &http.Client{Transport: Transport(nil)}
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		c.Status(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/models status = %d", rec.Code)
+	}
+
+	Shutdown()
+	if exports.Load() == 0 {
+		t.Fatal("collector received no trace exports")
+	}
+	if Enabled() {
+		t.Fatal("Enabled() = true after Shutdown")
+	}
+}
+
+func TestTracingDisabledByDefault(t *testing.T) {
+	if err := Configure(config.TracingConfig{}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if Enabled() {
+		t.Fatal("Enabled() = true for zero config")
+	}
+	base := http.DefaultTransport
+	if _, ok := Transport(base).(*transport); !ok {
+		t.Fatal("Transport() did not wrap base")
+	}
+}
+
+func TestTracingLeavesEmbedderSpansAndProviderAlone(t *testing.T) {
+	embedder := sdktrace.NewTracerProvider()
+	defer func() { _ = embedder.Shutdown(context.Background()) }()
+	otel.SetTracerProvider(embedder)
+
+	ctx, ambient := embedder.Tracer("embedder").Start(context.Background(), "embedder")
+	defer ambient.End()
+	_, span := Start(ctx, "disabled")
+	if span.SpanContext().Equal(ambient.SpanContext()) {
+		t.Fatal("Start() returned the ambient span while tracing is disabled")
+	}
+	End(span, io.ErrUnexpectedEOF)
+	if !ambient.IsRecording() {
+		t.Fatal("End() ended the ambient span")
+	}
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }))
+	defer collector.Close()
+	if err := Configure(config.TracingConfig{Enable: true, Endpoint: collector.URL}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	Shutdown()
+	if otel.GetTracerProvider() != embedder {
+		t.Fatal("Configure/Shutdown replaced the global tracer provider")
+	}
+}
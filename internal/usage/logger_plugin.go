@@ -94,6 +94,7 @@ type RequestDetail struct {
 	AuthIndex string     `json:"auth_index"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	Cache     string     `json:"cache,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -202,6 +203,7 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		AuthIndex: record.AuthIndex,
 		Tokens:    detail,
 		Failed:    failed,
+		Cache:     record.Cache,
 	})
 
 	s.requestsByDay[dayKey]++
@@ -0,0 +1,548 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// DefaultUsageTable is the table name used when none is configured.
+	DefaultUsageTable = "usage_records"
+
+	usageFlushInterval = 2 * time.Second
+	usageFlushBatch    = 200
+	usageQueueSize     = 4096
+	usageQueryTimeout  = 30 * time.Second
+)
+
+// Dialect selects SQL placeholder and DDL flavour.
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Bucket granularities accepted by HistoryQuery.
+const (
+	BucketNone = ""
+	BucketHour = "hour"
+	BucketDay  = "day"
+)
+
+// historyDimension names the column a HistoryQuery.GroupBy value groups on and the
+// expression reported as the group label.
+type historyDimension struct {
+	column string
+	label  string
+}
+
+// Dimensions accepted by HistoryQuery.GroupBy. Client keys are grouped by their hash and
+// labelled with the masked key.
+var historyDimensions = map[string]historyDimension{
+	"api_key":    {column: "api_key_hash", label: "MAX(api_key)"},
+	"auth_index": {column: "auth_index", label: "auth_index"},
+	"provider":   {column: "provider", label: "provider"},
+	"model":      {column: "model", label: "model"},
+}
+
+// HistoryQuery filters and aggregates persisted usage records.
+type HistoryQuery struct {
+	From time.Time
+	To   time.Time
+	// APIKey filters on the raw client key; it is matched against the stored key hash.
+	APIKey    string
+	AuthIndex string
+	Provider  string
+	Model     string
+	// Bucket is "hour", "day" or empty for a single bucket spanning the range.
+	Bucket string
+	// GroupBy optionally splits buckets by "api_key", "auth_index", "provider" or "model".
+	GroupBy string
+}
+
+// HistoryBucket aggregates the records falling into one time bucket (and group).
+type HistoryBucket struct {
+	Start           time.Time `json:"start"`
+	Group           string    `json:"group,omitempty"`
+	Requests        int64     `json:"requests"`
+	Failed          int64     `json:"failed"`
+	InputTokens     int64     `json:"input_tokens"`
+	OutputTokens    int64     `json:"output_tokens"`
+	ReasoningTokens int64     `json:"reasoning_tokens"`
+	CachedTokens    int64     `json:"cached_tokens"`
+	TotalTokens     int64     `json:"total_tokens"`
+}
+
+// SQLStore persists usage records into a SQLite or PostgreSQL table. Client API keys are
+// never stored: the api_key column holds the masked key and api_key_hash a hash for matching.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	ownsDB  bool
+}
+
+// NewSQLStore wraps an open database handle and creates the usage table when missing.
+// table may be schema qualified and must already be quoted when necessary.
+func NewSQLStore(ctx context.Context, db *sql.DB, dialect Dialect, table string, ownsDB bool) (*SQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("usage store: database is nil")
+	}
+	if table == "" {
+		table = DefaultUsageTable
+	}
+	s := &SQLStore{db: db, dialect: dialect, table: table, ownsDB: ownsDB}
+	if err := s.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenSQLiteStore opens (or creates) a SQLite database file for usage records.
+func OpenSQLiteStore(ctx context.Context, path string) (*SQLStore, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("usage store: sqlite path is required")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: open sqlite: %w", err)
+	}
+	// SQLite serializes writers; a single connection avoids SQLITE_BUSY between our own goroutines.
+	db.SetMaxOpenConns(1)
+	if _, err = db.ExecContext(ctx, "PRAGMA journal_mode=WAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("usage store: configure sqlite: %w", err)
+	}
+	store, err := NewSQLStore(ctx, db, DialectSQLite, DefaultUsageTable, true)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Name identifies the backend.
+func (s *SQLStore) Name() string { return string(s.dialect) }
+
+// Close releases the database handle when the store owns it.
+func (s *SQLStore) Close() error {
+	if s == nil || s.db == nil || !s.ownsDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *SQLStore) ensureSchema(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			requested_at BIGINT NOT NULL,
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			api_key TEXT NOT NULL DEFAULT '',
+			api_key_hash TEXT NOT NULL DEFAULT '',
+			auth_id TEXT NOT NULL DEFAULT '',
+			auth_index TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			failed INTEGER NOT NULL DEFAULT 0,
+			input_tokens BIGINT NOT NULL DEFAULT 0,
+			output_tokens BIGINT NOT NULL DEFAULT 0,
+			reasoning_tokens BIGINT NOT NULL DEFAULT 0,
+			cached_tokens BIGINT NOT NULL DEFAULT 0,
+			total_tokens BIGINT NOT NULL DEFAULT 0
+		)`, s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (requested_at)", s.indexName("requested_at"), s.table),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("usage store: create schema: %w", err)
+		}
+	}
+	return s.migrateAPIKeys(ctx)
+}
+
+// migrateAPIKeys adds the api_key_hash column to tables created before it existed and
+// replaces the raw client keys stored there with their masked form.
+func (s *SQLStore) migrateAPIKeys(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT api_key_hash FROM %s LIMIT 0", s.table))
+	if err == nil {
+		_ = rows.Close()
+		return nil
+	}
+	if _, err = s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN api_key_hash TEXT NOT NULL DEFAULT ''", s.table)); err != nil {
+		return fmt.Errorf("usage store: add api_key_hash column: %w", err)
+	}
+	rows, err = s.db.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT api_key FROM %s WHERE api_key <> ''", s.table))
+	if err != nil {
+		return fmt.Errorf("usage store: list stored keys: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("usage store: scan stored key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	_ = rows.Close()
+	update := fmt.Sprintf("UPDATE %s SET api_key = %s, api_key_hash = %s WHERE api_key = %s AND api_key_hash = ''",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3))
+	for _, key := range keys {
+		if _, err = s.db.ExecContext(ctx, update, util.HideAPIKey(key), util.HashAPIKey(key), key); err != nil {
+			return fmt.Errorf("usage store: mask stored key: %w", err)
+		}
+	}
+	return nil
+}
+
+// indexName derives an unqualified index name from the (possibly schema qualified) table.
+func (s *SQLStore) indexName(column string) string {
+	base := s.table
+	if idx := strings.LastIndex(base, "."); idx >= 0 {
+		base = base[idx+1:]
+	}
+	base = strings.Trim(base, `"`)
+	return fmt.Sprintf(`"%s_%s_idx"`, base, column)
+}
+
+// placeholder returns the n-th (1-based) bind parameter for the dialect.
+func (s *SQLStore) placeholder(n int) string {
+	if s.dialect == DialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Insert writes records in a single transaction.
+func (s *SQLStore) Insert(ctx context.Context, records []coreusage.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("usage store: begin: %w", err)
+	}
+	placeholders := make([]string, 14)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (requested_at, provider, model, api_key, auth_id, auth_index, source, failed,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens, api_key_hash) VALUES (%s)`, s.table, strings.Join(placeholders, ", "))
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("usage store: prepare insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, record := range records {
+		requestedAt := record.RequestedAt
+		if requestedAt.IsZero() {
+			requestedAt = time.Now()
+		}
+		failed := 0
+		if record.Failed {
+			failed = 1
+		}
+		detail := record.Detail
+		if detail.TotalTokens == 0 {
+			detail.TotalTokens = detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
+		}
+		if _, err = stmt.ExecContext(ctx, requestedAt.Unix(), record.Provider, record.Model, util.HideAPIKey(record.APIKey), record.AuthID,
+			record.AuthIndex, record.Source, failed, detail.InputTokens, detail.OutputTokens, detail.ReasoningTokens,
+			detail.CachedTokens, detail.TotalTokens, util.HashAPIKey(record.APIKey)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("usage store: insert: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("usage store: commit: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes records older than the cutoff and reports how many were removed.
+func (s *SQLStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE requested_at < %s", s.table, s.placeholder(1)), before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("usage store: prune: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// History aggregates records matching q into time buckets ordered by start time and group.
+func (s *SQLStore) History(ctx context.Context, q HistoryQuery) ([]HistoryBucket, error) {
+	var width int64
+	switch q.Bucket {
+	case BucketHour:
+		width = 3600
+	case BucketDay:
+		width = 86400
+	case BucketNone:
+	default:
+		return nil, fmt.Errorf("usage store: unsupported bucket %q", q.Bucket)
+	}
+	var dimension historyDimension
+	if q.GroupBy != "" {
+		var ok bool
+		dimension, ok = historyDimensions[q.GroupBy]
+		if !ok {
+			return nil, fmt.Errorf("usage store: unsupported group_by %q", q.GroupBy)
+		}
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	addFilter := func(expr string, value any) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(expr, s.placeholder(len(args))))
+	}
+	if !q.From.IsZero() {
+		addFilter("requested_at >= %s", q.From.Unix())
+	}
+	if !q.To.IsZero() {
+		addFilter("requested_at < %s", q.To.Unix())
+	}
+	for _, f := range []struct{ column, value string }{
+		{"api_key_hash", util.HashAPIKey(q.APIKey)}, {"auth_index", q.AuthIndex}, {"provider", q.Provider}, {"model", q.Model},
+	} {
+		if f.value != "" {
+			addFilter(f.column+" = %s", f.value)
+		}
+	}
+
+	bucketExpr := "0"
+	if width > 0 {
+		bucketExpr = fmt.Sprintf("(requested_at / %d) * %d", width, width)
+	}
+	groupExpr := "''"
+	if dimension.column != "" {
+		groupExpr = dimension.label
+	}
+	query := fmt.Sprintf(`SELECT %[1]s AS bucket, %[2]s AS grp, COUNT(*), COALESCE(SUM(failed), 0),
+		COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(reasoning_tokens), 0),
+		COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(total_tokens), 0)
+		FROM %[3]s`, bucketExpr, groupExpr, s.table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// Constant bucket or group expressions are omitted: both dialects read integer literals
+	// in GROUP BY as column positions.
+	var groupTerms []string
+	if width > 0 {
+		groupTerms = append(groupTerms, bucketExpr)
+	}
+	if dimension.column != "" {
+		groupTerms = append(groupTerms, dimension.column)
+	}
+	if len(groupTerms) > 0 {
+		query += " GROUP BY " + strings.Join(groupTerms, ", ") + " ORDER BY 1, 2"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, usageQueryTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: query history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]HistoryBucket, 0)
+	for rows.Next() {
+		var (
+			bucketStart int64
+			b           HistoryBucket
+		)
+		if err = rows.Scan(&bucketStart, &b.Group, &b.Requests, &b.Failed, &b.InputTokens, &b.OutputTokens,
+			&b.ReasoningTokens, &b.CachedTokens, &b.TotalTokens); err != nil {
+			return nil, fmt.Errorf("usage store: scan history: %w", err)
+		}
+		switch {
+		case width > 0:
+			b.Start = time.Unix(bucketStart, 0).UTC()
+		case !q.From.IsZero():
+			b.Start = q.From.UTC()
+		}
+		out = append(out, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage store: read history: %w", err)
+	}
+	return out, nil
+}
+
+// TokensByKey sums the tokens recorded since the given time per client key hash
+// (see util.HashAPIKey).
+func (s *SQLStore) TokensByKey(ctx context.Context, since time.Time) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, usageQueryTimeout)
+	defer cancel()
+	query := fmt.Sprintf(`SELECT api_key_hash, COALESCE(SUM(total_tokens), 0) FROM %s
+		WHERE requested_at >= %s AND api_key_hash <> '' GROUP BY api_key_hash`, s.table, s.placeholder(1))
+	rows, err := s.db.QueryContext(ctx, query, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("usage store: query key totals: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := make(map[string]int64)
+	for rows.Next() {
+		var (
+			hash   string
+			tokens int64
+		)
+		if err = rows.Scan(&hash, &tokens); err != nil {
+			return nil, fmt.Errorf("usage store: scan key totals: %w", err)
+		}
+		out[hash] = tokens
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage store: read key totals: %w", err)
+	}
+	return out, nil
+}
+
+// PersistentPlugin implements coreusage.Plugin by batching records into a SQLStore.
+type PersistentPlugin struct {
+	store     *SQLStore
+	retention time.Duration
+	queue     chan coreusage.Record
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+	dropped   atomic.Int64
+}
+
+// NewPersistentPlugin starts the background writer. retention <= 0 keeps records forever.
+func NewPersistentPlugin(store *SQLStore, retention time.Duration) *PersistentPlugin {
+	p := &PersistentPlugin{
+		store:     store,
+		retention: retention,
+		queue:     make(chan coreusage.Record, usageQueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Store returns the backing store.
+func (p *PersistentPlugin) Store() *SQLStore { return p.store }
+
+// HandleUsage implements coreusage.Plugin.
+func (p *PersistentPlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if p == nil {
+		return
+	}
+	if record.RequestedAt.IsZero() {
+		record.RequestedAt = time.Now()
+	}
+	if !record.Failed {
+		record.Failed = !resolveSuccess(ctx)
+	}
+	select {
+	case p.queue <- record:
+	default:
+		if p.dropped.Add(1)%100 == 1 {
+			log.Warnf("usage store: write queue full, dropping records (%d dropped so far)", p.dropped.Load())
+		}
+	}
+}
+
+// Close flushes pending records and stops the writer.
+func (p *PersistentPlugin) Close() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+func (p *PersistentPlugin) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	batch := make([]coreusage.Record, 0, usageFlushBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), usageQueryTimeout)
+		if err := p.store.Insert(ctx, batch); err != nil {
+			log.Warnf("usage store: %v", err)
+		}
+		cancel()
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case record := <-p.queue:
+			batch = append(batch, record)
+			if len(batch) >= usageFlushBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			if p.retention > 0 && time.Since(lastPrune) > time.Hour {
+				lastPrune = time.Now()
+				ctx, cancel := context.WithTimeout(context.Background(), usageQueryTimeout)
+				if removed, err := p.store.Prune(ctx, time.Now().Add(-p.retention)); err != nil {
+					log.Warnf("usage store: %v", err)
+				} else if removed > 0 {
+					log.Debugf("usage store: pruned %d records", removed)
+				}
+				cancel()
+			}
+		case <-p.stop:
+			for {
+				select {
+				case record := <-p.queue:
+					batch = append(batch, record)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+var (
+	activeStore  atomic.Pointer[SQLStore]
+	activePlugin atomic.Pointer[PersistentPlugin]
+)
+
+// SetPersistentPlugin registers the plugin flushed by ClosePersistentStore.
+func SetPersistentPlugin(plugin *PersistentPlugin) { activePlugin.Store(plugin) }
+
+// ClosePersistentStore flushes the records still queued in the registered persistent plugin
+// and closes its store. It is called on shutdown after the usage dispatcher has drained.
+func ClosePersistentStore() {
+	plugin := activePlugin.Swap(nil)
+	if plugin == nil {
+		return
+	}
+	plugin.Close()
+	activeStore.CompareAndSwap(plugin.store, nil)
+	if err := plugin.store.Close(); err != nil {
+		log.Warnf("usage store: close: %v", err)
+	}
+}
+
+// SetHistoryStore registers the store queried by the management usage history endpoint.
+func SetHistoryStore(store *SQLStore) { activeStore.Store(store) }
+
+// HistoryStore returns the registered persistent store, or nil when persistence is disabled.
+func HistoryStore() *SQLStore { return activeStore.Load() }
@@ -0,0 +1,130 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestSQLiteStore_HistoryBuckets(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLiteStore(ctx, filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	base := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	records := []coreusage.Record{
+		{Provider: "gemini", Model: "gemini-2.5-pro", APIKey: "k1", AuthIndex: "0", RequestedAt: base, Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5}},
+		{Provider: "gemini", Model: "gemini-2.5-pro", APIKey: "k2", AuthIndex: "1", RequestedAt: base.Add(20 * time.Minute), Failed: true},
+		{Provider: "claude", Model: "claude-sonnet-4", APIKey: "k1", AuthIndex: "2", RequestedAt: base.Add(time.Hour), Detail: coreusage.Detail{TotalTokens: 100, CachedTokens: 40}},
+		{Provider: "claude", Model: "claude-sonnet-4", APIKey: "k1", AuthIndex: "2", RequestedAt: base.Add(26 * time.Hour), Detail: coreusage.Detail{TotalTokens: 7}},
+	}
+	if err = store.Insert(ctx, records); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	hourly, err := store.History(ctx, HistoryQuery{From: base.Add(-time.Hour), To: base.Add(24 * time.Hour), Bucket: BucketHour})
+	if err != nil {
+		t.Fatalf("History(hour) error = %v", err)
+	}
+	if len(hourly) != 2 {
+		t.Fatalf("History(hour) buckets = %+v, want 2", hourly)
+	}
+	if !hourly[0].Start.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) || hourly[0].Requests != 2 || hourly[0].Failed != 1 || hourly[0].TotalTokens != 15 {
+		t.Fatalf("History(hour)[0] = %+v", hourly[0])
+	}
+	if hourly[1].TotalTokens != 100 || hourly[1].CachedTokens != 40 {
+		t.Fatalf("History(hour)[1] = %+v", hourly[1])
+	}
+
+	daily, err := store.History(ctx, HistoryQuery{APIKey: "k1", Bucket: BucketDay, GroupBy: "model"})
+	if err != nil {
+		t.Fatalf("History(day) error = %v", err)
+	}
+	if len(daily) != 3 {
+		t.Fatalf("History(day) buckets = %+v, want 3", daily)
+	}
+	if daily[0].Group != "claude-sonnet-4" || daily[1].Group != "gemini-2.5-pro" || daily[2].TotalTokens != 7 {
+		t.Fatalf("History(day) = %+v", daily)
+	}
+
+	removed, err := store.Prune(ctx, base.Add(2*time.Hour))
+	if err != nil || removed != 3 {
+		t.Fatalf("Prune() = %d, %v, want 3", removed, err)
+	}
+}
+
+func TestPersistentPlugin_FlushesOnClose(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLiteStore(ctx, filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	plugin := NewPersistentPlugin(store, 0)
+	plugin.HandleUsage(ctx, coreusage.Record{Provider: "codex", Model: "gpt-5", Detail: coreusage.Detail{TotalTokens: 3}})
+	plugin.HandleUsage(ctx, coreusage.Record{Provider: "codex", Model: "gpt-5", Detail: coreusage.Detail{TotalTokens: 4}})
+	plugin.Close()
+
+	totals, err := store.History(ctx, HistoryQuery{Provider: "codex"})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(totals) != 1 || totals[0].Requests != 2 || totals[0].TotalTokens != 7 {
+		t.Fatalf("History() = %+v, want 2 requests / 7 tokens", totals)
+	}
+}
+
+func TestSQLiteStore_MasksAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLiteStore(ctx, filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	now := time.Now()
+	records := []coreusage.Record{
+		{Provider: "gemini", Model: "m", APIKey: "sk-client-one-secret", RequestedAt: now, Detail: coreusage.Detail{TotalTokens: 3}},
+		{Provider: "gemini", Model: "m", APIKey: "sk-client-two-secret", RequestedAt: now, Detail: coreusage.Detail{TotalTokens: 5}},
+	}
+	if err = store.Insert(ctx, records); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	var raw int
+	if err = store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM usage_records WHERE api_key LIKE '%secret%'").Scan(&raw); err != nil || raw != 0 {
+		t.Fatalf("raw keys stored: count = %d, err = %v", raw, err)
+	}
+
+	grouped, err := store.History(ctx, HistoryQuery{GroupBy: "api_key"})
+	if err != nil {
+		t.Fatalf("History(group_by=api_key) error = %v", err)
+	}
+	if len(grouped) != 2 || grouped[0].Group != "sk-c...cret" || grouped[1].Group != "sk-c...cret" {
+		t.Fatalf("History(group_by=api_key) = %+v, want two masked groups", grouped)
+	}
+
+	filtered, err := store.History(ctx, HistoryQuery{APIKey: "sk-client-two-secret"})
+	if err != nil {
+		t.Fatalf("History(api_key) error = %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].TotalTokens != 5 {
+		t.Fatalf("History(api_key) = %+v, want the second key only", filtered)
+	}
+
+	totals, err := store.TokensByKey(ctx, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("TokensByKey() error = %v", err)
+	}
+	if totals[util.HashAPIKey("sk-client-one-secret")] != 3 || totals[util.HashAPIKey("sk-client-two-secret")] != 5 {
+		t.Fatalf("TokensByKey() = %v", totals)
+	}
+}
@@ -4,6 +4,8 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"strings"
 
@@ -170,6 +172,22 @@ func HideAPIKey(apiKey string) string {
 	return apiKey
 }
 
+// HashAPIKey returns a stable, non-reversible identifier for an API key so stored records can
+// be matched against a key without keeping the key itself. Empty keys hash to "".
+//
+// Parameters:
+//   - apiKey: The API key to hash.
+//
+// Returns:
+//   - string: The hex-encoded identifier.
+func HashAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:16])
+}
+
 // maskAuthorizationHeader masks the Authorization header value while preserving the auth type prefix.
 // Common formats: "Bearer <token>", "Basic <credentials>", "ApiKey <key>", etc.
 // It preserves the prefix (e.g., "Bearer ") and only masks the token/credential part.
@@ -253,6 +271,29 @@ func MaskSensitiveQuery(raw string) string {
 	return strings.Join(parts, "&")
 }
 
+// StripSensitiveQuery removes the query parameters MaskSensitiveQuery would mask.
+func StripSensitiveQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parts := strings.Split(raw, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		keyPart, _, _ := strings.Cut(part, "=")
+		decodedKey, err := url.QueryUnescape(keyPart)
+		if err != nil {
+			decodedKey = keyPart
+		}
+		if !shouldMaskQueryParam(decodedKey) {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
 func shouldMaskQueryParam(key string) bool {
 	key = strings.ToLower(strings.TrimSpace(key))
 	if key == "" {
@@ -3,6 +3,7 @@ package diff
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"sort"
 	"strings"
 
@@ -29,6 +30,11 @@ type VertexModelsSummary struct {
 	count int
 }
 
+type OpenAICompatModelsSummary struct {
+	hash  string
+	count int
+}
+
 // SummarizeGeminiModels hashes Gemini model aliases for change detection.
 func SummarizeGeminiModels(models []config.GeminiModel) GeminiModelsSummary {
 	if len(models) == 0 {
@@ -119,3 +125,74 @@ func SummarizeVertexModels(models []config.VertexCompatModel) VertexModelsSummar
 		count: len(names),
 	}
 }
+
+// SummarizeOpenAICompatModels hashes OpenAI-compatible model aliases for change detection.
+func SummarizeOpenAICompatModels(models []config.OpenAICompatibilityModel) OpenAICompatModelsSummary {
+	if len(models) == 0 {
+		return OpenAICompatModelsSummary{}
+	}
+	keys := normalizeModelPairs(func(out func(key string)) {
+		for _, model := range models {
+			name := strings.TrimSpace(model.Name)
+			alias := strings.TrimSpace(model.Alias)
+			if name == "" && alias == "" {
+				continue
+			}
+			out(strings.ToLower(name) + "|" + strings.ToLower(alias))
+		}
+	})
+	return OpenAICompatModelsSummary{
+		hash:  hashJoined(keys),
+		count: len(keys),
+	}
+}
+
+// DiffOpenAICompatModels describes how an OpenAI-compatible model list changed, naming the
+// client-facing models that were added or removed. It returns nil when the lists are equivalent.
+func DiffOpenAICompatModels(oldModels, newModels []config.OpenAICompatibilityModel) []string {
+	oldSummary := SummarizeOpenAICompatModels(oldModels)
+	newSummary := SummarizeOpenAICompatModels(newModels)
+	if oldSummary.hash == newSummary.hash {
+		return nil
+	}
+	oldIDs := openAICompatModelIDs(oldModels)
+	newIDs := openAICompatModelIDs(newModels)
+	changes := []string{fmt.Sprintf("models %d -> %d", oldSummary.count, newSummary.count)}
+	if added := missingKeys(newIDs, oldIDs); len(added) > 0 {
+		changes = append(changes, "added: "+strings.Join(added, ", "))
+	}
+	if removed := missingKeys(oldIDs, newIDs); len(removed) > 0 {
+		changes = append(changes, "removed: "+strings.Join(removed, ", "))
+	}
+	if len(changes) == 1 && oldSummary.count == newSummary.count {
+		changes[0] = "model mappings updated"
+	}
+	return changes
+}
+
+// openAICompatModelIDs maps client-facing model IDs to their upstream names.
+func openAICompatModelIDs(models []config.OpenAICompatibilityModel) map[string]string {
+	ids := make(map[string]string, len(models))
+	for _, model := range models {
+		name := strings.TrimSpace(model.Name)
+		id := strings.TrimSpace(model.Alias)
+		if id == "" {
+			id = name
+		}
+		if id != "" {
+			ids[id] = name
+		}
+	}
+	return ids
+}
+
+func missingKeys(from, in map[string]string) []string {
+	var out []string
+	for key := range from {
+		if _, ok := in[key]; !ok {
+			out = append(out, key)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
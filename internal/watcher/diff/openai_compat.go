@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -75,12 +76,25 @@ func describeOpenAICompatibilityUpdate(oldEntry, newEntry config.OpenAICompatibi
 	if !equalStringMap(oldEntry.Headers, newEntry.Headers) {
 		details = append(details, "headers updated")
 	}
+	if oldProtocol, newProtocol := config.NormalizeOpenAICompatProtocol(oldEntry.Protocol), config.NormalizeOpenAICompatProtocol(newEntry.Protocol); oldProtocol != newProtocol {
+		details = append(details, fmt.Sprintf("protocol %s -> %s", oldProtocol, newProtocol))
+	}
+	if !reflect.DeepEqual(oldEntry.ModelDiscovery, newEntry.ModelDiscovery) {
+		details = append(details, fmt.Sprintf("model-discovery %s", describeModelDiscovery(newEntry.ModelDiscovery)))
+	}
 	if len(details) == 0 {
 		return ""
 	}
 	return "(" + strings.Join(details, ", ") + ")"
 }
 
+func describeModelDiscovery(discovery config.OpenAICompatibilityModelDiscovery) string {
+	if !discovery.Enabled {
+		return "disabled"
+	}
+	return fmt.Sprintf("enabled (interval=%s, include=%d, exclude=%d)", discovery.Interval(), len(discovery.Include), len(discovery.Exclude))
+}
+
 func countAPIKeys(entry config.OpenAICompatibility) int {
 	count := 0
 	for _, keyEntry := range entry.APIKeyEntries {
@@ -185,3 +185,42 @@ func TestOpenAICompatKeyUsesModelNameWhenAliasEmpty(t *testing.T) {
 		t.Fatalf("expected model-name fallback, got %s/%s", key, label)
 	}
 }
+
+func TestDiffOpenAICompatModels(t *testing.T) {
+	oldModels := []config.OpenAICompatibilityModel{{Name: "m1"}, {Name: "m2", Alias: "relay-m2"}}
+	if changes := DiffOpenAICompatModels(oldModels, []config.OpenAICompatibilityModel{{Name: "M2", Alias: "relay-m2"}, {Name: "m1"}}); changes != nil {
+		t.Fatalf("expected no changes for equivalent lists, got %v", changes)
+	}
+
+	newModels := []config.OpenAICompatibilityModel{{Name: "m2", Alias: "relay-m2"}, {Name: "m3"}, {Name: "m4"}}
+	changes := DiffOpenAICompatModels(oldModels, newModels)
+	expectContains(t, changes, "models 2 -> 3")
+	expectContains(t, changes, "added: m3, m4")
+	expectContains(t, changes, "removed: m1")
+
+	remapped := []config.OpenAICompatibilityModel{{Name: "m1"}, {Name: "m2-v2", Alias: "relay-m2"}}
+	changes = DiffOpenAICompatModels(oldModels, remapped)
+	if len(changes) != 1 || changes[0] != "model mappings updated" {
+		t.Fatalf("expected mapping update, got %v", changes)
+	}
+}
+
+func TestDiffOpenAICompatibility_ModelDiscovery(t *testing.T) {
+	oldList := []config.OpenAICompatibility{{Name: "relay", BaseURL: "https://relay"}}
+	newList := []config.OpenAICompatibility{{
+		Name:           "relay",
+		BaseURL:        "https://relay",
+		ModelDiscovery: config.OpenAICompatibilityModelDiscovery{Enabled: true, Include: []string{"gpt-*"}},
+	}}
+	changes := DiffOpenAICompatibility(oldList, newList)
+	expectContains(t, changes, "provider updated: relay (model-discovery enabled (interval=1h0m0s, include=1, exclude=0))")
+}
+
+func TestDiffOpenAICompatibility_Protocol(t *testing.T) {
+	oldList := []config.OpenAICompatibility{{Name: "relay", BaseURL: "https://relay"}}
+	newList := []config.OpenAICompatibility{{Name: "relay", BaseURL: "https://relay", Protocol: "messages"}}
+	expectContains(t, DiffOpenAICompatibility(oldList, newList), "provider updated: relay (protocol chat -> messages)")
+	if changes := DiffOpenAICompatibility(oldList, []config.OpenAICompatibility{{Name: "relay", BaseURL: "https://relay", Protocol: "chat"}}); len(changes) != 0 {
+		t.Fatalf("explicit chat protocol reported as a change: %v", changes)
+	}
+}
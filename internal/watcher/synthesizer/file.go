@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
 )
 
 // FileSynthesizer generates Auth entries from OAuth JSON files.
@@ -46,8 +48,14 @@ func (s *FileSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth, e
 			continue
 		}
 		full := filepath.Join(ctx.AuthDir, name)
-		data, errRead := os.ReadFile(full)
-		if errRead != nil || len(data) == 0 {
+		data, errRead := tokencrypt.ReadFile(full)
+		if errRead != nil {
+			if !os.IsNotExist(errRead) {
+				log.Warnf("skipping auth file %s: %v", name, errRead)
+			}
+			continue
+		}
+		if len(data) == 0 {
 			continue
 		}
 		var metadata map[string]any
@@ -66,6 +74,9 @@ func (s *FileSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth, e
 		if email, _ := metadata["email"].(string); email != "" {
 			label = email
 		}
+		if custom, _ := metadata["label"].(string); strings.TrimSpace(custom) != "" {
+			label = strings.TrimSpace(custom)
+		}
 		// Use relative path under authDir as ID to stay consistent with the file-based token store
 		id := full
 		if rel, errRel := filepath.Rel(ctx.AuthDir, full); errRel == nil && rel != "" {
@@ -101,6 +112,10 @@ func (s *FileSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth, e
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
+		if disabled, _ := metadata["disabled"].(bool); disabled {
+			a.Disabled = true
+			a.Status = coreauth.StatusDisabled
+		}
 		ApplyAuthExcludedModelsMeta(a, cfg, nil, "oauth")
 		if provider == "gemini-cli" {
 			if virtuals := SynthesizeGeminiVirtualAuths(a, metadata, now); len(virtuals) > 0 {
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -108,3 +109,10 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 		attrs["header:"+key] = val
 	}
 }
+
+func addWeightToAttrs(weight int, attrs map[string]string) {
+	if weight <= 0 || attrs == nil {
+		return
+	}
+	attrs["weight"] = strconv.Itoa(weight)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestExecuteEmbeddingWithAuthManager_RejectsChatOnlyProviders(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&failOnceStreamExecutor{})
+	auth := &coreauth.Auth{ID: "embed-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "embed-chat-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	stream, stop := events.Default().Subscribe(events.Filter{Model: "embed-chat-model"}, 4)
+	defer stop()
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	_, errMsg := handler.ExecuteEmbeddingWithAuthManager(context.Background(), "openai", "embed-chat-model", []byte(`{"input":"hi"}`), "embeddings")
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a chat-only provider, got %+v", errMsg)
+	}
+	for _, want := range []string{events.TypeStarted, events.TypeError} {
+		select {
+		case event := <-stream:
+			if event.Type != want {
+				t.Fatalf("live event type = %q, want %q", event.Type, want)
+			}
+		default:
+			t.Fatalf("embedding request did not publish a %q live event", want)
+		}
+	}
+}
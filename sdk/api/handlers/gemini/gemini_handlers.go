@@ -16,6 +16,7 @@ import (
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 )
 
@@ -70,7 +71,8 @@ func (h *GeminiAPIHandler) GeminiModels(c *gin.Context) {
 }
 
 // GeminiGetHandler handles GET requests for specific Gemini model information.
-// It returns detailed information about a specific Gemini model based on the action parameter.
+// Metadata comes from the model registry, so every registered model from every provider
+// can be described; unknown models yield a 404 in Google's error format.
 func (h *GeminiAPIHandler) GeminiGetHandler(c *gin.Context) {
 	var request struct {
 		Action string `uri:"action" binding:"required"`
@@ -84,95 +86,77 @@ func (h *GeminiAPIHandler) GeminiGetHandler(c *gin.Context) {
 		})
 		return
 	}
-	action := strings.TrimPrefix(request.Action, "/")
-	switch action {
-	case "gemini-3-pro-preview":
-		c.JSON(http.StatusOK, gin.H{
-			"name":             "models/gemini-3-pro-preview",
-			"version":          "3",
-			"displayName":      "Gemini 3 Pro Preview",
-			"description":      "Gemini 3 Pro Preview",
-			"inputTokenLimit":  1048576,
-			"outputTokenLimit": 65536,
-			"supportedGenerationMethods": []string{
-				"generateContent",
-				"countTokens",
-				"createCachedContent",
-				"batchGenerateContent",
-			},
-			"temperature":    1,
-			"topP":           0.95,
-			"topK":           64,
-			"maxTemperature": 2,
-			"thinking":       true,
-		},
-		)
-	case "gemini-2.5-pro":
-		c.JSON(http.StatusOK, gin.H{
-			"name":             "models/gemini-2.5-pro",
-			"version":          "2.5",
-			"displayName":      "Gemini 2.5 Pro",
-			"description":      "Stable release (June 17th, 2025) of Gemini 2.5 Pro",
-			"inputTokenLimit":  1048576,
-			"outputTokenLimit": 65536,
-			"supportedGenerationMethods": []string{
-				"generateContent",
-				"countTokens",
-				"createCachedContent",
-				"batchGenerateContent",
-			},
-			"temperature":    1,
-			"topP":           0.95,
-			"topK":           64,
-			"maxTemperature": 2,
-			"thinking":       true,
-		},
-		)
-	case "gemini-2.5-flash":
-		c.JSON(http.StatusOK, gin.H{
-			"name":             "models/gemini-2.5-flash",
-			"version":          "001",
-			"displayName":      "Gemini 2.5 Flash",
-			"description":      "Stable version of Gemini 2.5 Flash, our mid-size multimodal model that supports up to 1 million tokens, released in June of 2025.",
-			"inputTokenLimit":  1048576,
-			"outputTokenLimit": 65536,
-			"supportedGenerationMethods": []string{
-				"generateContent",
-				"countTokens",
-				"createCachedContent",
-				"batchGenerateContent",
-			},
-			"temperature":    1,
-			"topP":           0.95,
-			"topK":           64,
-			"maxTemperature": 2,
-			"thinking":       true,
-		})
-	case "gpt-5":
-		c.JSON(http.StatusOK, gin.H{
-			"name":             "gpt-5",
-			"version":          "001",
-			"displayName":      "GPT 5",
-			"description":      "Stable version of GPT 5, The best model for coding and agentic tasks across domains.",
-			"inputTokenLimit":  400000,
-			"outputTokenLimit": 128000,
-			"supportedGenerationMethods": []string{
-				"generateContent",
-			},
-			"temperature":    1,
-			"topP":           0.95,
-			"topK":           64,
-			"maxTemperature": 2,
-			"thinking":       true,
-		})
-	default:
-		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
-			Error: handlers.ErrorDetail{
-				Message: "Not Found",
-				Type:    "not_found",
-			},
-		})
+	modelName := strings.TrimPrefix(strings.TrimPrefix(request.Action, "/"), "models/")
+	info := lookupModelInfo(modelName)
+	if info == nil {
+		message := fmt.Sprintf("models/%s is not found for API version v1beta, or is not supported for getModel.", modelName)
+		c.Data(http.StatusNotFound, "application/json", handlers.BuildErrorResponseBodyForFormat(h.HandlerType(), http.StatusNotFound, message))
+		return
+	}
+	c.JSON(http.StatusOK, geminiModelMetadata(info))
+}
+
+// lookupModelInfo resolves a registered model, also accepting names with a thinking suffix.
+func lookupModelInfo(modelName string) *registry.ModelInfo {
+	if modelName == "" {
+		return nil
+	}
+	modelRegistry := registry.GetGlobalRegistry()
+	if info := modelRegistry.GetModelInfo(modelName); info != nil {
+		return info
+	}
+	if baseModel, _ := util.NormalizeThinkingModel(modelName); baseModel != modelName {
+		return modelRegistry.GetModelInfo(baseModel)
+	}
+	return nil
+}
+
+// geminiModelMetadata renders a registry entry as a Gemini API Model resource.
+// Fields missing from non-Gemini definitions are derived from their OpenAI-style equivalents.
+func geminiModelMetadata(info *registry.ModelInfo) map[string]any {
+	name := info.Name
+	if name == "" {
+		name = info.ID
+	}
+	if !strings.HasPrefix(name, "models/") {
+		name = "models/" + name
 	}
+	displayName := info.DisplayName
+	if displayName == "" {
+		displayName = info.ID
+	}
+	inputLimit := info.InputTokenLimit
+	if inputLimit <= 0 {
+		inputLimit = info.ContextLength
+	}
+	outputLimit := info.OutputTokenLimit
+	if outputLimit <= 0 {
+		outputLimit = info.MaxCompletionTokens
+	}
+	methods := info.SupportedGenerationMethods
+	if len(methods) == 0 {
+		methods = []string{"generateContent", "countTokens"}
+	}
+	result := map[string]any{
+		"name":                       name,
+		"baseModelId":                info.ID,
+		"displayName":                displayName,
+		"supportedGenerationMethods": methods,
+		"thinking":                   info.Thinking != nil,
+	}
+	if info.Version != "" {
+		result["version"] = info.Version
+	}
+	if info.Description != "" {
+		result["description"] = info.Description
+	}
+	if inputLimit > 0 {
+		result["inputTokenLimit"] = inputLimit
+	}
+	if outputLimit > 0 {
+		result["outputTokenLimit"] = outputLimit
+	}
+	return result
 }
 
 // GeminiHandler handles POST requests for Gemini API operations.
@@ -211,9 +195,33 @@ func (h *GeminiAPIHandler) GeminiHandler(c *gin.Context) {
 		h.handleStreamGenerateContent(c, action[0], rawJSON)
 	case "countTokens":
 		h.handleCountTokens(c, action[0], rawJSON)
+	case "embedContent", "batchEmbedContents":
+		h.handleEmbedContent(c, action[0], method, rawJSON)
 	}
 }
 
+// handleEmbedContent handles embedContent and batchEmbedContents requests for Gemini models.
+// Requests are routed through the auth manager like generation requests, so any provider
+// serving the model (Gemini, Vertex or OpenAI-compatible) can answer them.
+//
+// Parameters:
+//   - c: The Gin context for the request
+//   - modelName: The name of the embedding model
+//   - method: Either "embedContent" or "batchEmbedContents"
+//   - rawJSON: The raw JSON request body
+func (h *GeminiAPIHandler) handleEmbedContent(c *gin.Context, modelName, method string, rawJSON []byte) {
+	c.Header("Content-Type", "application/json")
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteEmbeddingWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, method)
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
 // handleStreamGenerateContent handles streaming content generation requests for Gemini models.
 // This function establishes a Server-Sent Events connection and streams the generated content
 // back to the client in real-time. It supports both SSE format and direct streaming based
@@ -0,0 +1,70 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+func serveGeminiGet(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h := NewGeminiAPIHandler(&handlers.BaseAPIHandler{})
+	router.GET("/v1beta/models/*action", h.GeminiGetHandler)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestGeminiGetHandlerDescribesRegisteredModels(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("gemini-get-test", "claude", []*registry.ModelInfo{{
+		ID:                  "get-test-claude",
+		DisplayName:         "Get Test Claude",
+		ContextLength:       200000,
+		MaxCompletionTokens: 64000,
+		Thinking:            &registry.ThinkingSupport{Min: 1024, Max: 100000},
+	}})
+	t.Cleanup(func() { reg.UnregisterClient("gemini-get-test") })
+
+	recorder := serveGeminiGet(t, "/v1beta/models/get-test-claude")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.Bytes()
+	if got := gjson.GetBytes(body, "name").String(); got != "models/get-test-claude" {
+		t.Fatalf("name = %q", got)
+	}
+	if got := gjson.GetBytes(body, "inputTokenLimit").Int(); got != 200000 {
+		t.Fatalf("inputTokenLimit = %d", got)
+	}
+	if got := gjson.GetBytes(body, "outputTokenLimit").Int(); got != 64000 {
+		t.Fatalf("outputTokenLimit = %d", got)
+	}
+	if !gjson.GetBytes(body, "thinking").Bool() {
+		t.Fatalf("thinking = false: %s", body)
+	}
+	if got := gjson.GetBytes(body, "supportedGenerationMethods.0").String(); got != "generateContent" {
+		t.Fatalf("supportedGenerationMethods = %s", gjson.GetBytes(body, "supportedGenerationMethods").Raw)
+	}
+}
+
+func TestGeminiGetHandlerUnknownModel(t *testing.T) {
+	recorder := serveGeminiGet(t, "/v1beta/models/no-such-model")
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	body := recorder.Body.Bytes()
+	if got := gjson.GetBytes(body, "error.status").String(); got != "NOT_FOUND" {
+		t.Fatalf("error.status = %q, body = %s", got, body)
+	}
+	if got := gjson.GetBytes(body, "error.code").Int(); got != http.StatusNotFound {
+		t.Fatalf("error.code = %d", got)
+	}
+}
@@ -6,20 +6,28 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 )
 
@@ -100,6 +108,62 @@ func BuildErrorResponseBody(status int, errText string) []byte {
 	return payload
 }
 
+// BuildErrorResponseBodyForFormat builds an error body in the schema of the given handler type
+// ("claude", "gemini", "gemini-cli", otherwise OpenAI via BuildErrorResponseBody).
+func BuildErrorResponseBodyForFormat(handlerType string, status int, errText string) []byte {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	if strings.TrimSpace(errText) == "" {
+		errText = http.StatusText(status)
+	}
+	switch handlerType {
+	case "claude":
+		errType := "api_error"
+		switch status {
+		case http.StatusBadRequest:
+			errType = "invalid_request_error"
+		case http.StatusUnauthorized:
+			errType = "authentication_error"
+		case http.StatusForbidden:
+			errType = "permission_error"
+		case http.StatusNotFound:
+			errType = "not_found_error"
+		case http.StatusTooManyRequests:
+			errType = "rate_limit_error"
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": errType, "message": errText},
+		})
+		return payload
+	case "gemini", "gemini-cli":
+		errStatus := "UNKNOWN"
+		switch status {
+		case http.StatusBadRequest:
+			errStatus = "INVALID_ARGUMENT"
+		case http.StatusUnauthorized:
+			errStatus = "UNAUTHENTICATED"
+		case http.StatusForbidden:
+			errStatus = "PERMISSION_DENIED"
+		case http.StatusNotFound:
+			errStatus = "NOT_FOUND"
+		case http.StatusTooManyRequests:
+			errStatus = "RESOURCE_EXHAUSTED"
+		default:
+			if status >= http.StatusInternalServerError {
+				errStatus = "INTERNAL"
+			}
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"error": map[string]any{"code": status, "message": errText, "status": errStatus},
+		})
+		return payload
+	default:
+		return BuildErrorResponseBody(status, errText)
+	}
+}
+
 // StreamingKeepAliveInterval returns the SSE keep-alive interval for this server.
 // Returning 0 disables keep-alives (default when unset).
 func StreamingKeepAliveInterval(cfg *config.SDKConfig) time.Duration {
@@ -240,6 +304,11 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
+	if requestCtx != nil && !trace.SpanContextFromContext(parentCtx).IsValid() {
+		if span := trace.SpanFromContext(requestCtx); span.SpanContext().IsValid() {
+			parentCtx = trace.ContextWithSpan(parentCtx, span)
+		}
+	}
 	newCtx, cancel := context.WithCancel(parentCtx)
 	if requestCtx != nil && requestCtx != parentCtx {
 		go func() {
@@ -317,11 +386,25 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
-func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	ctx, span := startExecuteSpan(ctx, "ExecuteWithAuthManager", handlerType, modelName)
+	live := startLiveRequest(ctx, handlerType, modelName, false)
+	defer func() {
+		endExecuteSpan(span, errMsg)
+		live.finish(errMsg)
+	}()
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	ctx, providers, errMsg = enforceKeyPolicy(ctx, handlerType, modelName, providers, true)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	ctx, cacheKey, cached := responseCacheLookup(ctx, handlerType, modelName, normalizedModel, false, alt, rawJSON)
+	if cached != nil {
+		return cloneBytes(cached.Payload), nil
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
@@ -353,16 +436,30 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		}
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
+	if cacheKey != "" {
+		cache.DefaultResponseCache().Put(&cache.CachedResponse{Key: cacheKey, Model: modelName, Format: handlerType, Payload: cloneBytes(resp.Payload)})
+	}
 	return cloneBytes(resp.Payload), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
-// This path is the only supported execution route.
-func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+// This path is the only supported execution route. Counts are reused for identical payloads,
+// and a local estimate is returned when no credential can count a Claude or Gemini request.
+func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	ctx, span := startExecuteSpan(ctx, "ExecuteCountWithAuthManager", handlerType, modelName)
+	live := startLiveRequest(ctx, handlerType, modelName, false)
+	defer func() {
+		endExecuteSpan(span, errMsg)
+		live.finish(errMsg)
+	}()
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	ctx, providers, errMsg = enforceKeyPolicy(ctx, handlerType, modelName, providers, false)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
@@ -378,7 +475,98 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = mergeMetadata(cloneMetadata(metadata), reqMeta)
+	countCache := cache.DefaultTokenCountCache()
+	cacheKey := cache.TokenCountKey(handlerType, normalizedModel, alt, rawJSON)
+	if cached, ok := countCache.Get(cacheKey); ok {
+		setTokenCountSource(ctx, "cache")
+		return cached, nil
+	}
 	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
+	if err != nil {
+		if estimate := h.estimateTokenCount(ctx, handlerType, rawJSON, err); estimate != nil {
+			return estimate, nil
+		}
+		status := http.StatusInternalServerError
+		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
+			if code := se.StatusCode(); code > 0 {
+				status = code
+			}
+		}
+		var addon http.Header
+		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
+			if hdr := he.Headers(); hdr != nil {
+				addon = hdr.Clone()
+			}
+		}
+		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+	}
+	countCache.Put(cacheKey, resp.Payload)
+	setTokenCountSource(ctx, "upstream")
+	return cloneBytes(resp.Payload), nil
+}
+
+// chatOnlyProviders lists providers whose executors have no embedding endpoint and would
+// otherwise run embedding requests as chat completions.
+var chatOnlyProviders = map[string]struct{}{
+	"claude":      {},
+	"codex":       {},
+	"antigravity": {},
+	"gemini-cli":  {},
+	"aistudio":    {},
+	"qwen":        {},
+	"iflow":       {},
+}
+
+// embeddingProviders drops providers that cannot serve embedding requests.
+func embeddingProviders(providers []string) []string {
+	out := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if _, chatOnly := chatOnlyProviders[strings.ToLower(provider)]; !chatOnly {
+			out = append(out, provider)
+		}
+	}
+	return out
+}
+
+// ExecuteEmbeddingWithAuthManager executes an embedding request via the core auth manager.
+// The action ("embeddings", "embedContent" or "batchEmbedContents") is passed to executors
+// through request metadata so they call the provider's embedding endpoint.
+func (h *BaseAPIHandler) ExecuteEmbeddingWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, action string) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	ctx, span := startExecuteSpan(ctx, "ExecuteEmbeddingWithAuthManager", handlerType, modelName)
+	live := startLiveRequest(ctx, handlerType, modelName, false)
+	defer func() {
+		endExecuteSpan(span, errMsg)
+		live.finish(errMsg)
+	}()
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	providers = embeddingProviders(providers)
+	if len(providers) == 0 {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("model %s does not support embeddings", modelName)}
+	}
+	ctx, providers, errMsg = enforceKeyPolicy(ctx, handlerType, modelName, providers, true)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	reqMeta := requestExecutionMetadata(ctx)
+	req := coreexecutor.Request{
+		Model:    normalizedModel,
+		Payload:  cloneBytes(rawJSON),
+		Metadata: cloneMetadata(metadata),
+	}
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]any, 1)
+	}
+	req.Metadata["action"] = action
+	opts := coreexecutor.Options{
+		Stream:          false,
+		OriginalRequest: cloneBytes(rawJSON),
+		SourceFormat:    sdktranslator.FromString(handlerType),
+	}
+	opts.Metadata = mergeMetadata(cloneMetadata(metadata), reqMeta)
+	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -400,13 +588,26 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	ctx, span := startExecuteSpan(ctx, "ExecuteStreamWithAuthManager", handlerType, modelName)
+	live := startLiveRequest(ctx, handlerType, modelName, true)
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		ctx, providers, errMsg = enforceKeyPolicy(ctx, handlerType, modelName, providers, true)
+	}
 	if errMsg != nil {
+		endExecuteSpan(span, errMsg)
+		live.finish(errMsg)
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
+	ctx, cacheKey, cached := responseCacheLookup(ctx, handlerType, modelName, normalizedModel, true, alt, rawJSON)
+	if cached != nil {
+		endExecuteSpan(span, nil)
+		live.finish(nil)
+		return replayCachedStream(ctx, cached)
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
@@ -437,16 +638,26 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				addon = hdr.Clone()
 			}
 		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		errMsg = &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		endExecuteSpan(span, errMsg)
+		live.finish(errMsg)
+		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 	go func() {
+		var streamErrMsg *interfaces.ErrorMessage
+		defer func() {
+			endExecuteSpan(span, streamErrMsg)
+			live.finish(streamErrMsg)
+		}()
 		defer close(dataChan)
 		defer close(errChan)
 		sentPayload := false
+		var cacheChunks [][]byte
+		completed := false
 		bootstrapRetries := 0
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
 
@@ -479,6 +690,10 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					chunk, ok = <-chunks
 				}
 				if !ok {
+					// A stream that closed after cancellation or without its terminal event is truncated.
+					if cacheKey != "" && completed && (ctx == nil || ctx.Err() == nil) {
+						cache.DefaultResponseCache().Put(&cache.CachedResponse{Key: cacheKey, Model: modelName, Format: handlerType, Stream: true, Chunks: cacheChunks})
+					}
 					return
 				}
 				if chunk.Err != nil {
@@ -509,11 +724,16 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 							addon = hdr.Clone()
 						}
 					}
-					errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon}
+					streamErrMsg = &interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon}
+					errChan <- streamErrMsg
 					return
 				}
 				if len(chunk.Payload) > 0 {
 					sentPayload = true
+					if cacheKey != "" {
+						cacheChunks = append(cacheChunks, cloneBytes(chunk.Payload))
+						completed = completed || isTerminalStreamChunk(chunk.Payload)
+					}
 					dataChan <- cloneBytes(chunk.Payload)
 				}
 			}
@@ -522,6 +742,27 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	return dataChan, errChan
 }
 
+// startExecuteSpan opens the span covering one handler execution through the auth manager.
+func startExecuteSpan(ctx context.Context, name, handlerType, modelName string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "BaseAPIHandler."+name,
+		attribute.String("cliproxy.handler", handlerType),
+		attribute.String("cliproxy.model", modelName),
+	)
+}
+
+func endExecuteSpan(span trace.Span, errMsg *interfaces.ErrorMessage) {
+	if errMsg == nil {
+		tracing.End(span, nil)
+		return
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", errMsg.StatusCode))
+	err := errMsg.Error
+	if err == nil {
+		err = fmt.Errorf("status %d", errMsg.StatusCode)
+	}
+	tracing.End(span, err)
+}
+
 func statusFromError(err error) int {
 	if err == nil {
 		return 0
@@ -568,6 +809,37 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 	return providers, normalizedModel, metadata, nil
 }
 
+// enforceKeyPolicy applies the client API key policy to the request, narrowing the provider
+// list to the providers the key may use. Violations carry a body in the handler's format.
+// The returned context carries the same model and provider restrictions for fallback models.
+// rateLimited is false for auxiliary calls (token counting) that do not count as requests.
+func enforceKeyPolicy(ctx context.Context, handlerType, modelName string, providers []string, rateLimited bool) (context.Context, []string, *interfaces.ErrorMessage) {
+	apiKey := ""
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			apiKey = ginCtx.GetString("apiKey")
+		}
+	}
+	if apiKey == "" {
+		return ctx, providers, nil
+	}
+	allowed, violation := policy.Default().Check(policy.Request{APIKey: apiKey, Model: modelName, Providers: providers, SkipRateLimit: !rateLimited})
+	if violation == nil {
+		ctx = coreauth.WithFallbackFilter(ctx, func(model string, providers []string) []string {
+			return policy.Default().Allowed(apiKey, model, providers)
+		})
+		return ctx, allowed, nil
+	}
+	errMsg := &interfaces.ErrorMessage{
+		StatusCode: violation.StatusCode,
+		Error:      errors.New(string(BuildErrorResponseBodyForFormat(handlerType, violation.StatusCode, violation.Message))),
+	}
+	if violation.RetryAfter > 0 {
+		errMsg.Addon = http.Header{"Retry-After": {strconv.Itoa(int(math.Ceil(violation.RetryAfter.Seconds())))}}
+	}
+	return ctx, nil, errMsg
+}
+
 func cloneBytes(src []byte) []byte {
 	if len(src) == 0 {
 		return nil
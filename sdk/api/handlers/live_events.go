@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// liveRequest reports one handler execution to the live request stream. A nil liveRequest,
+// returned when nobody is listening, ignores every call.
+type liveRequest struct {
+	bus    *events.Bus
+	ginCtx *gin.Context
+	// recordsFrom is how many usage records the request had collected before this execution.
+	recordsFrom int
+	base        events.Event
+	started     time.Time
+}
+
+// startLiveRequest publishes the "started" event and begins collecting the usage records the
+// "completed" event reports. A collection the request logging middleware already tracks is
+// reused, and only records added after this point are reported.
+func startLiveRequest(ctx context.Context, handlerType, modelName string, stream bool) *liveRequest {
+	bus := events.Default()
+	if !bus.Active() {
+		return nil
+	}
+	r := &liveRequest{
+		bus:     bus,
+		base:    events.Event{RequestID: logging.GetRequestID(ctx), Handler: handlerType, Model: modelName, Stream: stream},
+		started: time.Now(),
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		r.ginCtx = ginCtx
+		r.base.APIKey = ginCtx.GetString("apiKey")
+		coreusage.TrackRequestRecords(ginCtx)
+		r.recordsFrom = len(coreusage.RequestRecords(ginCtx))
+	}
+	event := r.base
+	event.Type = events.TypeStarted
+	bus.Publish(event)
+	return r
+}
+
+// finish publishes "completed" with latency and token usage, or "error" with the failure.
+func (r *liveRequest) finish(errMsg *interfaces.ErrorMessage) {
+	if r == nil {
+		return
+	}
+	event := r.base
+	event.LatencyMs = time.Since(r.started).Milliseconds()
+	if errMsg != nil {
+		event.Type = events.TypeError
+		event.Status = errMsg.StatusCode
+		if errMsg.Error != nil {
+			event.Error = errMsg.Error.Error()
+		}
+		r.bus.Publish(event)
+		return
+	}
+	event.Type = events.TypeCompleted
+	event.Status = http.StatusOK
+	if r.ginCtx != nil {
+		tokens := &events.Tokens{}
+		records := coreusage.RequestRecords(r.ginCtx)
+		for _, record := range records[min(r.recordsFrom, len(records)):] {
+			event.Provider = record.Provider
+			event.AuthIndex = record.AuthIndex
+			tokens.Input += record.Detail.InputTokens
+			tokens.Output += record.Detail.OutputTokens
+			tokens.Reasoning += record.Detail.ReasoningTokens
+			tokens.Cached += record.Detail.CachedTokens
+			tokens.Total += record.Detail.TotalTokens
+		}
+		event.Tokens = tokens
+	}
+	r.bus.Publish(event)
+}
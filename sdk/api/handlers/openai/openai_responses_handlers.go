@@ -9,13 +9,16 @@ package openai
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responses"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 	"github.com/tidwall/gjson"
 )
@@ -81,14 +84,120 @@ func (h *OpenAIResponsesAPIHandler) Responses(c *gin.Context) {
 		return
 	}
 
+	// Replace previous_response_id with the stored conversation so every backend sees full history.
+	// Only responses created with the caller's API key can be continued.
+	rawJSON, own, err := responses.Expand(responses.ActiveStore(), rawJSON, c.GetString("apiKey"))
+	if err != nil {
+		var notFound *responses.NotFoundError
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: notFound.Error(),
+					Type:    "invalid_request_error",
+					Code:    "previous_response_not_found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	if !responses.ShouldStore(rawJSON) {
+		own = nil
+	}
+
 	// Check if the client requested a streaming response.
 	streamResult := gjson.GetBytes(rawJSON, "stream")
 	if streamResult.Type == gjson.True {
-		h.handleStreamingResponse(c, rawJSON)
+		h.handleStreamingResponse(c, rawJSON, own)
 	} else {
-		h.handleNonStreamingResponse(c, rawJSON)
+		h.handleNonStreamingResponse(c, rawJSON, own)
+	}
+
+}
+
+// GetResponse handles GET /v1/responses/{id}, returning a stored response.
+// Responses created with a different API key are reported as not found.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) GetResponse(c *gin.Context) {
+	record, ok := responses.Lookup(responses.ActiveStore(), c.Param("id"), c.GetString("apiKey"))
+	if !ok {
+		writeResponseNotFound(c, c.Param("id"))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", record.Response)
+}
+
+// DeleteResponse handles DELETE /v1/responses/{id}.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) DeleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	store := responses.ActiveStore()
+	if _, ok := responses.Lookup(store, id, c.GetString("apiKey")); !ok || !store.Delete(id) {
+		writeResponseNotFound(c, id)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "response.deleted", "deleted": true})
+}
+
+// ListInputItems handles GET /v1/responses/{id}/input_items.
+// It supports the order ("asc" or "desc", default "desc"), after and limit query parameters.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) ListInputItems(c *gin.Context) {
+	record, ok := responses.Lookup(responses.ActiveStore(), c.Param("id"), c.GetString("apiKey"))
+	if !ok {
+		writeResponseNotFound(c, c.Param("id"))
+		return
 	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	c.Data(http.StatusOK, "application/json", responses.InputItemsList(record, c.Query("order"), c.Query("after"), limit))
+}
 
+func writeResponseNotFound(c *gin.Context, id string) {
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: fmt.Sprintf("Response with id '%s' not found.", id),
+			Type:    "invalid_request_error",
+		},
+	})
+}
+
+// storeResponse records a completed response for apiKey when the request asked for it to be stored.
+func storeResponse(apiKey string, rawJSON, own, response []byte) {
+	if own == nil {
+		return
+	}
+	if record, ok := responses.NewRecord(rawJSON, own, response, apiKey); ok {
+		responses.ActiveStore().Put(record)
+	}
+}
+
+// storeStreamedResponse stores the response carried by a response.completed stream event.
+func storeStreamedResponse(apiKey string, rawJSON, own, chunk []byte) {
+	if own == nil || !bytes.Contains(chunk, []byte("response.completed")) {
+		return
+	}
+	for _, line := range bytes.Split(chunk, []byte("\n")) {
+		payload := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+		if gjson.GetBytes(payload, "type").String() != "response.completed" {
+			continue
+		}
+		if response := gjson.GetBytes(payload, "response"); response.IsObject() {
+			storeResponse(apiKey, rawJSON, own, []byte(response.Raw))
+		}
+		return
+	}
 }
 
 // handleNonStreamingResponse handles non-streaming chat completion responses
@@ -98,7 +207,8 @@ func (h *OpenAIResponsesAPIHandler) Responses(c *gin.Context) {
 // Parameters:
 //   - c: The Gin context containing the HTTP request and response
 //   - rawJSON: The raw JSON bytes of the OpenAIResponses-compatible request
-func (h *OpenAIResponsesAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSON []byte) {
+//   - own: The request's own input items to store with the response, or nil when not storing
+func (h *OpenAIResponsesAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSON, own []byte) {
 	c.Header("Content-Type", "application/json")
 
 	modelName := gjson.GetBytes(rawJSON, "model").String()
@@ -112,6 +222,7 @@ func (h *OpenAIResponsesAPIHandler) handleNonStreamingResponse(c *gin.Context, r
 		h.WriteErrorResponse(c, errMsg)
 		return
 	}
+	storeResponse(c.GetString("apiKey"), rawJSON, own, resp)
 	_, _ = c.Writer.Write(resp)
 	return
 
@@ -126,7 +237,8 @@ func (h *OpenAIResponsesAPIHandler) handleNonStreamingResponse(c *gin.Context, r
 // Parameters:
 //   - c: The Gin context containing the HTTP request and response
 //   - rawJSON: The raw JSON bytes of the OpenAIResponses-compatible request
-func (h *OpenAIResponsesAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON []byte) {
+//   - own: The request's own input items to store with the response, or nil when not storing
+func (h *OpenAIResponsesAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON, own []byte) {
 	// Get the http.Flusher interface to manually flush the response.
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
@@ -141,6 +253,7 @@ func (h *OpenAIResponsesAPIHandler) handleStreamingResponse(c *gin.Context, rawJ
 
 	// New core execution path
 	modelName := gjson.GetBytes(rawJSON, "model").String()
+	apiKey := c.GetString("apiKey")
 	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
 	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, "")
 
@@ -185,6 +298,7 @@ func (h *OpenAIResponsesAPIHandler) handleStreamingResponse(c *gin.Context, rawJ
 			setSSEHeaders()
 
 			// Write first chunk logic (matching forwardResponsesStream)
+			storeStreamedResponse(apiKey, rawJSON, own, chunk)
 			if bytes.HasPrefix(chunk, []byte("event:")) {
 				_, _ = c.Writer.Write([]byte("\n"))
 			}
@@ -193,15 +307,20 @@ func (h *OpenAIResponsesAPIHandler) handleStreamingResponse(c *gin.Context, rawJ
 			flusher.Flush()
 
 			// Continue
-			h.forwardResponsesStream(c, flusher, func(err error) { cliCancel(err) }, dataChan, errChan)
+			h.forwardResponsesStream(c, flusher, func(err error) { cliCancel(err) }, dataChan, errChan, func(chunk []byte) {
+				storeStreamedResponse(apiKey, rawJSON, own, chunk)
+			})
 			return
 		}
 	}
 }
 
-func (h *OpenAIResponsesAPIHandler) forwardResponsesStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
+func (h *OpenAIResponsesAPIHandler) forwardResponsesStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage, observe func([]byte)) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
 		WriteChunk: func(chunk []byte) {
+			if observe != nil {
+				observe(chunk)
+			}
 			if bytes.HasPrefix(chunk, []byte("event:")) {
 				_, _ = c.Writer.Write([]byte("\n"))
 			}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+const (
+	// ResponseCacheHeader lets a client skip ("bypass") or force ("force") the response cache.
+	ResponseCacheHeader = "X-CLIProxy-Cache"
+	// ResponseCacheStatusHeader reports whether the response cache served the request ("hit" or "miss").
+	ResponseCacheStatusHeader = "X-CLIProxy-Cache-Status"
+)
+
+// responseCacheLookup consults the response cache for a request. It returns the cache key, or ""
+// when the request must not use the cache, and the cached response on a hit. A hit is recorded
+// as a usage record; on a miss the returned context marks upstream usage records as misses.
+func responseCacheLookup(ctx context.Context, handlerType, modelName, normalizedModel string, stream bool, alt string, rawJSON []byte) (context.Context, string, *cache.CachedResponse) {
+	responseCache := cache.DefaultResponseCache()
+	if !responseCache.Enabled() {
+		return ctx, "", nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	directive, apiKey := "", ""
+	if ginCtx != nil {
+		directive = strings.ToLower(strings.TrimSpace(ginCtx.GetHeader(ResponseCacheHeader)))
+		apiKey = ginCtx.GetString("apiKey")
+	}
+	if directive == "bypass" || directive == "no-cache" || !responseCache.Cacheable(rawJSON, directive == "force") {
+		responseCache.Bypass()
+		return ctx, "", nil
+	}
+	// Key on the request as translated to the OpenAI chat schema every source format converts to,
+	// so client-format noise (field order, aliases the translators fold) does not split entries.
+	translated := sdktranslator.TranslateRequest(sdktranslator.FromString(handlerType), sdktranslator.FormatOpenAI, normalizedModel, cloneBytes(rawJSON), stream)
+	key := cache.ResponseCacheKey(util.HashAPIKey(apiKey), handlerType, modelName, stream, alt, translated)
+	entry, hit := responseCache.Get(key)
+	status := coreusage.CacheMiss
+	if hit {
+		status = coreusage.CacheHit
+	}
+	if ginCtx != nil {
+		ginCtx.Header(ResponseCacheStatusHeader, status)
+	}
+	if !hit {
+		return coreusage.WithCacheStatus(ctx, status), key, nil
+	}
+	record := coreusage.Record{
+		Provider:    "response-cache",
+		Model:       normalizedModel,
+		Source:      "response-cache",
+		RequestedAt: time.Now(),
+		Cache:       status,
+	}
+	record.APIKey = apiKey
+	coreusage.PublishRecord(ctx, record)
+	return ctx, key, entry
+}
+
+// replayCachedStream streams a cached response's chunks to the client. Chunks are cached after
+// translation into the client's format, so they are replayed as-is.
+func replayCachedStream(ctx context.Context, entry *cache.CachedResponse) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	dataChan := make(chan []byte)
+	errChan := make(chan *interfaces.ErrorMessage)
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+		for _, chunk := range entry.Chunks {
+			select {
+			case <-ctx.Done():
+				return
+			case dataChan <- cloneBytes(chunk):
+			}
+		}
+	}()
+	return dataChan, errChan
+}
+
+// streamTerminalMarkers identify the final event of a stream in each client format: OpenAI chat
+// finish_reason or [DONE], Claude message_stop, Responses response.completed and Gemini finishReason.
+var streamTerminalMarkers = [][]byte{
+	[]byte("[DONE]"),
+	[]byte(`"finish_reason":"`),
+	[]byte("message_stop"),
+	[]byte("response.completed"),
+	[]byte(`"finishReason":"`),
+}
+
+// isTerminalStreamChunk reports whether a translated chunk ends the stream, so only complete
+// streams are cached.
+func isTerminalStreamChunk(chunk []byte) bool {
+	for _, marker := range streamTerminalMarkers {
+		if bytes.Contains(chunk, marker) {
+			return true
+		}
+	}
+	return false
+}
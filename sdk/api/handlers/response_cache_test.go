@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// scriptedStreamExecutor streams the same chunks on every call.
+type scriptedStreamExecutor struct {
+	mu     sync.Mutex
+	calls  int
+	chunks []string
+}
+
+func (e *scriptedStreamExecutor) Identifier() string { return "codex" }
+
+func (e *scriptedStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (e *scriptedStreamExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+	ch := make(chan coreexecutor.StreamChunk, len(e.chunks))
+	for _, chunk := range e.chunks {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(chunk)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (e *scriptedStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *scriptedStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *scriptedStreamExecutor) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func TestExecuteStreamWithAuthManager_ReplaysCachedStream(t *testing.T) {
+	cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{Enabled: true})
+	t.Cleanup(func() { cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{}) })
+
+	executor := &scriptedStreamExecutor{chunks: []string{"ok", `{"choices":[{"finish_reason":"stop"}]}`}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	for _, id := range []string{"cache-auth1", "cache-auth2"} {
+		auth := &coreauth.Auth{ID: id, Provider: "codex", Status: coreauth.StatusActive}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("manager.Register(%s): %v", id, err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(id, auth.Provider, []*registry.ModelInfo{{ID: "cache-model"}})
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	}
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	run := func(payload string) string {
+		dataChan, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), "openai", "cache-model", []byte(payload), "")
+		var got []byte
+		for chunk := range dataChan {
+			got = append(got, chunk...)
+		}
+		for msg := range errChan {
+			if msg != nil {
+				t.Fatalf("unexpected error: %+v", msg)
+			}
+		}
+		return string(got)
+	}
+
+	want := `ok{"choices":[{"finish_reason":"stop"}]}`
+	if got := run(`{"model":"cache-model","temperature":0}`); got != want {
+		t.Fatalf("first response = %q", got)
+	}
+	if got := run(`{"temperature":0,"model":"cache-model"}`); got != want {
+		t.Fatalf("cached response = %q", got)
+	}
+	if executor.Calls() != 1 {
+		t.Fatalf("expected the repeat request to be served from cache, executor calls = %d", executor.Calls())
+	}
+	if stats := cache.DefaultResponseCache().Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	run(`{"model":"cache-model","temperature":1}`)
+	if executor.Calls() != 2 {
+		t.Fatalf("non-deterministic request was not sent upstream, executor calls = %d", executor.Calls())
+	}
+}
+
+func TestExecuteStreamWithAuthManager_SkipsCachingTruncatedStream(t *testing.T) {
+	cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{Enabled: true})
+	t.Cleanup(func() { cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{}) })
+
+	executor := &scriptedStreamExecutor{chunks: []string{"partial"}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "truncated-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "truncated-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	for i := 0; i < 2; i++ {
+		dataChan, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), "openai", "truncated-model", []byte(`{"model":"truncated-model","temperature":0}`), "")
+		for range dataChan {
+		}
+		for range errChan {
+		}
+	}
+	if executor.Calls() != 2 {
+		t.Fatalf("a stream without a terminal event was cached, executor calls = %d", executor.Calls())
+	}
+	if stats := cache.DefaultResponseCache().Stats(); stats.Entries != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestExecuteStreamWithAuthManager_ScopesCacheByAPIKey(t *testing.T) {
+	cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{Enabled: true})
+	t.Cleanup(func() { cache.DefaultResponseCache().SetOptions(cache.ResponseCacheOptions{}) })
+
+	executor := &scriptedStreamExecutor{chunks: []string{"ok", `{"choices":[{"finish_reason":"stop"}]}`}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "tenant-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "tenant-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	gin.SetMode(gin.TestMode)
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	run := func(apiKey string) string {
+		recorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(recorder)
+		ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		ginCtx.Set("apiKey", apiKey)
+		ctx := context.WithValue(context.Background(), "gin", ginCtx)
+		dataChan, errChan := handler.ExecuteStreamWithAuthManager(ctx, "openai", "tenant-model", []byte(`{"model":"tenant-model","temperature":0}`), "")
+		for range dataChan {
+		}
+		for range errChan {
+		}
+		return recorder.Header().Get(ResponseCacheStatusHeader)
+	}
+
+	if status := run("sk-tenant-one"); status != "miss" {
+		t.Fatalf("first tenant status = %q, want miss", status)
+	}
+	if status := run("sk-tenant-two"); status != "miss" {
+		t.Fatalf("second tenant status = %q, want miss for a prompt it never sent", status)
+	}
+	if status := run("sk-tenant-one"); status != "hit" {
+		t.Fatalf("repeat status = %q, want hit", status)
+	}
+	if executor.Calls() != 2 {
+		t.Fatalf("executor calls = %d, want one per tenant", executor.Calls())
+	}
+}
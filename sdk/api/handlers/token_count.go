@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// TokenCountSourceHeader reports where a count-tokens response came from: "upstream", "cache"
+// or "estimate".
+const TokenCountSourceHeader = "X-CLIProxy-Token-Count-Source"
+
+func setTokenCountSource(ctx context.Context, source string) {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header(TokenCountSourceHeader, source)
+	}
+}
+
+// estimateTokenCount answers a count-tokens request locally when no credential could count it
+// upstream. It returns nil when the failure is not an availability problem (so request errors such
+// as 400 or 413 reach the client), local estimates are disabled, the source format is not
+// supported or the client went away.
+func (h *BaseAPIHandler) estimateTokenCount(ctx context.Context, handlerType string, rawJSON []byte, cause error) []byte {
+	if ctx.Err() != nil || !countUnavailable(cause) || !tokencount.Supported(handlerType) {
+		return nil
+	}
+	if h.Cfg != nil && h.Cfg.CountTokens.DisableLocalEstimate {
+		return nil
+	}
+	count, err := tokencount.Estimate(handlerType, rawJSON)
+	if err != nil {
+		log.Debugf("count tokens: local estimate failed: %v", err)
+		return nil
+	}
+	log.Debugf("count tokens: upstream count failed (%v), returning local estimate of %d tokens", cause, count)
+	setTokenCountSource(ctx, "estimate")
+	return tokencount.Response(handlerType, count)
+}
+
+// countUnavailable reports whether a count-tokens failure means no upstream could serve the
+// call: no usable credential, cooldown or rate limiting (429), an upstream or transport failure
+// (5xx, which the handler also reports for errors without a status) or an executor that does not
+// implement counting (501).
+func countUnavailable(err error) bool {
+	var authErr *coreauth.Error
+	isAuthErr := errors.As(err, &authErr)
+	if isAuthErr {
+		switch authErr.Code {
+		case "auth_not_found", "auth_unavailable", "not_implemented":
+			return true
+		}
+	}
+	switch status := statusFromError(err); {
+	case status == 0:
+		return !isAuthErr
+	case status == http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
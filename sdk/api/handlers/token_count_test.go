@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestExecuteCountWithAuthManager_FallsBackToLocalEstimate(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&failOnceStreamExecutor{})
+	auth := &coreauth.Auth{ID: "count-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "count-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	payload := []byte(`{"model":"count-model","system":"Be brief.","messages":[{"role":"user","content":"How many tokens is this?"}]}`)
+
+	cfg := &sdkconfig.SDKConfig{}
+	handler := NewBaseAPIHandlers(cfg, manager)
+	resp, errMsg := handler.ExecuteCountWithAuthManager(context.Background(), "claude", "count-model", payload, "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if count := gjson.GetBytes(resp, "input_tokens").Int(); count <= 0 {
+		t.Fatalf("estimate = %s", resp)
+	}
+
+	cfg.CountTokens.DisableLocalEstimate = true
+	if _, errMsg = handler.ExecuteCountWithAuthManager(context.Background(), "claude", "count-model", payload, ""); errMsg == nil {
+		t.Fatal("expected the upstream error once local estimates are disabled")
+	}
+}
+
+func TestCountUnavailable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no auth", &coreauth.Error{Code: "auth_not_found"}, true},
+		{"not implemented", &coreauth.Error{Code: "not_implemented"}, true},
+		{"rate limited", &coreauth.Error{HTTPStatus: http.StatusTooManyRequests}, true},
+		{"upstream failure", &coreauth.Error{HTTPStatus: http.StatusBadGateway}, true},
+		{"transport failure", errors.New("connection reset"), true},
+		{"bad request", &coreauth.Error{HTTPStatus: http.StatusBadRequest}, false},
+		{"too large", &coreauth.Error{HTTPStatus: http.StatusRequestEntityTooLarge}, false},
+		{"request error without status", &coreauth.Error{Code: "invalid_request"}, false},
+	}
+	for _, tc := range cases {
+		if got := countUnavailable(tc.err); got != tc.want {
+			t.Errorf("%s: countUnavailable() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -14,9 +14,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ProviderExecutor defines the contract required by Manager to execute provider calls.
@@ -104,6 +106,8 @@ type Manager struct {
 	hook      Hook
 	mu        sync.RWMutex
 	auths     map[string]*Auth
+	// refreshing holds the IDs of auths whose tokens are being refreshed right now.
+	refreshing map[string]struct{}
 	// providerOffsets tracks per-model provider rotation state for multi-provider routing.
 	providerOffsets map[string]int
 
@@ -114,9 +118,24 @@ type Manager struct {
 	// modelNameMappings stores global model name alias mappings (alias -> upstream name) keyed by channel.
 	modelNameMappings atomic.Value
 
+	// upstreamTimeouts stores UpstreamTimeoutConfig for connect and stream stall detection.
+	upstreamTimeouts atomic.Value
+
+	// modelFallbacks stores fallback chains (map[string][]ModelFallback) keyed by lowercased model.
+	modelFallbacks atomic.Value
+
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
+	// execHooks run around every executor invocation.
+	execHooks []ExecutionHook
+
+	// affinity pins conversations to credentials when session affinity is enabled.
+	affinity *sessionAffinity
+
+	// health probes credentials in the background when health checks are enabled.
+	health *healthChecker
+
 	// Auto refresh state
 	refreshCancel context.CancelFunc
 }
@@ -136,6 +155,9 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		hook:            hook,
 		auths:           make(map[string]*Auth),
 		providerOffsets: make(map[string]int),
+		refreshing:      make(map[string]struct{}),
+		affinity:        newSessionAffinity(),
+		health:          newHealthChecker(),
 	}
 }
 
@@ -236,6 +258,55 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	return auth.Clone(), nil
 }
 
+// ResetCooldown clears the cooldown and quota state recorded for the given models of an auth, or
+// for every model when none are given, so the auth can be selected again immediately.
+func (m *Manager) ResetCooldown(ctx context.Context, id string, models ...string) (*Auth, error) {
+	m.mu.Lock()
+	auth, ok := m.auths[id]
+	if !ok || auth == nil {
+		m.mu.Unlock()
+		return nil, &Error{Code: "auth_not_found", Message: "auth not found"}
+	}
+	now := time.Now()
+	var reset []string
+	if len(models) == 0 {
+		for model := range auth.ModelStates {
+			reset = append(reset, model)
+		}
+		auth.ModelStates = nil
+	} else {
+		for _, model := range models {
+			if _, exists := auth.ModelStates[model]; exists {
+				delete(auth.ModelStates, model)
+				reset = append(reset, model)
+			}
+		}
+	}
+	if len(auth.ModelStates) == 0 {
+		auth.Unavailable = false
+		auth.NextRetryAfter = time.Time{}
+		auth.Quota = QuotaState{}
+		auth.LastError = nil
+		if !auth.Disabled {
+			auth.Status = StatusActive
+			auth.StatusMessage = ""
+		}
+	} else {
+		updateAggregatedAvailability(auth, now)
+	}
+	auth.UpdatedAt = now
+	_ = m.persist(ctx, auth)
+	snapshot := auth.Clone()
+	m.mu.Unlock()
+
+	for _, model := range reset {
+		registry.GetGlobalRegistry().ClearModelQuotaExceeded(id, model)
+		registry.GetGlobalRegistry().ResumeClientModel(id, model)
+	}
+	m.hook.OnAuthUpdated(ctx, snapshot.Clone())
+	return snapshot, nil
+}
+
 // Load resets manager state from the backing store.
 func (m *Manager) Load(ctx context.Context) error {
 	m.mu.Lock()
@@ -260,8 +331,14 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model cannot be served, its configured fallback models are tried in order.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
-	normalized := m.normalizeProviders(providers)
+	return executeWithFallbacks(ctx, m, providers, req, opts, m.executeModel)
+}
+
+// executeModel runs Execute for a single model without fallbacks.
+func (m *Manager) executeModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	normalized := executionTargetFromContext(ctx).restrictProviders(m.normalizeProviders(providers))
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -286,6 +363,7 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
@@ -298,8 +376,14 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 
 // ExecuteCount performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model cannot be served, its configured fallback models are tried in order.
 func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
-	normalized := m.normalizeProviders(providers)
+	return executeWithFallbacks(ctx, m, providers, req, opts, m.executeCountModel)
+}
+
+// executeCountModel runs ExecuteCount for a single model without fallbacks.
+func (m *Manager) executeCountModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	normalized := executionTargetFromContext(ctx).restrictProviders(m.normalizeProviders(providers))
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -324,6 +408,7 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
@@ -336,8 +421,14 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model cannot be served, its configured fallback models are tried in order.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
-	normalized := m.normalizeProviders(providers)
+	return executeWithFallbacks(ctx, m, providers, req, opts, m.executeStreamModel)
+}
+
+// executeStreamModel runs ExecuteStream for a single model without fallbacks.
+func (m *Manager) executeStreamModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	normalized := executionTargetFromContext(ctx).restrictProviders(m.normalizeProviders(providers))
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -362,6 +453,7 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return nil, errWait
 		}
@@ -380,7 +472,7 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
+		auth, executor, release, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -411,10 +503,14 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
+		execCtx = m.withNonStreamTimeouts(execCtx, provider)
 		execReq := req
 		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
 		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
-		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+		execCtx, execState, hooks := m.beforeExecute(execCtx, provider, auth, execReq, opts)
+		resp, errExec := executor.Execute(execCtx, execState.Auth, execState.Request, execState.Options)
+		release()
+		afterExecute(execCtx, hooks, execState, resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -442,7 +538,7 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
+		auth, executor, release, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -473,10 +569,14 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
+		execCtx = m.withNonStreamTimeouts(execCtx, provider)
 		execReq := req
 		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
 		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
-		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
+		execCtx, execState, hooks := m.beforeExecute(execCtx, provider, auth, execReq, opts)
+		resp, errExec := executor.CountTokens(execCtx, execState.Auth, execState.Request, execState.Options)
+		release()
+		afterExecute(execCtx, hooks, execState, resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -504,7 +604,7 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
+		auth, executor, release, errPick := m.pickNext(ctx, provider, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return nil, lastErr
@@ -535,11 +635,18 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
+		execCtx = m.withConnectTimeout(execCtx, provider)
 		execReq := req
 		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
 		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
+		execCtx, execState, hooks := m.beforeExecute(execCtx, provider, auth, execReq, opts)
+		timeouts := m.timeoutsFor(provider)
+		execCtx, cancelAttempt := context.WithCancel(execCtx)
+		chunks, errStream := executor.ExecuteStream(execCtx, execState.Auth, execState.Request, execState.Options)
 		if errStream != nil {
+			cancelAttempt()
+			release()
+			afterExecute(execCtx, hooks, execState, cliproxyexecutor.Response{}, errStream)
 			rerr := &Error{Message: errStream.Error()}
 			var se cliproxyexecutor.StatusError
 			if errors.As(errStream, &se) && se != nil {
@@ -551,13 +658,45 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			lastErr = errStream
 			continue
 		}
+		// Wait for the first chunk when a first-byte timeout is set so a silent upstream can be
+		// abandoned in favour of the next credential before anything reaches the client.
+		var first cliproxyexecutor.StreamChunk
+		hasFirst, closed := false, false
+		if timeouts.FirstByte > 0 {
+			chunk, ok, stalled := nextStreamChunk(execCtx, chunks, timeouts.FirstByte)
+			if stalled && execCtx.Err() != nil {
+				// The client went away and the executor did not close its channel in time.
+				cancelAttempt()
+				go drainStream(chunks)
+				release()
+				afterExecute(execCtx, hooks, execState, cliproxyexecutor.Response{}, execCtx.Err())
+				return nil, execCtx.Err()
+			}
+			if stalled {
+				cancelAttempt()
+				go drainStream(chunks)
+				release()
+				errStall := &StallError{Provider: provider, Phase: "first_byte", Timeout: timeouts.FirstByte}
+				entry.Warnf("%s, rotating credential", errStall.Error())
+				afterExecute(execCtx, hooks, execState, cliproxyexecutor.Response{}, errStall)
+				m.MarkResult(execCtx, Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: &Error{Code: "upstream_timeout", Message: errStall.Error(), HTTPStatus: errStall.StatusCode()}})
+				lastErr = errStall
+				continue
+			}
+			first, hasFirst, closed = chunk, ok, !ok
+		}
 		out := make(chan cliproxyexecutor.StreamChunk)
 		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
+			defer release()
+			defer cancelAttempt()
 			var failed bool
-			for chunk := range streamChunks {
+			var streamErr error
+			forward := func(chunk cliproxyexecutor.StreamChunk) {
+				onStreamChunk(streamCtx, hooks, execState, chunk)
 				if chunk.Err != nil && !failed {
 					failed = true
+					streamErr = chunk.Err
 					rerr := &Error{Message: chunk.Err.Error()}
 					var se cliproxyexecutor.StatusError
 					if errors.As(chunk.Err, &se) && se != nil {
@@ -567,9 +706,35 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 				}
 				out <- chunk
 			}
+			if hasFirst {
+				forward(first)
+			}
+			for !closed {
+				chunk, ok, stalled := nextStreamChunk(streamCtx, streamChunks, timeouts.Idle)
+				if stalled && streamCtx.Err() != nil {
+					cancelAttempt()
+					go drainStream(streamChunks)
+					break
+				}
+				if stalled {
+					// Stop the upstream call and surface the stall as a terminal error chunk so
+					// handlers emit a format-specific error event instead of hanging.
+					cancelAttempt()
+					go drainStream(streamChunks)
+					errStall := &StallError{Provider: streamProvider, Phase: "idle", Timeout: timeouts.Idle}
+					logEntryWithRequestID(streamCtx).Warn(errStall.Error())
+					forward(cliproxyexecutor.StreamChunk{Err: errStall})
+					break
+				}
+				if !ok {
+					break
+				}
+				forward(chunk)
+			}
 			if !failed {
 				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true})
 			}
+			afterExecute(streamCtx, hooks, execState, cliproxyexecutor.Response{}, streamErr)
 		}(execCtx, auth.Clone(), provider, chunks)
 		return out, nil
 	}
@@ -905,6 +1070,13 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
 
+	m.mu.RLock()
+	selector := m.selector
+	m.mu.RUnlock()
+	if observer, ok := selector.(ResultAwareSelector); ok {
+		observer.ObserveResult(result)
+	}
+
 	m.hook.OnResult(ctx, result)
 }
 
@@ -1164,6 +1336,22 @@ func (m *Manager) List() []*Auth {
 	return list
 }
 
+// HasModelPrefix reports whether an enabled auth exposes its models under prefix.
+func (m *Manager) HasModelPrefix(prefix string) bool {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, auth := range m.auths {
+		if !auth.Disabled && strings.TrimSpace(auth.Prefix) == prefix {
+			return true
+		}
+	}
+	return false
+}
+
 // GetByID retrieves an auth entry by its ID.
 
 func (m *Manager) GetByID(id string) (*Auth, bool) {
@@ -1179,15 +1367,32 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
-func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+// pickNext selects the next auth to try. The returned release function must be called once
+// the upstream call for that auth has finished.
+func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, func(), error) {
+	_, span := tracing.Start(ctx, "Manager.pickNext",
+		attribute.String("cliproxy.provider", provider),
+		attribute.String("cliproxy.model", model),
+		attribute.Int("cliproxy.attempt", len(tried)+1),
+	)
+	auth, executor, release, err := m.selectNext(ctx, provider, model, opts, tried)
+	if auth != nil {
+		span.SetAttributes(attribute.String("cliproxy.auth_index", auth.EnsureIndex()))
+	}
+	tracing.End(span, err)
+	return auth, executor, release, err
+}
+
+func (m *Manager) selectNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, func(), error) {
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
 	if !okExecutor {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
+		return nil, nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
 	candidates := make([]*Auth, 0, len(m.auths))
 	modelKey := strings.TrimSpace(model)
+	target := executionTargetFromContext(ctx)
 	registryRef := registry.GetGlobalRegistry()
 	for _, candidate := range m.auths {
 		if candidate.Provider != provider || candidate.Disabled {
@@ -1196,6 +1401,9 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if target.AuthID != "" && candidate.ID != target.AuthID {
+			continue
+		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
@@ -1203,16 +1411,29 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
-	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
-	if errPick != nil {
-		m.mu.RUnlock()
-		return nil, nil, errPick
-	}
-	if selected == nil {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		return nil, nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
+	}
+	selector := m.selector
+	affinityKey := m.affinity.sessionKey(ctx, provider, model, opts)
+	var selected *Auth
+	if affinityKey != "" {
+		selected = m.affinity.lookup(affinityKey, model, candidates, time.Now())
+	}
+	pinned := selected != nil
+	if !pinned {
+		var errPick error
+		selected, errPick = selector.Pick(ctx, provider, model, opts, candidates)
+		if errPick != nil {
+			m.mu.RUnlock()
+			return nil, nil, nil, errPick
+		}
+		if selected == nil {
+			m.mu.RUnlock()
+			return nil, nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		}
+		if affinityKey != "" {
+			m.affinity.pin(affinityKey, selected.ID, time.Now())
+		}
 	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
@@ -1224,7 +1445,17 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		}
 		m.mu.Unlock()
 	}
-	return authCopy, executor, nil
+	release := func() {}
+	if tracker, ok := selector.(InFlightSelector); ok {
+		authID := authCopy.ID
+		if pinned {
+			// Pinned picks bypass Pick, so reserve the slot here to keep the load visible.
+			tracker.Acquire(authID, model)
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { tracker.Release(authID, model) }) }
+	}
+	return authCopy, executor, release, nil
 }
 
 func (m *Manager) persist(ctx context.Context, auth *Auth) error {
@@ -1297,7 +1528,7 @@ func (m *Manager) checkRefreshes(ctx context.Context) {
 			if exec := m.executorFor(a.Provider); exec == nil {
 				continue
 			}
-			if !m.markRefreshPending(a.ID, now) {
+			if m.markRefreshPending(a.ID, now) != refreshSlotTaken {
 				continue
 			}
 			go m.refreshAuth(ctx, a.ID)
@@ -1520,25 +1751,50 @@ func lookupMetadataTime(meta map[string]any, keys ...string) (time.Time, bool) {
 	return time.Time{}, false
 }
 
-func (m *Manager) markRefreshPending(id string, now time.Time) bool {
+// refreshSlot is the outcome of markRefreshPending.
+type refreshSlot int
+
+const (
+	// refreshSlotTaken means the caller now owns the refresh and must call refreshAuth.
+	refreshSlotTaken refreshSlot = iota
+	// refreshSlotInFlight means another refresh of the auth is running.
+	refreshSlotInFlight
+	// refreshSlotBackoff means the last refresh failed and the auth waits before retrying.
+	refreshSlotBackoff
+	// refreshSlotUnavailable means the auth is unknown or disabled.
+	refreshSlotUnavailable
+)
+
+func (m *Manager) markRefreshPending(id string, now time.Time) refreshSlot {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	auth, ok := m.auths[id]
 	if !ok || auth == nil || auth.Disabled {
-		return false
+		return refreshSlotUnavailable
+	}
+	if _, running := m.refreshing[id]; running {
+		return refreshSlotInFlight
 	}
 	if !auth.NextRefreshAfter.IsZero() && now.Before(auth.NextRefreshAfter) {
-		return false
+		return refreshSlotBackoff
 	}
 	auth.NextRefreshAfter = now.Add(refreshPendingBackoff)
 	m.auths[id] = auth
-	return true
+	m.refreshing[id] = struct{}{}
+	return refreshSlotTaken
 }
 
-func (m *Manager) refreshAuth(ctx context.Context, id string) {
+// refreshAuth refreshes the tokens of an auth whose refresh-pending slot the caller holds and
+// stores the result. It returns the refresh error, if any.
+func (m *Manager) refreshAuth(ctx context.Context, id string) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	defer func() {
+		m.mu.Lock()
+		delete(m.refreshing, id)
+		m.mu.Unlock()
+	}()
 	m.mu.RLock()
 	auth := m.auths[id]
 	var exec ProviderExecutor
@@ -1547,13 +1803,19 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	}
 	m.mu.RUnlock()
 	if auth == nil || exec == nil {
-		return
+		return nil
 	}
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
 	if err != nil && errors.Is(err, context.Canceled) {
 		log.Debugf("refresh canceled for %s, %s", auth.Provider, auth.ID)
-		return
+		// Release the slot: nothing failed, so the next attempt must not wait out a backoff.
+		m.mu.Lock()
+		if current := m.auths[id]; current != nil {
+			current.NextRefreshAfter = time.Time{}
+		}
+		m.mu.Unlock()
+		return err
 	}
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
@@ -1565,7 +1827,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 			m.auths[id] = current
 		}
 		m.mu.Unlock()
-		return
+		return err
 	}
 	if updated == nil {
 		updated = cloned
@@ -1580,6 +1842,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	updated.LastError = nil
 	updated.UpdatedAt = now
 	_, _ = m.Update(ctx, updated)
+	return nil
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// httpClientContextKey is the context key executors consult for a per-request HTTP client override.
+const httpClientContextKey = "cliproxy.httpclient"
+
+// ExecutionState captures a single executor invocation performed by the manager.
+// Hooks may mutate Request, Options and HTTPClient inside BeforeExecute; the
+// updated values are used for the upstream call.
+type ExecutionState struct {
+	// Provider is the provider key handling the invocation.
+	Provider string
+	// Auth is a copy of the credential selected for this attempt.
+	Auth *Auth
+	// Request is the provider facing request payload.
+	Request cliproxyexecutor.Request
+	// Options carries execution flags (streaming, headers, etc.).
+	Options cliproxyexecutor.Options
+	// HTTPClient optionally overrides the outbound HTTP client used by the executor.
+	HTTPClient *http.Client
+}
+
+// ExecutionHook observes and customises every executor invocation, including
+// retries across credentials and providers.
+type ExecutionHook interface {
+	// BeforeExecute fires after an auth has been selected and before the executor runs.
+	BeforeExecute(ctx context.Context, state *ExecutionState)
+	// AfterExecute fires once the invocation completes. For streams it fires after the last chunk.
+	AfterExecute(ctx context.Context, state *ExecutionState, resp cliproxyexecutor.Response, err error)
+	// OnStreamChunk fires for every chunk produced by a streaming invocation.
+	OnStreamChunk(ctx context.Context, state *ExecutionState, chunk cliproxyexecutor.StreamChunk)
+}
+
+// RetryObserver may be implemented by an ExecutionHook to learn when the manager waits for a
+// credential cooldown before retrying a request.
+type RetryObserver interface {
+	// OnRetry fires before the manager sleeps for wait and starts the next attempt.
+	OnRetry(ctx context.Context, model string, attempt int, wait time.Duration)
+}
+
+// SetExecutionHooks replaces the hooks invoked around executor calls.
+func (m *Manager) SetExecutionHooks(hooks ...ExecutionHook) {
+	if m == nil {
+		return
+	}
+	filtered := make([]ExecutionHook, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook != nil {
+			filtered = append(filtered, hook)
+		}
+	}
+	m.mu.Lock()
+	m.execHooks = filtered
+	m.mu.Unlock()
+}
+
+// AddExecutionHook appends a hook invoked around executor calls.
+func (m *Manager) AddExecutionHook(hook ExecutionHook) {
+	if m == nil || hook == nil {
+		return
+	}
+	m.mu.Lock()
+	m.execHooks = append(append([]ExecutionHook(nil), m.execHooks...), hook)
+	m.mu.Unlock()
+}
+
+func (m *Manager) executionHooks() []ExecutionHook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.execHooks
+}
+
+// beforeExecute builds the execution state for an attempt and runs BeforeExecute hooks.
+// The returned context carries the HTTP client override when a hook provided one.
+func (m *Manager) beforeExecute(ctx context.Context, provider string, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (context.Context, *ExecutionState, []ExecutionHook) {
+	state := &ExecutionState{Provider: provider, Auth: auth, Request: req, Options: opts}
+	hooks := m.executionHooks()
+	if len(hooks) == 0 {
+		return ctx, state, nil
+	}
+	for _, hook := range hooks {
+		hook.BeforeExecute(ctx, state)
+	}
+	if state.Auth == nil {
+		state.Auth = auth
+	}
+	if state.HTTPClient != nil {
+		ctx = context.WithValue(ctx, httpClientContextKey, state.HTTPClient)
+	}
+	return ctx, state, hooks
+}
+
+func afterExecute(ctx context.Context, hooks []ExecutionHook, state *ExecutionState, resp cliproxyexecutor.Response, err error) {
+	for _, hook := range hooks {
+		hook.AfterExecute(ctx, state, resp, err)
+	}
+}
+
+func onStreamChunk(ctx context.Context, hooks []ExecutionHook, state *ExecutionState, chunk cliproxyexecutor.StreamChunk) {
+	for _, hook := range hooks {
+		hook.OnStreamChunk(ctx, state, chunk)
+	}
+}
+
+// notifyRetry informs hooks implementing RetryObserver about a scheduled retry.
+func (m *Manager) notifyRetry(ctx context.Context, model string, attempt int, wait time.Duration) {
+	for _, hook := range m.executionHooks() {
+		if observer, ok := hook.(RetryObserver); ok {
+			observer.OnRetry(ctx, model, attempt, wait)
+		}
+	}
+}
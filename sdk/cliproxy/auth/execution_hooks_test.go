@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type hookTestExecutor struct {
+	mu       sync.Mutex
+	payloads []string
+	clients  []*http.Client
+}
+
+func (e *hookTestExecutor) Identifier() string { return "hooktest" }
+
+func (e *hookTestExecutor) Execute(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.payloads = append(e.payloads, string(req.Payload))
+	client, _ := ctx.Value(httpClientContextKey).(*http.Client)
+	e.clients = append(e.clients, client)
+	e.mu.Unlock()
+	if auth.ID == "bad" {
+		return cliproxyexecutor.Response{}, &Error{Message: "unauthorized", HTTPStatus: http.StatusUnauthorized}
+	}
+	return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+}
+
+func (e *hookTestExecutor) ExecuteStream(_ context.Context, _ *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	ch := make(chan cliproxyexecutor.StreamChunk, 2)
+	ch <- cliproxyexecutor.StreamChunk{Payload: []byte("a")}
+	ch <- cliproxyexecutor.StreamChunk{Payload: []byte("b")}
+	close(ch)
+	return ch, nil
+}
+
+func (e *hookTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *hookTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+type recordingHook struct {
+	mu     sync.Mutex
+	before []string
+	after  []string
+	chunks int
+	client *http.Client
+}
+
+func (h *recordingHook) BeforeExecute(_ context.Context, state *ExecutionState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.before = append(h.before, state.Auth.ID)
+	state.Request.Payload = []byte("rewritten")
+	state.HTTPClient = h.client
+}
+
+func (h *recordingHook) AfterExecute(_ context.Context, state *ExecutionState, _ cliproxyexecutor.Response, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry := state.Auth.ID + ":ok"
+	if err != nil {
+		entry = state.Auth.ID + ":err"
+	}
+	h.after = append(h.after, entry)
+}
+
+func (h *recordingHook) OnStreamChunk(context.Context, *ExecutionState, cliproxyexecutor.StreamChunk) {
+	h.mu.Lock()
+	h.chunks++
+	h.mu.Unlock()
+}
+
+func newHookTestManager(t *testing.T, ids ...string) (*Manager, *hookTestExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	executor := &hookTestExecutor{}
+	manager.RegisterExecutor(executor)
+	for _, id := range ids {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "hooktest"}); err != nil {
+			t.Fatalf("Register(%s): %v", id, err)
+		}
+	}
+	return manager, executor
+}
+
+func TestExecutionHooks_InvokedForEveryAttempt(t *testing.T) {
+	manager, executor := newHookTestManager(t, "bad", "good")
+	hook := &recordingHook{client: &http.Client{}}
+	manager.SetExecutionHooks(hook)
+
+	resp, err := manager.Execute(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{Payload: []byte("original")}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "ok" {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, "ok")
+	}
+	if len(hook.before) != 2 || hook.before[0] != "bad" || hook.before[1] != "good" {
+		t.Fatalf("BeforeExecute auths = %v, want [bad good]", hook.before)
+	}
+	if len(hook.after) != 2 || hook.after[0] != "bad:err" || hook.after[1] != "good:ok" {
+		t.Fatalf("AfterExecute results = %v, want [bad:err good:ok]", hook.after)
+	}
+	for i, payload := range executor.payloads {
+		if payload != "rewritten" {
+			t.Fatalf("executor payload[%d] = %q, want %q", i, payload, "rewritten")
+		}
+		if executor.clients[i] != hook.client {
+			t.Fatalf("executor client[%d] was not the hook supplied client", i)
+		}
+	}
+}
+
+func TestExecutionHooks_ObservesStreamChunks(t *testing.T) {
+	manager, _ := newHookTestManager(t, "good")
+	hook := &recordingHook{}
+	manager.AddExecutionHook(hook)
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{Stream: true})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	for range chunks {
+	}
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if hook.chunks != 2 {
+		t.Fatalf("OnStreamChunk calls = %d, want 2", hook.chunks)
+	}
+	if len(hook.after) != 1 || hook.after[0] != "good:ok" {
+		t.Fatalf("AfterExecute results = %v, want [good:ok]", hook.after)
+	}
+}
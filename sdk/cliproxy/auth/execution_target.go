@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// executionTargetKey is the request-scoped store key (typically on a *gin.Context) holding the
+// ExecutionTarget of a request.
+const executionTargetKey = "__cliproxy_execution_target__"
+
+// ExecutionTarget pins a request to one provider and/or one credential. It is set by in-process
+// callers such as request replay and is never derived from client input.
+type ExecutionTarget struct {
+	// Provider restricts execution to this provider key.
+	Provider string
+	// AuthID restricts execution to this credential.
+	AuthID string
+}
+
+// SetExecutionTarget pins the request behind store (typically a *gin.Context) to target.
+func SetExecutionTarget(store interface{ Set(string, any) }, target ExecutionTarget) {
+	if store != nil {
+		store.Set(executionTargetKey, target)
+	}
+}
+
+func executionTargetFromContext(ctx context.Context) ExecutionTarget {
+	if ctx == nil {
+		return ExecutionTarget{}
+	}
+	store, ok := ctx.Value("gin").(interface{ Get(string) (any, bool) })
+	if !ok || store == nil {
+		return ExecutionTarget{}
+	}
+	value, _ := store.Get(executionTargetKey)
+	target, _ := value.(ExecutionTarget)
+	return target
+}
+
+// restrictProviders drops providers other than the pinned one.
+func (t ExecutionTarget) restrictProviders(providers []string) []string {
+	provider := strings.ToLower(strings.TrimSpace(t.Provider))
+	if provider == "" {
+		return providers
+	}
+	for _, candidate := range providers {
+		if candidate == provider {
+			return []string{candidate}
+		}
+	}
+	return nil
+}
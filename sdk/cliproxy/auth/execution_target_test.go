@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type targetTestStore map[string]any
+
+func (s targetTestStore) Get(key string) (any, bool) { v, ok := s[key]; return v, ok }
+func (s targetTestStore) Set(key string, value any)  { s[key] = value }
+
+func TestExecutionTarget_PinsCredential(t *testing.T) {
+	manager, _ := newHookTestManager(t, "first", "second")
+	hook := &recordingHook{}
+	manager.SetExecutionHooks(hook)
+
+	store := targetTestStore{}
+	SetExecutionTarget(store, ExecutionTarget{AuthID: "second"})
+	ctx := context.WithValue(context.Background(), "gin", store)
+
+	if _, err := manager.Execute(ctx, []string{"hooktest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(hook.before) != 1 || hook.before[0] != "second" {
+		t.Fatalf("executed auths = %v, want [second]", hook.before)
+	}
+}
+
+func TestExecutionTarget_UnknownProviderFails(t *testing.T) {
+	manager, _ := newHookTestManager(t, "first")
+
+	store := targetTestStore{}
+	SetExecutionTarget(store, ExecutionTarget{Provider: "other"})
+	ctx := context.WithValue(context.Background(), "gin", store)
+
+	if _, err := manager.Execute(ctx, []string{"hooktest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("expected an error when the pinned provider is not available")
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+// ServedModelHeader names the response header set when a fallback model served the request.
+const ServedModelHeader = "X-CLIProxy-Served-Model"
+
+// ModelFallback is one entry of a fallback chain.
+type ModelFallback struct {
+	// Model is the fallback model ID.
+	Model string
+	// Provider optionally restricts the fallback to a single provider.
+	Provider string
+}
+
+// FallbackFilter narrows the providers a fallback model may use, e.g. to apply a client key
+// policy. Returning no providers skips the fallback.
+type FallbackFilter func(model string, providers []string) []string
+
+type fallbackFilterKey struct{}
+
+// WithFallbackFilter attaches a FallbackFilter to ctx for the conductor to consult.
+func WithFallbackFilter(ctx context.Context, filter FallbackFilter) context.Context {
+	if filter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, fallbackFilterKey{}, filter)
+}
+
+// SetModelFallbacks replaces the fallback chains keyed by requested model.
+func (m *Manager) SetModelFallbacks(chains map[string][]ModelFallback) {
+	if m == nil {
+		return
+	}
+	normalized := make(map[string][]ModelFallback, len(chains))
+	for model, chain := range chains {
+		key := strings.ToLower(strings.TrimSpace(model))
+		if key == "" {
+			continue
+		}
+		targets := make([]ModelFallback, 0, len(chain))
+		for _, target := range chain {
+			target.Model = strings.TrimSpace(target.Model)
+			target.Provider = strings.ToLower(strings.TrimSpace(target.Provider))
+			if target.Model == "" || strings.EqualFold(target.Model, model) {
+				continue
+			}
+			targets = append(targets, target)
+		}
+		if len(targets) > 0 {
+			normalized[key] = targets
+		}
+	}
+	m.modelFallbacks.Store(normalized)
+}
+
+// fallbackRoute is a resolved fallback model with the providers able to serve it.
+type fallbackRoute struct {
+	model     string
+	providers []string
+}
+
+// fallbackRoutes resolves the chain configured for model. Fallback chains are not followed
+// transitively, so a misconfigured cycle cannot loop.
+func (m *Manager) fallbackRoutes(ctx context.Context, model string) []fallbackRoute {
+	if m == nil {
+		return nil
+	}
+	chains, _ := m.modelFallbacks.Load().(map[string][]ModelFallback)
+	if len(chains) == 0 {
+		return nil
+	}
+	var chain []ModelFallback
+	for _, candidate := range m.fallbackKeys(model) {
+		if chain = chains[candidate]; len(chain) > 0 {
+			break
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	filter, _ := ctx.Value(fallbackFilterKey{}).(FallbackFilter)
+	routes := make([]fallbackRoute, 0, len(chain))
+	for _, target := range chain {
+		providers := util.GetProviderName(target.Model)
+		if target.Provider != "" {
+			filtered := providers[:0]
+			for _, provider := range providers {
+				if strings.EqualFold(provider, target.Provider) {
+					filtered = append(filtered, provider)
+				}
+			}
+			providers = filtered
+		}
+		if filter != nil && len(providers) > 0 {
+			providers = filter(target.Model, providers)
+		}
+		if len(providers) == 0 {
+			log.Debugf("fallback %s -> %s skipped: no provider available", model, target.Model)
+			continue
+		}
+		routes = append(routes, fallbackRoute{model: target.Model, providers: providers})
+	}
+	return routes
+}
+
+// fallbackKeys lists the names a fallback chain may be configured under for model, most specific
+// first: the name as requested, without its thinking suffix ("model(high)"), and without a
+// credential model prefix ("team/model").
+func (m *Manager) fallbackKeys(model string) []string {
+	model = strings.TrimSpace(model)
+	names := []string{model}
+	if base, _ := util.NormalizeThinkingModel(model); base != model {
+		names = append(names, base)
+	}
+	for _, name := range names {
+		if prefix, rest, ok := strings.Cut(name, "/"); ok && rest != "" && m.HasModelPrefix(prefix) {
+			names = append(names, rest)
+		}
+	}
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, strings.ToLower(name))
+	}
+	return keys
+}
+
+// fallbackEligible reports whether err means the requested model is currently unservable:
+// every credential for it is cooling down, or none is available at all. Errors from a single
+// upstream attempt, even 403 or 5xx, are returned to the client rather than switching models.
+func fallbackEligible(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var cooldown *modelCooldownError
+	if errors.As(err, &cooldown) {
+		return true
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) {
+		switch authErr.Code {
+		case "auth_not_found", "auth_unavailable":
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackRequest retargets req and opts at model. Original-model hints set by the handler
+// describe the requested model and are dropped so executors resolve the fallback afresh.
+func fallbackRequest(req cliproxyexecutor.Request, opts cliproxyexecutor.Options, model string) (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	req.Model = model
+	req.Metadata = withoutModelHints(req.Metadata)
+	opts.Metadata = withoutModelHints(opts.Metadata)
+	return req, opts
+}
+
+func withoutModelHints(metadata map[string]any) map[string]any {
+	if len(metadata) == 0 {
+		return metadata
+	}
+	out := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		switch key {
+		case util.ThinkingOriginalModelMetadataKey, util.GeminiOriginalModelMetadataKey, util.ModelMappingOriginalModelMetadataKey:
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// markServedModel tells the client which model answered when a fallback was used.
+func markServedModel(ctx context.Context, model string) {
+	if ctx == nil {
+		return
+	}
+	if ginCtx, ok := ctx.Value("gin").(interface{ Header(string, string) }); ok && ginCtx != nil {
+		ginCtx.Header(ServedModelHeader, model)
+	}
+}
+
+// executeWithFallbacks runs execute for the requested model and, when it fails in a way that
+// makes the model unservable, for each configured fallback in order. The original error is
+// returned when no fallback succeeds.
+func executeWithFallbacks[T any](ctx context.Context, m *Manager, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, execute func(context.Context, []string, cliproxyexecutor.Request, cliproxyexecutor.Options) (T, error)) (T, error) {
+	result, err := execute(ctx, providers, req, opts)
+	if err == nil || !fallbackEligible(err) {
+		return result, err
+	}
+	for _, route := range m.fallbackRoutes(ctx, req.Model) {
+		if ctx.Err() != nil {
+			break
+		}
+		fbReq, fbOpts := fallbackRequest(req, opts, route.model)
+		log.Debugf("model %s unavailable (%v), falling back to %s", req.Model, err, route.model)
+		fbResult, errFallback := execute(ctx, route.providers, fbReq, fbOpts)
+		if errFallback == nil {
+			markServedModel(ctx, route.model)
+			return fbResult, nil
+		}
+		log.Debugf("fallback %s for %s failed: %v", route.model, req.Model, errFallback)
+	}
+	return result, err
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type fallbackTestError int
+
+func (e fallbackTestError) Error() string   { return http.StatusText(int(e)) }
+func (e fallbackTestError) StatusCode() int { return int(e) }
+
+// fallbackTestExecutor fails every request with status, or echoes the model when status is 0.
+type fallbackTestExecutor struct {
+	provider string
+	status   int
+	seen     *[]cliproxyexecutor.Request
+}
+
+func (e fallbackTestExecutor) Identifier() string { return e.provider }
+
+func (e fallbackTestExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if e.seen != nil {
+		*e.seen = append(*e.seen, req)
+	}
+	if e.status != 0 {
+		return cliproxyexecutor.Response{}, fallbackTestError(e.status)
+	}
+	return cliproxyexecutor.Response{Payload: []byte(req.Model)}, nil
+}
+
+func (e fallbackTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, fallbackTestError(http.StatusNotImplemented)
+}
+
+func (e fallbackTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e fallbackTestExecutor) CountTokens(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type headerRecorder map[string]string
+
+func (h headerRecorder) Header(key, value string) { h[key] = value }
+
+func newFallbackTestManager(t *testing.T, primaryStatus int, seen *[]cliproxyexecutor.Request) *Manager {
+	t.Helper()
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("fb-primary", "fbprimary", []*registry.ModelInfo{{ID: "fb-model-a"}})
+	reg.RegisterClient("fb-secondary", "fbsecondary", []*registry.ModelInfo{{ID: "fb-model-b"}})
+	t.Cleanup(func() {
+		reg.UnregisterClient("fb-primary")
+		reg.UnregisterClient("fb-secondary")
+	})
+
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	manager.RegisterExecutor(fallbackTestExecutor{provider: "fbprimary", status: primaryStatus})
+	manager.RegisterExecutor(fallbackTestExecutor{provider: "fbsecondary", seen: seen})
+	for _, auth := range []*Auth{{ID: "fb-primary", Provider: "fbprimary"}, {ID: "fb-secondary", Provider: "fbsecondary"}} {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("Register(%s): %v", auth.ID, err)
+		}
+	}
+	manager.SetModelFallbacks(map[string][]ModelFallback{"FB-Model-A": {{Model: "fb-model-b"}}})
+	return manager
+}
+
+func TestExecute_FallsBackWhenModelUnavailable(t *testing.T) {
+	var seen []cliproxyexecutor.Request
+	manager := newFallbackTestManager(t, http.StatusTooManyRequests, &seen)
+	headers := headerRecorder{}
+	ctx := context.WithValue(context.Background(), "gin", headers)
+
+	req := cliproxyexecutor.Request{
+		Model:    "fb-model-a",
+		Metadata: map[string]any{util.ModelMappingOriginalModelMetadataKey: "fb-model-a", "keep": true},
+	}
+	if _, err := manager.Execute(ctx, []string{"fbprimary"}, req, cliproxyexecutor.Options{}); statusCodeFromError(err) != http.StatusTooManyRequests {
+		t.Fatalf("first Execute() error = %v, want the upstream 429 without fallback", err)
+	}
+	if len(seen) != 0 {
+		t.Fatal("a single failed attempt switched models")
+	}
+	// The 429 put the only credential into cooldown, so the model is now unservable.
+	resp, err := manager.Execute(ctx, []string{"fbprimary"}, req, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "fb-model-b" {
+		t.Fatalf("payload = %q, want fallback model", resp.Payload)
+	}
+	if got := headers[ServedModelHeader]; got != "fb-model-b" {
+		t.Fatalf("%s = %q", ServedModelHeader, got)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("fallback executor saw %d requests", len(seen))
+	}
+	if _, ok := seen[0].Metadata[util.ModelMappingOriginalModelMetadataKey]; ok {
+		t.Fatal("original model hint leaked into fallback request")
+	}
+	if seen[0].Metadata["keep"] != true {
+		t.Fatal("fallback request lost unrelated metadata")
+	}
+}
+
+func TestExecute_NoFallbackForSingleAttemptErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError} {
+		var seen []cliproxyexecutor.Request
+		manager := newFallbackTestManager(t, status, &seen)
+
+		_, err := manager.Execute(context.Background(), []string{"fbprimary"}, cliproxyexecutor.Request{Model: "fb-model-a"}, cliproxyexecutor.Options{})
+		if statusCodeFromError(err) != status {
+			t.Fatalf("Execute() error = %v, want the original %d", err, status)
+		}
+		if len(seen) != 0 {
+			t.Fatalf("fallback executor was called for a %d", status)
+		}
+	}
+}
+
+func TestFallbackRoutes_NormalizesModelName(t *testing.T) {
+	manager := newFallbackTestManager(t, 0, nil)
+	if _, err := manager.Register(context.Background(), &Auth{ID: "fb-prefixed", Provider: "fbprimary", Prefix: "team"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	for _, model := range []string{"fb-model-a", "FB-Model-A(high)", "team/fb-model-a", "team/fb-model-a(8192)"} {
+		routes := manager.fallbackRoutes(context.Background(), model)
+		if len(routes) != 1 || routes[0].model != "fb-model-b" {
+			t.Fatalf("fallbackRoutes(%q) = %+v", model, routes)
+		}
+	}
+	if routes := manager.fallbackRoutes(context.Background(), "other/fb-model-a"); len(routes) != 0 {
+		t.Fatalf("unknown prefix resolved a fallback: %+v", routes)
+	}
+}
+
+func TestExecute_FallbackFilterSkipsDeniedModels(t *testing.T) {
+	var seen []cliproxyexecutor.Request
+	manager := newFallbackTestManager(t, http.StatusTooManyRequests, &seen)
+	ctx := WithFallbackFilter(context.Background(), func(string, []string) []string { return nil })
+
+	// Put the only credential into cooldown first so the fallback chain is consulted.
+	_, _ = manager.Execute(ctx, []string{"fbprimary"}, cliproxyexecutor.Request{Model: "fb-model-a"}, cliproxyexecutor.Options{})
+	_, err := manager.Execute(ctx, []string{"fbprimary"}, cliproxyexecutor.Request{Model: "fb-model-a"}, cliproxyexecutor.Options{})
+	if !fallbackEligible(err) {
+		t.Fatalf("Execute() error = %v, want the original cooldown error", err)
+	}
+	if len(seen) != 0 {
+		t.Fatalf("denied fallback model was executed")
+	}
+}
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultHealthCheckInterval is how often credentials are probed when no interval is configured.
+	DefaultHealthCheckInterval = 10 * time.Minute
+	// DefaultHealthCheckTimeout bounds a single probe when no timeout is configured.
+	DefaultHealthCheckTimeout = 30 * time.Second
+	// DefaultHealthCheckConcurrency bounds parallel probes when no limit is configured.
+	DefaultHealthCheckConcurrency = 4
+
+	// healthCheckErrorCode marks auth errors recorded by the prober; such auths are kept out of
+	// rotation until a later probe succeeds.
+	healthCheckErrorCode = "health_check_failed"
+)
+
+// HealthProber is implemented by provider executors that can verify a credential with a cheap
+// upstream call, such as listing models.
+type HealthProber interface {
+	// Probe checks that the upstream still accepts auth. It returns the auth with refreshed tokens
+	// when the probe had to refresh them, or nil when nothing changed. Rejections are reported
+	// as errors carrying an HTTP status code.
+	Probe(ctx context.Context, auth *Auth) (*Auth, error)
+}
+
+// HealthCheckConfig controls background credential probing.
+type HealthCheckConfig struct {
+	// Enabled turns periodic probing on.
+	Enabled bool
+	// Interval is the time between probe rounds.
+	Interval time.Duration
+	// Timeout bounds a single probe.
+	Timeout time.Duration
+	// Concurrency bounds the number of probes running at once.
+	Concurrency int
+	// Providers optionally restricts probing to these provider keys.
+	Providers []string
+}
+
+// ProbeResult is the outcome of the latest health probe of a credential.
+type ProbeResult struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	Healthy    bool      `json:"healthy"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type healthChecker struct {
+	mu      sync.Mutex
+	cfg     HealthCheckConfig
+	cancel  context.CancelFunc
+	results map[string]ProbeResult
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{results: make(map[string]ProbeResult)}
+}
+
+// SetHealthCheck applies the probing configuration, starting, restarting or stopping the
+// background loop as needed.
+func (m *Manager) SetHealthCheck(cfg HealthCheckConfig) {
+	if m == nil || m.health == nil {
+		return
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthCheckInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHealthCheckTimeout
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultHealthCheckConcurrency
+	}
+	providers := make([]string, 0, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		if provider = strings.ToLower(strings.TrimSpace(provider)); provider != "" {
+			providers = append(providers, provider)
+		}
+	}
+	cfg.Providers = providers
+
+	h := m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	running := h.cancel != nil
+	if running && cfg.Enabled && h.cfg.Interval == cfg.Interval {
+		h.cfg = cfg
+		return
+	}
+	if running {
+		h.cancel()
+		h.cancel = nil
+	}
+	h.cfg = cfg
+	if !cfg.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go m.runHealthChecks(ctx, cfg.Interval)
+	log.Infof("credential health checks started (interval=%s)", cfg.Interval)
+}
+
+// StopHealthCheck cancels the background probing loop, if running.
+func (m *Manager) StopHealthCheck() {
+	if m == nil || m.health == nil {
+		return
+	}
+	m.health.mu.Lock()
+	if m.health.cancel != nil {
+		m.health.cancel()
+		m.health.cancel = nil
+	}
+	m.health.mu.Unlock()
+}
+
+// ProbeResult returns the latest probe outcome recorded for the auth.
+func (m *Manager) ProbeResult(id string) (ProbeResult, bool) {
+	if m == nil || m.health == nil {
+		return ProbeResult{}, false
+	}
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	result, ok := m.health.results[id]
+	return result, ok
+}
+
+func (m *Manager) healthCheckConfig() HealthCheckConfig {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	return m.health.cfg
+}
+
+func (m *Manager) runHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth probes every eligible credential once and waits for the round to finish.
+func (m *Manager) checkHealth(ctx context.Context) {
+	cfg := m.healthCheckConfig()
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, auth := range m.snapshotAuths() {
+		if auth.Disabled || !providerSelected(cfg.Providers, auth.Provider) {
+			continue
+		}
+		if _, ok := m.executorFor(auth.Provider).(HealthProber); !ok {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			probeCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+			m.probeAuth(probeCtx, id, cfg.Interval+cfg.Timeout)
+		}(auth.ID)
+	}
+	wg.Wait()
+	m.pruneProbeResults()
+}
+
+func providerSelected(providers []string, provider string) bool {
+	if len(providers) == 0 {
+		return true
+	}
+	provider = strings.ToLower(provider)
+	for _, candidate := range providers {
+		if candidate == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// probeAuth probes one credential. A 401 from an OAuth credential is retried once after a token
+// refresh so that an expired access token is not mistaken for a revoked account. The refresh goes
+// through refreshAuth under the refresh-pending slot, so it never races the auto-refresh loop; when
+// a refresh is already under way the probe is inconclusive, and when the last refresh failed the
+// 401 stands. A 401 or 403 takes the credential out of rotation for holdFor, and a later
+// successful probe restores it.
+func (m *Manager) probeAuth(ctx context.Context, id string, holdFor time.Duration) {
+	m.mu.RLock()
+	current := m.auths[id]
+	var exec ProviderExecutor
+	if current != nil {
+		exec = m.executors[current.Provider]
+	}
+	m.mu.RUnlock()
+	prober, ok := exec.(HealthProber)
+	if current == nil || !ok {
+		return
+	}
+	auth := current.Clone()
+	if rt := m.roundTripperFor(auth); rt != nil {
+		ctx = context.WithValue(ctx, roundTripperContextKey{}, rt)
+		ctx = context.WithValue(ctx, "cliproxy.roundtripper", rt)
+	}
+
+	start := time.Now()
+	updated, err := prober.Probe(ctx, auth)
+	m.storeProbeTokens(ctx, current, updated)
+	refreshBusy := false
+	if statusCodeFromError(err) == 401 {
+		if kind, _ := auth.AccountInfo(); kind != "api_key" {
+			switch m.markRefreshPending(id, time.Now()) {
+			case refreshSlotTaken:
+				err = m.reprobeAfterRefresh(ctx, prober, id, err)
+			case refreshSlotInFlight:
+				refreshBusy = true
+				err = fmt.Errorf("%w; token refresh already in progress", err)
+			case refreshSlotBackoff:
+				// The last refresh failed; the credential is judged on that instead of refreshing again.
+				err = fmt.Errorf("%w; last token refresh failed", err)
+			}
+		}
+	}
+	now := time.Now()
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	result := ProbeResult{CheckedAt: now, Healthy: err == nil, LatencyMs: now.Sub(start).Milliseconds()}
+	if err != nil {
+		result.StatusCode = statusCodeFromError(err)
+		result.Error = err.Error()
+	}
+	m.health.mu.Lock()
+	m.health.results[id] = result
+	m.health.mu.Unlock()
+
+	switch {
+	case err == nil:
+		m.applyProbeSuccess(ctx, id, now)
+	case refreshBusy:
+		log.Debugf("health check: %s credential %s left for the pending token refresh: %v", current.Provider, id, err)
+	case result.StatusCode == 401 || result.StatusCode == 403:
+		log.Warnf("health check: %s credential %s rejected with status %d", current.Provider, id, result.StatusCode)
+		m.applyProbeFailure(ctx, id, result, now.Add(holdFor))
+	default:
+		log.Debugf("health check: %s credential %s probe failed: %v", current.Provider, id, err)
+	}
+}
+
+// reprobeAfterRefresh refreshes the auth through the slot the caller took and probes it again.
+// It returns the error the credential should be judged on.
+func (m *Manager) reprobeAfterRefresh(ctx context.Context, prober HealthProber, id string, probeErr error) error {
+	if errRefresh := m.refreshAuth(ctx, id); errRefresh != nil {
+		return fmt.Errorf("%w; token refresh failed: %v", probeErr, errRefresh)
+	}
+	m.mu.RLock()
+	refreshed := m.auths[id]
+	if refreshed != nil {
+		refreshed = refreshed.Clone()
+	}
+	m.mu.RUnlock()
+	if refreshed == nil {
+		return probeErr
+	}
+	updated, err := prober.Probe(ctx, refreshed)
+	m.storeProbeTokens(ctx, refreshed, updated)
+	return err
+}
+
+// storeProbeTokens saves tokens a prober refreshed on its own, even when the probe itself was cut
+// short; providers such as Codex rotate refresh tokens, so dropping them would lock the credential out.
+func (m *Manager) storeProbeTokens(ctx context.Context, base, updated *Auth) {
+	if updated == nil {
+		return
+	}
+	if updated.Runtime == nil {
+		updated.Runtime = base.Runtime
+	}
+	updated.LastRefreshedAt = time.Now()
+	updated.NextRefreshAfter = time.Time{}
+	if _, errUpdate := m.Update(context.WithoutCancel(ctx), updated); errUpdate != nil {
+		log.Warnf("health check: failed to store refreshed tokens for %s: %v", updated.ID, errUpdate)
+	}
+}
+
+// applyProbeFailure marks the auth as rejected upstream so the selector skips it. The mark lives
+// only in the manager's in-memory state: it is not persisted, and a restart or a watcher reload of
+// the auth file clears it until the next probe round.
+func (m *Manager) applyProbeFailure(ctx context.Context, id string, result ProbeResult, until time.Time) {
+	m.mu.Lock()
+	auth := m.auths[id]
+	if auth == nil {
+		m.mu.Unlock()
+		return
+	}
+	message := "credential rejected by upstream (unauthorized); it may have been revoked or expired"
+	if result.StatusCode == 403 {
+		message = "credential rejected by upstream (forbidden); the account may be suspended or lack access"
+	}
+	auth.Status = StatusError
+	auth.StatusMessage = "health check failed: " + message
+	auth.Unavailable = true
+	auth.NextRetryAfter = until
+	auth.LastError = &Error{Code: healthCheckErrorCode, Message: result.Error, HTTPStatus: result.StatusCode}
+	auth.UpdatedAt = result.CheckedAt
+	snapshot := auth.Clone()
+	m.mu.Unlock()
+	m.hook.OnAuthUpdated(ctx, snapshot)
+}
+
+// applyProbeSuccess restores an auth that a probe or a real request had marked as rejected.
+func (m *Manager) applyProbeSuccess(ctx context.Context, id string, now time.Time) {
+	m.mu.Lock()
+	auth := m.auths[id]
+	if auth == nil || auth.LastError == nil {
+		m.mu.Unlock()
+		return
+	}
+	switch auth.LastError.StatusCode() {
+	case 401, 403:
+	default:
+		m.mu.Unlock()
+		return
+	}
+	clearAuthStateOnSuccess(auth, now)
+	for _, state := range auth.ModelStates {
+		if state == nil || state.LastError == nil {
+			continue
+		}
+		if code := state.LastError.StatusCode(); code == 401 || code == 403 {
+			resetModelState(state, now)
+		}
+	}
+	snapshot := auth.Clone()
+	m.mu.Unlock()
+	log.Infof("health check: %s credential %s accepted again", snapshot.Provider, id)
+	m.hook.OnAuthUpdated(ctx, snapshot)
+}
+
+// blockedByHealthCheck reports whether a failed probe keeps the auth out of rotation.
+func blockedByHealthCheck(auth *Auth, now time.Time) (bool, time.Time) {
+	if auth == nil || auth.LastError == nil || auth.LastError.Code != healthCheckErrorCode {
+		return false, time.Time{}
+	}
+	if !auth.Unavailable || !auth.NextRetryAfter.After(now) {
+		return false, time.Time{}
+	}
+	return true, auth.NextRetryAfter
+}
+
+// pruneProbeResults drops results of auths that no longer exist.
+func (m *Manager) pruneProbeResults() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	for id := range m.health.results {
+		if _, ok := m.auths[id]; !ok {
+			delete(m.health.results, id)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type probeTestExecutor struct {
+	fallbackTestExecutor
+	probeStatus int
+	refreshErr  error
+	refreshes   int
+}
+
+func (e *probeTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	e.refreshes++
+	if e.refreshErr != nil {
+		return nil, e.refreshErr
+	}
+	return auth, nil
+}
+
+func (e *probeTestExecutor) Probe(context.Context, *Auth) (*Auth, error) {
+	if e.probeStatus != 0 {
+		return nil, fallbackTestError(e.probeStatus)
+	}
+	return nil, nil
+}
+
+func newProbeTestManager(t *testing.T) (*Manager, *probeTestExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	executor := &probeTestExecutor{fallbackTestExecutor: fallbackTestExecutor{provider: "probetest"}}
+	manager.RegisterExecutor(executor)
+	auth := &Auth{ID: "oauth", Provider: "probetest", Metadata: map[string]any{"email": "user@example.com"}}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return manager, executor
+}
+
+func TestProbeAuth_RevokedCredentialLeavesRotation(t *testing.T) {
+	manager, executor := newProbeTestManager(t)
+	executor.probeStatus = http.StatusUnauthorized
+	executor.refreshErr = fallbackTestError(http.StatusBadRequest)
+
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+
+	if executor.refreshes != 1 {
+		t.Fatalf("refreshes = %d, want one refresh before declaring the credential revoked", executor.refreshes)
+	}
+	result, ok := manager.ProbeResult("oauth")
+	if !ok || result.Healthy || result.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("ProbeResult = %+v, %v", result, ok)
+	}
+	auth, _ := manager.GetByID("oauth")
+	if auth.Status != StatusError || auth.StatusMessage == "" {
+		t.Fatalf("auth status = %s (%q), want error with a message", auth.Status, auth.StatusMessage)
+	}
+	if blocked, _, _ := isAuthBlockedForModel(auth, "some-model", time.Now()); !blocked {
+		t.Fatal("revoked credential is still selectable")
+	}
+	if _, err := manager.Execute(context.Background(), []string{"probetest"}, cliproxyexecutor.Request{Model: "some-model"}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() succeeded with the only credential revoked")
+	}
+
+	executor.probeStatus = 0
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+
+	auth, _ = manager.GetByID("oauth")
+	if auth.Status != StatusActive || auth.Unavailable {
+		t.Fatalf("auth status = %s unavailable=%v after a successful probe", auth.Status, auth.Unavailable)
+	}
+	if result, _ = manager.ProbeResult("oauth"); !result.Healthy {
+		t.Fatalf("ProbeResult = %+v, want healthy", result)
+	}
+}
+
+func TestProbeAuth_TransientFailureKeepsCredential(t *testing.T) {
+	manager, executor := newProbeTestManager(t)
+	executor.probeStatus = http.StatusServiceUnavailable
+
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+
+	if executor.refreshes != 0 {
+		t.Fatalf("refreshes = %d, want none for a 503", executor.refreshes)
+	}
+	auth, _ := manager.GetByID("oauth")
+	if blocked, _, _ := isAuthBlockedForModel(auth, "some-model", time.Now()); blocked {
+		t.Fatal("a transient probe failure took the credential out of rotation")
+	}
+	if result, _ := manager.ProbeResult("oauth"); result.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("ProbeResult = %+v", result)
+	}
+}
+
+func TestProbeAuth_LeavesPendingRefreshAlone(t *testing.T) {
+	manager, executor := newProbeTestManager(t)
+	executor.probeStatus = http.StatusUnauthorized
+	if slot := manager.markRefreshPending("oauth", time.Now()); slot != refreshSlotTaken {
+		t.Fatalf("markRefreshPending() = %v", slot)
+	}
+
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+
+	if executor.refreshes != 0 {
+		t.Fatalf("refreshes = %d, want none while the auto-refresh loop holds the credential", executor.refreshes)
+	}
+	auth, _ := manager.GetByID("oauth")
+	if blocked, _, _ := isAuthBlockedForModel(auth, "some-model", time.Now()); blocked {
+		t.Fatal("an inconclusive probe took the credential out of rotation")
+	}
+}
+
+func TestProbeAuth_FailedRefreshBackoffMarksCredential(t *testing.T) {
+	manager, executor := newProbeTestManager(t)
+	executor.probeStatus = http.StatusUnauthorized
+	executor.refreshErr = fallbackTestError(http.StatusBadRequest)
+
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+	auth, _ := manager.GetByID("oauth")
+	auth.Status, auth.Unavailable, auth.LastError, auth.NextRetryAfter = StatusActive, false, nil, time.Time{}
+	if _, err := manager.Update(context.Background(), auth); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	manager.probeAuth(context.Background(), "oauth", time.Hour)
+
+	if executor.refreshes != 1 {
+		t.Fatalf("refreshes = %d, want no retry during the failure backoff", executor.refreshes)
+	}
+	auth, _ = manager.GetByID("oauth")
+	if blocked, _, _ := isAuthBlockedForModel(auth, "some-model", time.Now()); !blocked {
+		t.Fatal("a credential whose last refresh failed stayed in rotation")
+	}
+	if result, _ := manager.ProbeResult("oauth"); !strings.Contains(result.Error, "last token refresh failed") {
+		t.Fatalf("ProbeResult = %+v", result)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,32 @@ type RoundRobinSelector struct {
 // rolling-window subscription caps (e.g. chat message limits).
 type FillFirstSelector struct{}
 
+// WeightedSelector distributes requests across credentials in proportion to their
+// "weight" attribute using smooth weighted round-robin. Credentials without a valid
+// weight count as 1. State for credentials that are no longer available is dropped on
+// the next pick so removed auths do not accumulate.
+type WeightedSelector struct {
+	mu      sync.Mutex
+	current map[string]map[string]int
+}
+
+// LeastInFlightSelector picks the credential with the fewest outstanding requests for
+// the requested model. Pick reserves a slot on the chosen credential; the manager
+// hands it back through Release once the upstream call finishes.
+type LeastInFlightSelector struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// InFlightSelector is implemented by selectors that account for outstanding requests.
+// Acquire reserves a slot on an auth chosen without Pick (for example a session-affinity
+// pin). Release is called exactly once for every successful Pick or Acquire.
+type InFlightSelector interface {
+	Selector
+	Acquire(authID, model string)
+	Release(authID, model string)
+}
+
 type blockReason int
 
 const (
@@ -142,7 +169,33 @@ func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]
 		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
 	}
 
-	return available, nil
+	return highestPriority(available), nil
+}
+
+// highestPriority keeps the available auths of the highest priority, so lower priority
+// credentials only serve requests while every higher priority one is unavailable. Every built-in
+// strategy picks from this set; auths without a priority count as 0 and are left unfiltered.
+func highestPriority(available []*Auth) []*Auth {
+	best := authPriority(available[0])
+	mixed := false
+	for _, candidate := range available[1:] {
+		if priority := authPriority(candidate); priority != best {
+			mixed = true
+			if priority > best {
+				best = priority
+			}
+		}
+	}
+	if !mixed {
+		return available
+	}
+	filtered := make([]*Auth, 0, len(available))
+	for _, candidate := range available {
+		if authPriority(candidate) == best {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
 }
 
 // Pick selects the next available auth for the provider in a round-robin manner.
@@ -183,6 +236,163 @@ func (s *FillFirstSelector) Pick(ctx context.Context, provider, model string, op
 	return available[0], nil
 }
 
+// Pick selects the available auth with the highest accumulated weight for the provider/model.
+func (s *WeightedSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	key := provider + ":" + model
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		s.current = make(map[string]map[string]int)
+	}
+	current := s.current[key]
+	if current == nil {
+		current = make(map[string]int)
+		s.current[key] = current
+	}
+	if len(current) > 0 {
+		live := make(map[string]struct{}, len(available))
+		for _, candidate := range available {
+			live[candidate.ID] = struct{}{}
+		}
+		for id := range current {
+			if _, ok := live[id]; !ok {
+				delete(current, id)
+			}
+		}
+	}
+	total := 0
+	var best *Auth
+	for _, candidate := range available {
+		weight := authWeight(candidate)
+		total += weight
+		current[candidate.ID] += weight
+		if best == nil || current[candidate.ID] > current[best.ID] {
+			best = candidate
+		}
+	}
+	current[best.ID] -= total
+	return best, nil
+}
+
+// Pick selects the available auth with the fewest in-flight requests for the model,
+// breaking ties by ID, and reserves a slot on it.
+func (s *LeastInFlightSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[string]int)
+	}
+	best := available[0]
+	bestCount := s.inFlight[inFlightKey(best.ID, model)]
+	for _, candidate := range available[1:] {
+		if count := s.inFlight[inFlightKey(candidate.ID, model)]; count < bestCount {
+			best = candidate
+			bestCount = count
+		}
+	}
+	s.inFlight[inFlightKey(best.ID, model)] = bestCount + 1
+	return best, nil
+}
+
+// Acquire reserves a slot on an auth that was selected outside Pick.
+func (s *LeastInFlightSelector) Acquire(authID, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[string]int)
+	}
+	s.inFlight[inFlightKey(authID, model)]++
+}
+
+// Release returns the slot reserved by Pick or Acquire.
+func (s *LeastInFlightSelector) Release(authID, model string) {
+	key := inFlightKey(authID, model)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if count := s.inFlight[key]; count > 1 {
+		s.inFlight[key] = count - 1
+	} else {
+		delete(s.inFlight, key)
+	}
+}
+
+// InFlight reports the number of outstanding requests for the auth/model pair.
+func (s *LeastInFlightSelector) InFlight(authID, model string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight[inFlightKey(authID, model)]
+}
+
+func inFlightKey(authID, model string) string {
+	return authID + "|" + model
+}
+
+// authWeight reads the routing weight from the auth attributes or metadata, defaulting to 1.
+func authWeight(auth *Auth) int {
+	if auth == nil {
+		return 1
+	}
+	raw := ""
+	if auth.Attributes != nil {
+		raw = strings.TrimSpace(auth.Attributes["weight"])
+	}
+	if raw == "" && auth.Metadata != nil {
+		switch v := auth.Metadata["weight"].(type) {
+		case float64:
+			raw = strconv.Itoa(int(v))
+		case int:
+			raw = strconv.Itoa(v)
+		case string:
+			raw = strings.TrimSpace(v)
+		}
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil || weight <= 0 {
+		return 1
+	}
+	return weight
+}
+
+// authPriority reads the routing priority from the auth attributes or metadata, defaulting to 0.
+func authPriority(auth *Auth) int {
+	if auth == nil {
+		return 0
+	}
+	raw := ""
+	if auth.Attributes != nil {
+		raw = strings.TrimSpace(auth.Attributes["priority"])
+	}
+	if raw == "" && auth.Metadata != nil {
+		switch v := auth.Metadata["priority"].(type) {
+		case float64:
+			raw = strconv.Itoa(int(v))
+		case int:
+			raw = strconv.Itoa(v)
+		case string:
+			raw = strings.TrimSpace(v)
+		}
+	}
+	priority, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
 func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, blockReason, time.Time) {
 	if auth == nil {
 		return true, blockReasonOther, time.Time{}
@@ -190,6 +400,9 @@ func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, block
 	if auth.Disabled || auth.Status == StatusDisabled {
 		return true, blockReasonDisabled, time.Time{}
 	}
+	if blocked, next := blockedByHealthCheck(auth, now); blocked {
+		return true, blockReasonOther, next
+	}
 	if model != "" {
 		if len(auth.ModelStates) > 0 {
 			if state, ok := auth.ModelStates[model]; ok && state != nil {
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// quotaHistoryWindow bounds how long a 429 counts against a credential's headroom.
+const quotaHistoryWindow = time.Hour
+
+// quotaSweepInterval is how often histories of credentials that are no longer picked, such as
+// removed auths or retired models, are dropped.
+const quotaSweepInterval = 10 * time.Minute
+
+// ResultAwareSelector is implemented by selectors that learn from execution outcomes.
+// The manager forwards every result recorded through MarkResult.
+type ResultAwareSelector interface {
+	Selector
+	ObserveResult(result Result)
+}
+
+// QuotaAwareSelector prefers the credential with the most remaining headroom for the
+// requested model: fewest recent 429 responses, then lowest quota backoff level, then
+// the earliest (or no) quota recovery time. Equally ranked credentials are rotated.
+type QuotaAwareSelector struct {
+	mu        sync.Mutex
+	limited   map[string][]time.Time
+	cursors   map[string]int
+	lastSweep time.Time
+}
+
+// ObserveResult records quota failures so Pick can rank credentials by recent 429s.
+func (s *QuotaAwareSelector) ObserveResult(result Result) {
+	if result.AuthID == "" || result.Success || statusCodeFromResult(result.Error) != 429 {
+		return
+	}
+	now := time.Now()
+	key := result.AuthID + "|" + result.Model
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limited == nil {
+		s.limited = make(map[string][]time.Time)
+	}
+	s.limited[key] = append(pruneQuotaHistory(s.limited[key], now), now)
+	s.sweepLocked(now)
+}
+
+// sweepLocked drops expired histories at most once per quotaSweepInterval. Pick only prunes
+// the keys of current candidates, so without this keys of unregistered auths would linger.
+func (s *QuotaAwareSelector) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < quotaSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, history := range s.limited {
+		if len(pruneQuotaHistory(history, now)) == 0 {
+			delete(s.limited, key)
+		}
+	}
+}
+
+// RecentQuotaHits reports how many 429 responses were observed for the auth/model pair
+// within the history window.
+func (s *QuotaAwareSelector) RecentQuotaHits(authID, model string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(pruneQuotaHistory(s.limited[authID+"|"+model], time.Now()))
+}
+
+// Pick selects the available auth with the most quota headroom for the model.
+func (s *QuotaAwareSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	type ranked struct {
+		auth    *Auth
+		hits    int
+		backoff int
+		recover time.Time
+	}
+	candidates := make([]ranked, 0, len(available))
+	for _, candidate := range available {
+		key := candidate.ID + "|" + model
+		history := pruneQuotaHistory(s.limited[key], now)
+		if len(history) == 0 {
+			delete(s.limited, key)
+		} else {
+			s.limited[key] = history
+		}
+		quota := quotaStateForModel(candidate, model)
+		candidates = append(candidates, ranked{
+			auth:    candidate,
+			hits:    len(history),
+			backoff: quota.BackoffLevel,
+			recover: quota.NextRecoverAt,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.hits != b.hits {
+			return a.hits < b.hits
+		}
+		if a.backoff != b.backoff {
+			return a.backoff < b.backoff
+		}
+		return a.recover.Before(b.recover)
+	})
+
+	tied := 1
+	for tied < len(candidates) {
+		a, b := candidates[0], candidates[tied]
+		if a.hits != b.hits || a.backoff != b.backoff || !a.recover.Equal(b.recover) {
+			break
+		}
+		tied++
+	}
+	if s.cursors == nil {
+		s.cursors = make(map[string]int)
+	}
+	cursorKey := provider + ":" + model
+	index := s.cursors[cursorKey]
+	if index >= 2_147_483_640 {
+		index = 0
+	}
+	s.cursors[cursorKey] = index + 1
+	return candidates[index%tied].auth, nil
+}
+
+// quotaStateForModel returns the model-level quota state, falling back to the auth-level state.
+func quotaStateForModel(auth *Auth, model string) QuotaState {
+	if auth == nil {
+		return QuotaState{}
+	}
+	if model != "" && len(auth.ModelStates) > 0 {
+		if state, ok := auth.ModelStates[model]; ok && state != nil {
+			return state.Quota
+		}
+	}
+	return auth.Quota
+}
+
+func pruneQuotaHistory(history []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-quotaHistoryWindow)
+	start := 0
+	for start < len(history) && history[start].Before(cutoff) {
+		start++
+	}
+	return history[start:]
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 )
@@ -111,3 +112,250 @@ func TestRoundRobinSelectorPick_Concurrent(t *testing.T) {
 	default:
 	}
 }
+
+func TestWeightedSelectorPick_HonorsWeights(t *testing.T) {
+	t.Parallel()
+
+	selector := &WeightedSelector{}
+	auths := []*Auth{
+		{ID: "a", Attributes: map[string]string{"weight": "3"}},
+		{ID: "b"},
+		{ID: "c", Metadata: map[string]any{"weight": float64(2)}},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 60; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		counts[got.ID]++
+	}
+
+	want := map[string]int{"a": 30, "b": 10, "c": 20}
+	for id, n := range want {
+		if counts[id] != n {
+			t.Fatalf("Pick() counts[%q] = %d, want %d (all counts: %v)", id, counts[id], n, counts)
+		}
+	}
+}
+
+func TestWeightedSelectorPick_PrunesRemovedAuths(t *testing.T) {
+	t.Parallel()
+
+	selector := &WeightedSelector{}
+	auths := []*Auth{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	for i := 0; i < 3; i++ {
+		if _, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths); err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+	}
+
+	if _, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths[:1]); err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	selector.mu.Lock()
+	defer selector.mu.Unlock()
+	if current := selector.current["gemini:"]; len(current) != 1 {
+		t.Fatalf("current = %v, want only the remaining auth", current)
+	}
+}
+
+func TestRoundRobinSelectorPick_PrefersHigherPriority(t *testing.T) {
+	t.Parallel()
+
+	selector := &RoundRobinSelector{}
+	auths := []*Auth{
+		{ID: "a"},
+		{ID: "b", Metadata: map[string]any{"priority": float64(1)}},
+		{ID: "c", Attributes: map[string]string{"priority": "1"}},
+	}
+	for i, id := range []string{"b", "c", "b"} {
+		got, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		if got.ID != id {
+			t.Fatalf("Pick() #%d auth.ID = %q, want %q", i, got.ID, id)
+		}
+	}
+
+	auths[1].Disabled = true
+	auths[2].Disabled = true
+	got, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "a" {
+		t.Fatalf("Pick() auth.ID = %q, want the lower priority auth once the others are disabled", got.ID)
+	}
+}
+
+func TestWeightedSelectorPick_SmoothInterleaving(t *testing.T) {
+	t.Parallel()
+
+	selector := &WeightedSelector{}
+	auths := []*Auth{
+		{ID: "a", Attributes: map[string]string{"weight": "2"}},
+		{ID: "b"},
+	}
+
+	want := []string{"a", "b", "a", "a", "b", "a"}
+	for i, id := range want {
+		got, err := selector.Pick(context.Background(), "claude", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		if got.ID != id {
+			t.Fatalf("Pick() #%d auth.ID = %q, want %q", i, got.ID, id)
+		}
+	}
+}
+
+func TestLeastInFlightSelectorPick_PrefersIdleAuth(t *testing.T) {
+	t.Parallel()
+
+	selector := &LeastInFlightSelector{}
+	auths := []*Auth{
+		{ID: "b"},
+		{ID: "a"},
+	}
+
+	first, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if first.ID != "a" {
+		t.Fatalf("first Pick() auth.ID = %q, want %q", first.ID, "a")
+	}
+	second, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if second.ID != "b" {
+		t.Fatalf("second Pick() auth.ID = %q, want %q", second.ID, "b")
+	}
+
+	selector.Release("b", "m")
+	third, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if third.ID != "b" {
+		t.Fatalf("third Pick() auth.ID = %q, want %q", third.ID, "b")
+	}
+	if got := selector.InFlight("a", "m"); got != 1 {
+		t.Fatalf("InFlight(a) = %d, want 1", got)
+	}
+	if got := selector.InFlight("a", "other"); got != 0 {
+		t.Fatalf("InFlight(a, other) = %d, want 0", got)
+	}
+}
+
+func TestLeastInFlightSelector_ReleasedByManager(t *testing.T) {
+	manager, _ := newHookTestManager(t, "bad", "good")
+	selector := &LeastInFlightSelector{}
+	manager.SetSelector(selector)
+
+	if _, err := manager.Execute(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{Stream: true})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	for range chunks {
+	}
+
+	for _, id := range []string{"bad", "good"} {
+		if got := selector.InFlight(id, ""); got != 0 {
+			t.Fatalf("InFlight(%s) = %d after completion, want 0", id, got)
+		}
+	}
+}
+
+func TestQuotaAwareSelectorPick_PrefersHeadroom(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	selector := &QuotaAwareSelector{}
+	auths := []*Auth{
+		{ID: "a", ModelStates: map[string]*ModelState{"m": {Quota: QuotaState{Exceeded: true, NextRecoverAt: past, BackoffLevel: 3}}}},
+		{ID: "b", ModelStates: map[string]*ModelState{"m": {Quota: QuotaState{Exceeded: true, NextRecoverAt: past, BackoffLevel: 1}}}},
+		{ID: "c", ModelStates: map[string]*ModelState{"m": {Quota: QuotaState{Exceeded: true, NextRecoverAt: past.Add(-time.Minute), BackoffLevel: 1}}}},
+	}
+
+	got, err := selector.Pick(context.Background(), "antigravity", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "c" {
+		t.Fatalf("Pick() auth.ID = %q, want %q", got.ID, "c")
+	}
+
+	selector.ObserveResult(Result{AuthID: "c", Model: "m", Error: &Error{HTTPStatus: 429}})
+	if hits := selector.RecentQuotaHits("c", "m"); hits != 1 {
+		t.Fatalf("RecentQuotaHits(c) = %d, want 1", hits)
+	}
+	got, err = selector.Pick(context.Background(), "antigravity", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("Pick() after 429 auth.ID = %q, want %q", got.ID, "b")
+	}
+}
+
+func TestQuotaAwareSelectorPick_RotatesAmongEquals(t *testing.T) {
+	t.Parallel()
+
+	selector := &QuotaAwareSelector{}
+	auths := []*Auth{
+		{ID: "b"},
+		{ID: "a"},
+		{ID: "c", ModelStates: map[string]*ModelState{"m": {Quota: QuotaState{BackoffLevel: 2}}}},
+	}
+
+	want := []string{"a", "b", "a", "b"}
+	for i, id := range want {
+		got, err := selector.Pick(context.Background(), "antigravity", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		if got.ID != id {
+			t.Fatalf("Pick() #%d auth.ID = %q, want %q", i, got.ID, id)
+		}
+	}
+}
+
+func TestQuotaAwareSelector_ObservesManagerResults(t *testing.T) {
+	manager, _ := newHookTestManager(t, "bad", "good")
+	selector := &QuotaAwareSelector{}
+	manager.SetSelector(selector)
+
+	manager.MarkResult(context.Background(), Result{AuthID: "good", Provider: "hooktest", Model: "m", Error: &Error{HTTPStatus: 429}})
+	manager.MarkResult(context.Background(), Result{AuthID: "bad", Provider: "hooktest", Model: "m", Error: &Error{HTTPStatus: 500}})
+
+	if hits := selector.RecentQuotaHits("good", "m"); hits != 1 {
+		t.Fatalf("RecentQuotaHits(good) = %d, want 1", hits)
+	}
+	if hits := selector.RecentQuotaHits("bad", "m"); hits != 0 {
+		t.Fatalf("RecentQuotaHits(bad) = %d, want 0", hits)
+	}
+}
+
+func TestQuotaAwareSelector_DropsStaleHistories(t *testing.T) {
+	selector := &QuotaAwareSelector{}
+	stale := time.Now().Add(-2 * quotaHistoryWindow)
+	selector.limited = map[string][]time.Time{"removed|m": {stale}}
+	selector.lastSweep = stale
+
+	selector.ObserveResult(Result{AuthID: "live", Model: "m", Error: &Error{HTTPStatus: 429}})
+
+	if _, ok := selector.limited["removed|m"]; ok {
+		t.Fatal("expired history of a removed auth was kept")
+	}
+	if hits := selector.RecentQuotaHits("live", "m"); hits != 1 {
+		t.Fatalf("RecentQuotaHits(live) = %d, want 1", hits)
+	}
+}
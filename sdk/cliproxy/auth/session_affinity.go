@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultSessionAffinityTTL is how long an idle session stays pinned when no TTL is configured.
+	DefaultSessionAffinityTTL = time.Hour
+	// DefaultSessionAffinityMaxEntries bounds the pin table when no limit is configured.
+	DefaultSessionAffinityMaxEntries = 10000
+
+	// SessionKeyMetadataKey lets callers supply the session key explicitly through Options.Metadata.
+	SessionKeyMetadataKey = "session_key"
+)
+
+// SessionAffinityConfig controls sticky credential routing for multi-turn conversations.
+type SessionAffinityConfig struct {
+	// Enabled turns session pinning on.
+	Enabled bool
+	// Header optionally names an inbound request header carrying the session identifier.
+	Header string
+	// TTL is how long an idle pin is kept.
+	TTL time.Duration
+	// MaxEntries bounds the number of pinned sessions; least recently used pins are evicted first.
+	MaxEntries int
+}
+
+// SessionAffinityStats reports pin table counters.
+type SessionAffinityStats struct {
+	Enabled  bool   `json:"enabled"`
+	Sessions int    `json:"sessions"`
+	Hits     uint64 `json:"hits"`
+	Pins     uint64 `json:"pins"`
+	Breaks   uint64 `json:"breaks"`
+	Evicted  uint64 `json:"evicted"`
+}
+
+type sessionAffinity struct {
+	mu      sync.Mutex
+	cfg     SessionAffinityConfig
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	pins    uint64
+	breaks  uint64
+	evicted uint64
+}
+
+type sessionPin struct {
+	key     string
+	authID  string
+	expires time.Time
+}
+
+func newSessionAffinity() *sessionAffinity {
+	return &sessionAffinity{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// SetSessionAffinity updates the sticky routing configuration. Disabling it drops every pin.
+func (m *Manager) SetSessionAffinity(cfg SessionAffinityConfig) {
+	if m == nil || m.affinity == nil {
+		return
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultSessionAffinityTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultSessionAffinityMaxEntries
+	}
+	cfg.Header = strings.TrimSpace(cfg.Header)
+	a := m.affinity
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg = cfg
+	if !cfg.Enabled {
+		a.entries = make(map[string]*list.Element)
+		a.order.Init()
+		return
+	}
+	for len(a.entries) > cfg.MaxEntries {
+		a.evictOldestLocked()
+	}
+}
+
+// SessionAffinityStats returns a snapshot of the pin table counters.
+func (m *Manager) SessionAffinityStats() SessionAffinityStats {
+	if m == nil || m.affinity == nil {
+		return SessionAffinityStats{}
+	}
+	a := m.affinity
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return SessionAffinityStats{
+		Enabled:  a.cfg.Enabled,
+		Sessions: len(a.entries),
+		Hits:     a.hits,
+		Pins:     a.pins,
+		Breaks:   a.breaks,
+		Evicted:  a.evicted,
+	}
+}
+
+// ClearSessionAffinity removes every pin while keeping counters.
+func (m *Manager) ClearSessionAffinity() {
+	if m == nil || m.affinity == nil {
+		return
+	}
+	a := m.affinity
+	a.mu.Lock()
+	a.entries = make(map[string]*list.Element)
+	a.order.Init()
+	a.mu.Unlock()
+}
+
+// sessionKey derives the pin table key for a request, or "" when affinity does not apply.
+func (a *sessionAffinity) sessionKey(ctx context.Context, provider, model string, opts cliproxyexecutor.Options) string {
+	if a == nil {
+		return ""
+	}
+	a.mu.Lock()
+	enabled, header := a.cfg.Enabled, a.cfg.Header
+	a.mu.Unlock()
+	if !enabled {
+		return ""
+	}
+	session := sessionIdentifier(ctx, header, opts)
+	if session == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(session))
+	return provider + ":" + model + "|" + hex.EncodeToString(sum[:16])
+}
+
+// sessionIdentifier extracts a conversation identifier from, in order: explicit metadata,
+// the configured header, Claude metadata.user_id, OpenAI prompt_cache_key and user.
+func sessionIdentifier(ctx context.Context, header string, opts cliproxyexecutor.Options) string {
+	if raw, ok := opts.Metadata[SessionKeyMetadataKey].(string); ok && strings.TrimSpace(raw) != "" {
+		return strings.TrimSpace(raw)
+	}
+	if header != "" {
+		if value := strings.TrimSpace(opts.Headers.Get(header)); value != "" {
+			return value
+		}
+		if ctx != nil {
+			if ginCtx, ok := ctx.Value("gin").(interface{ GetHeader(string) string }); ok && ginCtx != nil {
+				if value := strings.TrimSpace(ginCtx.GetHeader(header)); value != "" {
+					return value
+				}
+			}
+		}
+	}
+	if len(opts.OriginalRequest) == 0 {
+		return ""
+	}
+	for _, path := range []string{"metadata.user_id", "prompt_cache_key", "user"} {
+		if value := strings.TrimSpace(gjson.GetBytes(opts.OriginalRequest, path).String()); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// lookup returns the pinned auth for key if it is still among the usable candidates.
+// A pin whose auth is gone, blocked for the model, or already tried is broken.
+func (a *sessionAffinity) lookup(key, model string, candidates []*Auth, now time.Time) *Auth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	elem, ok := a.entries[key]
+	if !ok {
+		return nil
+	}
+	pin := elem.Value.(*sessionPin)
+	if now.After(pin.expires) {
+		a.removeLocked(elem)
+		return nil
+	}
+	for _, candidate := range candidates {
+		if candidate.ID != pin.authID {
+			continue
+		}
+		if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); blocked {
+			break
+		}
+		pin.expires = now.Add(a.cfg.TTL)
+		a.order.MoveToFront(elem)
+		a.hits++
+		return candidate
+	}
+	a.removeLocked(elem)
+	a.breaks++
+	return nil
+}
+
+// pin records authID as the credential for key.
+func (a *sessionAffinity) pin(key, authID string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.cfg.Enabled {
+		return
+	}
+	if elem, ok := a.entries[key]; ok {
+		pin := elem.Value.(*sessionPin)
+		pin.authID = authID
+		pin.expires = now.Add(a.cfg.TTL)
+		a.order.MoveToFront(elem)
+		a.pins++
+		return
+	}
+	for len(a.entries) >= a.cfg.MaxEntries {
+		a.evictOldestLocked()
+	}
+	a.entries[key] = a.order.PushFront(&sessionPin{key: key, authID: authID, expires: now.Add(a.cfg.TTL)})
+	a.pins++
+}
+
+func (a *sessionAffinity) evictOldestLocked() {
+	if elem := a.order.Back(); elem != nil {
+		a.removeLocked(elem)
+		a.evicted++
+	}
+}
+
+func (a *sessionAffinity) removeLocked(elem *list.Element) {
+	pin := elem.Value.(*sessionPin)
+	delete(a.entries, pin.key)
+	a.order.Remove(elem)
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestSessionAffinity_PinsConversationUntilUnavailable(t *testing.T) {
+	manager, _ := newHookTestManager(t, "a", "b", "c")
+	manager.SetSelector(&RoundRobinSelector{})
+	manager.SetSessionAffinity(SessionAffinityConfig{Enabled: true})
+	hook := &recordingHook{}
+	manager.SetExecutionHooks(hook)
+
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"user_1_session_x"}}`)}
+	for i := 0; i < 4; i++ {
+		if _, err := manager.Execute(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{}, opts); err != nil {
+			t.Fatalf("Execute() #%d error = %v", i, err)
+		}
+	}
+	pinnedID := hook.before[0]
+	for i, id := range hook.before {
+		if id != pinnedID {
+			t.Fatalf("Execute() #%d used auth %q, want pinned %q", i, id, pinnedID)
+		}
+	}
+	stats := manager.SessionAffinityStats()
+	if stats.Sessions != 1 || stats.Pins != 1 || stats.Hits != 3 || stats.Breaks != 0 {
+		t.Fatalf("SessionAffinityStats() = %+v, want 1 session, 1 pin, 3 hits, 0 breaks", stats)
+	}
+
+	pinned, _ := manager.GetByID(pinnedID)
+	pinned.Disabled = true
+	if _, err := manager.Update(context.Background(), pinned); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := manager.Execute(context.Background(), []string{"hooktest"}, cliproxyexecutor.Request{}, opts); err != nil {
+		t.Fatalf("Execute() after disable error = %v", err)
+	}
+	if got := hook.before[len(hook.before)-1]; got == pinnedID {
+		t.Fatalf("Execute() after disable used disabled auth %q", got)
+	}
+	stats = manager.SessionAffinityStats()
+	if stats.Pins != 2 {
+		t.Fatalf("SessionAffinityStats().Pins = %d, want 2", stats.Pins)
+	}
+}
+
+func TestSessionAffinity_PinnedPicksCountInFlight(t *testing.T) {
+	manager, _ := newHookTestManager(t, "a", "b")
+	selector := &LeastInFlightSelector{}
+	manager.SetSelector(selector)
+	manager.SetSessionAffinity(SessionAffinityConfig{Enabled: true})
+
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"user":"session-1"}`)}
+	first, _, releaseFirst, err := manager.pickNext(context.Background(), "hooktest", "", opts, nil)
+	if err != nil {
+		t.Fatalf("pickNext() error = %v", err)
+	}
+	second, _, releaseSecond, err := manager.pickNext(context.Background(), "hooktest", "", opts, nil)
+	if err != nil {
+		t.Fatalf("pickNext() pinned error = %v", err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("pickNext() pinned auth = %q, want %q", second.ID, first.ID)
+	}
+	if got := selector.InFlight(first.ID, ""); got != 2 {
+		t.Fatalf("InFlight() = %d, want 2 including the pinned pick", got)
+	}
+	releaseFirst()
+	releaseSecond()
+	if got := selector.InFlight(first.ID, ""); got != 0 {
+		t.Fatalf("InFlight() after release = %d, want 0", got)
+	}
+}
+
+func TestSessionAffinity_KeySources(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		opts cliproxyexecutor.Options
+		want string
+	}{
+		{name: "claude", opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"u1"}}`)}, want: "u1"},
+		{name: "openai cache key", opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"k1","user":"u2"}`)}, want: "k1"},
+		{name: "openai user", opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"user":"u2"}`)}, want: "u2"},
+		{name: "header", opts: cliproxyexecutor.Options{Headers: map[string][]string{"X-Session-Id": {"h1"}}, OriginalRequest: []byte(`{"user":"u2"}`)}, want: "h1"},
+		{name: "metadata", opts: cliproxyexecutor.Options{Metadata: map[string]any{SessionKeyMetadataKey: "m1"}}, want: "m1"},
+		{name: "none", opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"model":"x"}`)}, want: ""},
+	}
+	for _, tc := range cases {
+		if got := sessionIdentifier(context.Background(), "X-Session-ID", tc.opts); got != tc.want {
+			t.Fatalf("%s: sessionIdentifier() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSessionAffinity_BoundedTable(t *testing.T) {
+	t.Parallel()
+
+	affinity := newSessionAffinity()
+	affinity.cfg = SessionAffinityConfig{Enabled: true, TTL: time.Minute, MaxEntries: 2}
+	now := time.Now()
+	affinity.pin("s1", "a", now)
+	affinity.pin("s2", "a", now)
+	affinity.pin("s3", "b", now)
+
+	candidates := []*Auth{{ID: "a"}, {ID: "b"}}
+	if got := affinity.lookup("s1", "", candidates, now); got != nil {
+		t.Fatalf("lookup(s1) = %q, want evicted", got.ID)
+	}
+	if got := affinity.lookup("s3", "", candidates, now); got == nil || got.ID != "b" {
+		t.Fatalf("lookup(s3) = %v, want b", got)
+	}
+	if got := affinity.lookup("s2", "", candidates, now.Add(2*time.Minute)); got != nil {
+		t.Fatalf("lookup(s2) after TTL = %q, want expired", got.ID)
+	}
+	if affinity.evicted != 1 {
+		t.Fatalf("evicted = %d, want 1", affinity.evicted)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ConnectTimeoutContextKey carries the upstream connect timeout (time.Duration) to executors.
+const ConnectTimeoutContextKey = "cliproxy.connect_timeout"
+
+// ResponseHeaderTimeoutContextKey carries the non-stream response header timeout (time.Duration)
+// to executors.
+const ResponseHeaderTimeoutContextKey = "cliproxy.response_header_timeout"
+
+// UpstreamTimeouts bounds how long an upstream call may stay silent. Zero disables a check.
+type UpstreamTimeouts struct {
+	// Connect bounds establishing the upstream connection.
+	Connect time.Duration
+	// FirstByte bounds the wait for the first streamed chunk, or for the response headers of a
+	// non-stream call; a stall fails over to the next credential.
+	FirstByte time.Duration
+	// Idle bounds the gap between streamed chunks; a stall ends the stream with an error chunk.
+	Idle time.Duration
+}
+
+// UpstreamTimeoutConfig holds global timeouts and per-provider overrides.
+type UpstreamTimeoutConfig struct {
+	Default UpstreamTimeouts
+	// Providers overrides Default per provider key; zero fields inherit the default.
+	Providers map[string]UpstreamTimeouts
+}
+
+// StallError reports an upstream that exceeded one of the configured timeouts.
+type StallError struct {
+	Provider string
+	// Phase is "connect", "first_byte" or "idle".
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e *StallError) Error() string {
+	switch e.Phase {
+	case "first_byte":
+		return fmt.Sprintf("upstream %s sent no data within %s", e.Provider, e.Timeout)
+	case "idle":
+		return fmt.Sprintf("upstream %s stream stalled for %s", e.Provider, e.Timeout)
+	default:
+		return fmt.Sprintf("upstream %s %s timeout after %s", e.Provider, e.Phase, e.Timeout)
+	}
+}
+
+// StatusCode implements cliproxyexecutor.StatusError so stalls cool the credential down like a 504.
+func (e *StallError) StatusCode() int { return http.StatusGatewayTimeout }
+
+// SetUpstreamTimeouts updates upstream timeout configuration.
+func (m *Manager) SetUpstreamTimeouts(cfg UpstreamTimeoutConfig) {
+	if m == nil {
+		return
+	}
+	providers := make(map[string]UpstreamTimeouts, len(cfg.Providers))
+	for key, value := range cfg.Providers {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "" {
+			providers[key] = value
+		}
+	}
+	cfg.Providers = providers
+	m.upstreamTimeouts.Store(cfg)
+}
+
+// timeoutsFor resolves the effective timeouts for a provider.
+func (m *Manager) timeoutsFor(provider string) UpstreamTimeouts {
+	if m == nil {
+		return UpstreamTimeouts{}
+	}
+	cfg, _ := m.upstreamTimeouts.Load().(UpstreamTimeoutConfig)
+	out := cfg.Default
+	override, ok := cfg.Providers[strings.ToLower(strings.TrimSpace(provider))]
+	if !ok {
+		return out
+	}
+	if override.Connect > 0 {
+		out.Connect = override.Connect
+	}
+	if override.FirstByte > 0 {
+		out.FirstByte = override.FirstByte
+	}
+	if override.Idle > 0 {
+		out.Idle = override.Idle
+	}
+	return out
+}
+
+// withConnectTimeout exposes the provider connect timeout to executor HTTP clients.
+func (m *Manager) withConnectTimeout(ctx context.Context, provider string) context.Context {
+	if timeout := m.timeoutsFor(provider).Connect; timeout > 0 {
+		return context.WithValue(ctx, ConnectTimeoutContextKey, timeout)
+	}
+	return ctx
+}
+
+// withNonStreamTimeouts adds the first-byte timeout as a response header timeout on top of the
+// connect timeout, since non-stream calls have no chunks to watch.
+func (m *Manager) withNonStreamTimeouts(ctx context.Context, provider string) context.Context {
+	ctx = m.withConnectTimeout(ctx, provider)
+	if timeout := m.timeoutsFor(provider).FirstByte; timeout > 0 {
+		return context.WithValue(ctx, ResponseHeaderTimeoutContextKey, timeout)
+	}
+	return ctx
+}
+
+// nextStreamChunk receives the next chunk, reporting stalled when nothing arrives within timeout.
+func nextStreamChunk(ctx context.Context, chunks <-chan cliproxyexecutor.StreamChunk, timeout time.Duration) (chunk cliproxyexecutor.StreamChunk, ok, stalled bool) {
+	if timeout <= 0 {
+		chunk, ok = <-chunks
+		return chunk, ok, false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case chunk, ok = <-chunks:
+		return chunk, ok, false
+	case <-ctx.Done():
+		// Give the executor the rest of the timeout to close the channel after observing
+		// cancellation; one that ignores it is reported as stalled and drained in the background.
+		select {
+		case chunk, ok = <-chunks:
+			return chunk, ok, false
+		case <-timer.C:
+			return chunk, false, true
+		}
+	case <-timer.C:
+		return chunk, false, true
+	}
+}
+
+// drainStream discards remaining chunks so an abandoned executor goroutine can exit.
+func drainStream(chunks <-chan cliproxyexecutor.StreamChunk) {
+	for range chunks {
+	}
+}
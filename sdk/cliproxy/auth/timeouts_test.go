@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// stallTestExecutor never answers for the "a-silent" auth and stalls after one chunk for "partial".
+type stallTestExecutor struct{}
+
+func (stallTestExecutor) Identifier() string { return "stalltest" }
+
+func (stallTestExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (stallTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	ch := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(ch)
+		switch auth.ID {
+		case "a-silent":
+		case "partial":
+			ch <- cliproxyexecutor.StreamChunk{Payload: []byte("partial")}
+		default:
+			ch <- cliproxyexecutor.StreamChunk{Payload: []byte("ok")}
+			return
+		}
+		<-ctx.Done()
+	}()
+	return ch, nil
+}
+
+func (stallTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (stallTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func newStallTestManager(t *testing.T, timeouts UpstreamTimeouts, ids ...string) *Manager {
+	t.Helper()
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	manager.RegisterExecutor(stallTestExecutor{})
+	manager.SetUpstreamTimeouts(UpstreamTimeoutConfig{Providers: map[string]UpstreamTimeouts{"StallTest": timeouts}})
+	for _, id := range ids {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "stalltest"}); err != nil {
+			t.Fatalf("Register(%s): %v", id, err)
+		}
+	}
+	return manager
+}
+
+func collectStream(t *testing.T, chunks <-chan cliproxyexecutor.StreamChunk) ([]string, error) {
+	t.Helper()
+	var payloads []string
+	var streamErr error
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return payloads, streamErr
+			}
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			if len(chunk.Payload) > 0 {
+				payloads = append(payloads, string(chunk.Payload))
+			}
+		case <-timeout:
+			t.Fatal("stream did not finish")
+		}
+	}
+}
+
+func TestExecuteStream_FirstByteStallRotatesCredential(t *testing.T) {
+	manager := newStallTestManager(t, UpstreamTimeouts{FirstByte: 50 * time.Millisecond}, "a-silent", "b-good")
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"stalltest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	payloads, streamErr := collectStream(t, chunks)
+	if streamErr != nil {
+		t.Fatalf("stream error = %v", streamErr)
+	}
+	if len(payloads) != 1 || payloads[0] != "ok" {
+		t.Fatalf("payloads = %v, want [ok]", payloads)
+	}
+	silent, _ := manager.GetByID("a-silent")
+	if silent == nil || silent.LastError == nil || silent.LastError.HTTPStatus != 504 {
+		t.Fatalf("stalled auth not marked with 504: %+v", silent)
+	}
+}
+
+func TestExecuteStream_IdleStallEndsWithError(t *testing.T) {
+	manager := newStallTestManager(t, UpstreamTimeouts{Idle: 50 * time.Millisecond}, "partial")
+
+	chunks, err := manager.ExecuteStream(context.Background(), []string{"stalltest"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	payloads, streamErr := collectStream(t, chunks)
+	if len(payloads) != 1 || payloads[0] != "partial" {
+		t.Fatalf("payloads = %v, want [partial]", payloads)
+	}
+	var stall *StallError
+	if !errors.As(streamErr, &stall) || stall.Phase != "idle" {
+		t.Fatalf("stream error = %v, want idle StallError", streamErr)
+	}
+}
+
+func TestTimeoutsFor_ProviderOverrideInheritsDefault(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetUpstreamTimeouts(UpstreamTimeoutConfig{
+		Default:   UpstreamTimeouts{Connect: time.Second, Idle: time.Minute},
+		Providers: map[string]UpstreamTimeouts{"claude": {Idle: 2 * time.Minute}},
+	})
+	got := manager.timeoutsFor("claude")
+	if got.Connect != time.Second || got.Idle != 2*time.Minute {
+		t.Fatalf("timeoutsFor(claude) = %+v", got)
+	}
+	if got = manager.timeoutsFor("gemini"); got.Idle != time.Minute {
+		t.Fatalf("timeoutsFor(gemini) = %+v", got)
+	}
+}
+
+func TestNextStreamChunk_BoundsDrainAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chunks := make(chan cliproxyexecutor.StreamChunk)
+	start := time.Now()
+	_, ok, stalled := nextStreamChunk(ctx, chunks, 50*time.Millisecond)
+	if ok || !stalled {
+		t.Fatalf("nextStreamChunk() ok=%v stalled=%v, want a stall for an executor that never closes", ok, stalled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("drain took %s", elapsed)
+	}
+}
+
+func TestWithNonStreamTimeouts_SetsResponseHeaderTimeout(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetUpstreamTimeouts(UpstreamTimeoutConfig{Default: UpstreamTimeouts{Connect: time.Second, FirstByte: 3 * time.Second}})
+	ctx := manager.withNonStreamTimeouts(context.Background(), "gemini")
+	if got, _ := ctx.Value(ConnectTimeoutContextKey).(time.Duration); got != time.Second {
+		t.Fatalf("connect timeout = %v", got)
+	}
+	if got, _ := ctx.Value(ResponseHeaderTimeoutContextKey).(time.Duration); got != 3*time.Second {
+		t.Fatalf("response header timeout = %v", got)
+	}
+}
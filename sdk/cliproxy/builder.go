@@ -8,9 +8,11 @@ import (
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/pipeline"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 )
 
@@ -47,6 +49,9 @@ type Builder struct {
 
 	// serverOptions contains additional server configuration options.
 	serverOptions []api.ServerOption
+
+	// pipelineHooks run around every executor invocation.
+	pipelineHooks []pipeline.Hook
 }
 
 // Hooks allows callers to plug into service lifecycle stages.
@@ -143,6 +148,17 @@ func (b *Builder) WithServerOptions(opts ...api.ServerOption) *Builder {
 	return b
 }
 
+// WithPipelineHooks registers middleware hooks invoked around every executor call,
+// including credential rotation retries and streaming bootstrap retries.
+func (b *Builder) WithPipelineHooks(hooks ...pipeline.Hook) *Builder {
+	for _, hook := range hooks {
+		if hook != nil {
+			b.pipelineHooks = append(b.pipelineHooks, hook)
+		}
+	}
+	return b
+}
+
 // WithLocalManagementPassword configures a password that is only accepted from localhost management requests.
 func (b *Builder) WithLocalManagementPassword(password string) *Builder {
 	if password == "" {
@@ -207,6 +223,12 @@ func (b *Builder) Build() (*Service, error) {
 		switch strategy {
 		case "fill-first", "fillfirst", "ff":
 			selector = &coreauth.FillFirstSelector{}
+		case "weighted", "wrr":
+			selector = &coreauth.WeightedSelector{}
+		case "least-in-flight", "leastinflight", "least-requests", "lif":
+			selector = &coreauth.LeastInFlightSelector{}
+		case "quota-aware", "quotaaware", "quota":
+			selector = &coreauth.QuotaAwareSelector{}
 		default:
 			selector = &coreauth.RoundRobinSelector{}
 		}
@@ -216,6 +238,10 @@ func (b *Builder) Build() (*Service, error) {
 	// Attach a default RoundTripper provider so providers can opt-in per-auth transports.
 	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
 	coreManager.SetOAuthModelMappings(b.cfg.OAuthModelMappings)
+	for _, hook := range b.pipelineHooks {
+		coreManager.AddExecutionHook(pipeline.Adapt(hook))
+	}
+	coreManager.AddExecutionHook(newLiveEventHook(events.Default()))
 
 	service := &Service{
 		cfg:            b.cfg,
@@ -228,6 +254,7 @@ func (b *Builder) Build() (*Service, error) {
 		accessManager:  accessManager,
 		coreManager:    coreManager,
 		serverOptions:  append([]api.ServerOption(nil), b.serverOptions...),
+		discovery:      newModelDiscovery(),
 	}
 	return service, nil
 }
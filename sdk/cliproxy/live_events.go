@@ -0,0 +1,107 @@
+package cliproxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// liveEventHook publishes conductor-level request events (credential selection, first byte,
+// failed attempts and cooldown retries) to the live request stream.
+type liveEventHook struct {
+	bus      *events.Bus
+	attempts sync.Map // *coreauth.ExecutionState -> *liveAttempt
+}
+
+type liveAttempt struct {
+	started   time.Time
+	firstByte sync.Once
+}
+
+func newLiveEventHook(bus *events.Bus) *liveEventHook {
+	return &liveEventHook{bus: bus}
+}
+
+// BeforeExecute implements coreauth.ExecutionHook.
+func (h *liveEventHook) BeforeExecute(ctx context.Context, state *coreauth.ExecutionState) {
+	if !h.bus.Active() || state == nil {
+		return
+	}
+	h.attempts.Store(state, &liveAttempt{started: time.Now()})
+	h.bus.Publish(h.event(ctx, events.TypeAuthSelected, state))
+}
+
+// AfterExecute implements coreauth.ExecutionHook.
+func (h *liveEventHook) AfterExecute(ctx context.Context, state *coreauth.ExecutionState, _ cliproxyexecutor.Response, err error) {
+	value, ok := h.attempts.LoadAndDelete(state)
+	if !ok || err == nil {
+		return
+	}
+	event := h.event(ctx, events.TypeAttemptFailed, state)
+	event.LatencyMs = time.Since(value.(*liveAttempt).started).Milliseconds()
+	event.Error = err.Error()
+	if se, okStatus := err.(interface{ StatusCode() int }); okStatus && se != nil {
+		event.Status = se.StatusCode()
+	}
+	h.bus.Publish(event)
+}
+
+// OnStreamChunk implements coreauth.ExecutionHook.
+func (h *liveEventHook) OnStreamChunk(ctx context.Context, state *coreauth.ExecutionState, chunk cliproxyexecutor.StreamChunk) {
+	if chunk.Err != nil {
+		return
+	}
+	value, ok := h.attempts.Load(state)
+	if !ok {
+		return
+	}
+	attempt := value.(*liveAttempt)
+	attempt.firstByte.Do(func() {
+		event := h.event(ctx, events.TypeFirstByte, state)
+		event.LatencyMs = time.Since(attempt.started).Milliseconds()
+		h.bus.Publish(event)
+	})
+}
+
+// OnRetry implements coreauth.RetryObserver.
+func (h *liveEventHook) OnRetry(ctx context.Context, model string, _ int, wait time.Duration) {
+	if !h.bus.Active() {
+		return
+	}
+	h.bus.Publish(events.Event{
+		Type:      events.TypeRetry,
+		RequestID: logging.GetRequestID(ctx),
+		Model:     model,
+		APIKey:    liveEventAPIKey(ctx),
+		WaitMs:    wait.Milliseconds(),
+	})
+}
+
+func (h *liveEventHook) event(ctx context.Context, eventType string, state *coreauth.ExecutionState) events.Event {
+	event := events.Event{
+		Type:      eventType,
+		RequestID: logging.GetRequestID(ctx),
+		Model:     state.Request.Model,
+		APIKey:    liveEventAPIKey(ctx),
+		Stream:    state.Options.Stream,
+		Provider:  state.Provider,
+	}
+	if state.Auth != nil {
+		event.AuthID = state.Auth.ID
+		event.AuthIndex = state.Auth.EnsureIndex()
+	}
+	return event
+}
+
+func liveEventAPIKey(ctx context.Context) string {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		return ginCtx.GetString("apiKey")
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package cliproxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/events"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type liveEventTestError struct{}
+
+func (liveEventTestError) Error() string   { return "rate limited" }
+func (liveEventTestError) StatusCode() int { return http.StatusTooManyRequests }
+
+func TestLiveEventHookPublishesAttemptLifecycle(t *testing.T) {
+	bus := events.NewBus()
+	stream, stop := bus.Subscribe(events.Filter{}, 16)
+	defer stop()
+	hook := newLiveEventHook(bus)
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	state := &coreauth.ExecutionState{
+		Provider: "claude",
+		Auth:     &coreauth.Auth{ID: "auth-1", Provider: "claude"},
+		Request:  cliproxyexecutor.Request{Model: "claude-sonnet-4"},
+		Options:  cliproxyexecutor.Options{Stream: true},
+	}
+	hook.BeforeExecute(ctx, state)
+	hook.OnStreamChunk(ctx, state, cliproxyexecutor.StreamChunk{Payload: []byte("a")})
+	hook.OnStreamChunk(ctx, state, cliproxyexecutor.StreamChunk{Payload: []byte("b")})
+	hook.AfterExecute(ctx, state, cliproxyexecutor.Response{}, liveEventTestError{})
+
+	want := []string{events.TypeAuthSelected, events.TypeFirstByte, events.TypeAttemptFailed}
+	if len(stream) != len(want) {
+		t.Fatalf("got %d events, want %d", len(stream), len(want))
+	}
+	for _, eventType := range want {
+		event := <-stream
+		if event.Type != eventType || event.RequestID != "req-1" || event.AuthID != "auth-1" || event.Provider != "claude" {
+			t.Fatalf("event = %+v, want type %s", event, eventType)
+		}
+		if eventType == events.TypeAttemptFailed && (event.Status != http.StatusTooManyRequests || event.Error == "") {
+			t.Fatalf("failed attempt event = %+v", event)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package cliproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// modelDiscoveryTimeout bounds a single upstream /models request.
+const modelDiscoveryTimeout = 30 * time.Second
+
+// modelDiscovery holds the models discovered for OpenAI-compatible providers that enable
+// model-discovery, keyed by lower-cased provider name.
+type modelDiscovery struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	signature string
+	models    map[string][]config.OpenAICompatibilityModel
+}
+
+func newModelDiscovery() *modelDiscovery {
+	return &modelDiscovery{models: make(map[string][]config.OpenAICompatibilityModel)}
+}
+
+// applyModelDiscoveryConfig (re)starts one discovery loop per provider with model-discovery
+// enabled. Loops are left running when the relevant settings did not change.
+func (s *Service) applyModelDiscoveryConfig(cfg *config.Config) {
+	if s == nil || s.discovery == nil || cfg == nil {
+		return
+	}
+	enabled := make([]config.OpenAICompatibility, 0)
+	for i := range cfg.OpenAICompatibility {
+		if compat := cfg.OpenAICompatibility[i]; compat.ModelDiscovery.Enabled && compat.Name != "" {
+			enabled = append(enabled, compat)
+		}
+	}
+	signature := ""
+	if len(enabled) > 0 {
+		if raw, errMarshal := json.Marshal(enabled); errMarshal == nil {
+			signature = string(raw)
+		}
+	}
+
+	d := s.discovery
+	d.mu.Lock()
+	if signature == d.signature && (d.cancel != nil) == (len(enabled) > 0) {
+		d.mu.Unlock()
+		return
+	}
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
+	d.signature = signature
+	active := make(map[string]struct{}, len(enabled))
+	for _, compat := range enabled {
+		active[strings.ToLower(compat.Name)] = struct{}{}
+	}
+	var dropped []string
+	for key := range d.models {
+		if _, ok := active[key]; !ok {
+			delete(d.models, key)
+			dropped = append(dropped, key)
+		}
+	}
+	if len(enabled) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		d.cancel = cancel
+		for _, compat := range enabled {
+			go s.runModelDiscovery(ctx, compat.Name, compat.ModelDiscovery.Interval())
+		}
+	}
+	d.mu.Unlock()
+
+	for _, name := range dropped {
+		s.reregisterCompatModels(name)
+	}
+	if len(enabled) > 0 {
+		log.Infof("model discovery started for %d openai-compatibility provider(s)", len(enabled))
+	}
+}
+
+// stopModelDiscovery cancels all discovery loops.
+func (s *Service) stopModelDiscovery() {
+	if s == nil || s.discovery == nil {
+		return
+	}
+	s.discovery.mu.Lock()
+	if s.discovery.cancel != nil {
+		s.discovery.cancel()
+		s.discovery.cancel = nil
+	}
+	s.discovery.signature = ""
+	s.discovery.mu.Unlock()
+}
+
+func (s *Service) runModelDiscovery(ctx context.Context, name string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.discoverCompatModels(ctx, name)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// discoverCompatModels refreshes the discovered models of one provider and re-registers its
+// credentials when the list changed. A failed refresh keeps the previously discovered models.
+func (s *Service) discoverCompatModels(ctx context.Context, name string) {
+	s.cfgMu.RLock()
+	cfg := s.cfg
+	s.cfgMu.RUnlock()
+	compat := findOpenAICompat(cfg, name)
+	if compat == nil || !compat.ModelDiscovery.Enabled {
+		return
+	}
+	ids, err := fetchDiscoveryModelIDs(ctx, cfg, compat)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Warnf("model discovery: %s: failed to list upstream models: %v", compat.Name, err)
+		}
+		return
+	}
+	models := selectDiscoveredModels(compat.ModelDiscovery, ids)
+
+	key := strings.ToLower(compat.Name)
+	d := s.discovery
+	d.mu.Lock()
+	if ctx.Err() != nil {
+		d.mu.Unlock()
+		return
+	}
+	previous, seen := d.models[key]
+	changes := diff.DiffOpenAICompatModels(previous, models)
+	if seen && len(changes) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	d.models[key] = models
+	d.mu.Unlock()
+
+	if len(changes) > 0 {
+		log.Infof("model discovery: %s: %s", compat.Name, strings.Join(changes, "; "))
+	}
+	s.reregisterCompatModels(compat.Name)
+}
+
+// discoveredModels returns the models last discovered for the provider.
+func (s *Service) discoveredModels(name string) []config.OpenAICompatibilityModel {
+	if s == nil || s.discovery == nil {
+		return nil
+	}
+	s.discovery.mu.Lock()
+	defer s.discovery.mu.Unlock()
+	return s.discovery.models[strings.ToLower(name)]
+}
+
+// reregisterCompatModels refreshes the registry entries of every credential of the provider.
+func (s *Service) reregisterCompatModels(name string) {
+	if s == nil || s.coreManager == nil {
+		return
+	}
+	for _, auth := range s.coreManager.List() {
+		if auth == nil || auth.Disabled {
+			continue
+		}
+		if _, compatName, ok := openAICompatInfoFromAuth(auth); ok && strings.EqualFold(compatName, name) {
+			s.registerModelsForAuth(auth)
+		}
+	}
+}
+
+func findOpenAICompat(cfg *config.Config, name string) *config.OpenAICompatibility {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.OpenAICompatibility {
+		if strings.EqualFold(cfg.OpenAICompatibility[i].Name, name) {
+			return &cfg.OpenAICompatibility[i]
+		}
+	}
+	return nil
+}
+
+// fetchDiscoveryModelIDs lists the upstream models, trying each configured API key in turn.
+func fetchDiscoveryModelIDs(ctx context.Context, cfg *config.Config, compat *config.OpenAICompatibility) ([]string, error) {
+	entries := compat.APIKeyEntries
+	if len(entries) == 0 {
+		entries = []config.OpenAICompatibilityAPIKey{{}}
+	}
+	var lastErr error
+	for _, entry := range entries {
+		attrs := map[string]string{
+			"base_url":     compat.BaseURL,
+			"compat_name":  compat.Name,
+			"provider_key": strings.ToLower(compat.Name),
+		}
+		if key := strings.TrimSpace(entry.APIKey); key != "" {
+			attrs["api_key"] = key
+		}
+		for name, value := range compat.Headers {
+			attrs["header:"+name] = value
+		}
+		auth := &coreauth.Auth{
+			Provider:   strings.ToLower(compat.Name),
+			ProxyURL:   strings.TrimSpace(entry.ProxyURL),
+			Attributes: attrs,
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, modelDiscoveryTimeout)
+		ids, err := executor.FetchOpenAICompatModels(fetchCtx, auth, cfg)
+		cancel()
+		if err == nil {
+			return ids, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, lastErr
+}
+
+// selectDiscoveredModels applies the include/exclude patterns and alias template to the
+// upstream model IDs.
+func selectDiscoveredModels(discovery config.OpenAICompatibilityModelDiscovery, ids []string) []config.OpenAICompatibilityModel {
+	seen := make(map[string]struct{}, len(ids))
+	out := make([]config.OpenAICompatibilityModel, 0, len(ids))
+	for _, id := range ids {
+		lower := strings.ToLower(id)
+		if _, dup := seen[lower]; dup {
+			continue
+		}
+		seen[lower] = struct{}{}
+		if len(discovery.Include) > 0 && !matchAnyWildcard(discovery.Include, lower) {
+			continue
+		}
+		if matchAnyWildcard(discovery.Exclude, lower) {
+			continue
+		}
+		model := config.OpenAICompatibilityModel{Name: id}
+		if alias := discovery.Alias(id); alias != id {
+			model.Alias = alias
+		}
+		out = append(out, model)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func matchAnyWildcard(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchWildcard(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendDiscoveredModels adds discovered models to the configured ones. Configured entries win:
+// a discovered model is skipped when its upstream name or client-facing ID is already configured.
+func appendDiscoveredModels(ms []*ModelInfo, compat *config.OpenAICompatibility, discovered []config.OpenAICompatibilityModel) []*ModelInfo {
+	if len(discovered) == 0 {
+		return ms
+	}
+	taken := make(map[string]struct{}, len(ms)+len(compat.Models))
+	for _, model := range ms {
+		taken[strings.ToLower(model.ID)] = struct{}{}
+	}
+	for _, model := range compat.Models {
+		if name := strings.TrimSpace(model.Name); name != "" {
+			taken[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	now := time.Now().Unix()
+	for _, model := range discovered {
+		modelID := model.Alias
+		if modelID == "" {
+			modelID = model.Name
+		}
+		if _, ok := taken[strings.ToLower(modelID)]; ok {
+			continue
+		}
+		if _, ok := taken[strings.ToLower(model.Name)]; ok {
+			continue
+		}
+		taken[strings.ToLower(modelID)] = struct{}{}
+		ms = append(ms, &ModelInfo{
+			ID:          modelID,
+			Object:      "model",
+			Created:     now,
+			OwnedBy:     compat.Name,
+			Type:        "openai-compatibility",
+			DisplayName: modelID,
+		})
+	}
+	return ms
+}
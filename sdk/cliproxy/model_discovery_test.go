@@ -0,0 +1,80 @@
+package cliproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestSelectDiscoveredModels(t *testing.T) {
+	discovery := config.OpenAICompatibilityModelDiscovery{
+		Include:       []string{"qwen/*", "gpt-*"},
+		Exclude:       []string{"*:free"},
+		AliasTemplate: "relay-{name}",
+	}
+	got := selectDiscoveredModels(discovery, []string{"qwen/qwen3", "qwen/qwen3:free", "gpt-4o", "GPT-4o", "llama-3"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 models, got %+v", got)
+	}
+	if got[0].Name != "gpt-4o" || got[0].Alias != "relay-gpt-4o" || got[1].Name != "qwen/qwen3" {
+		t.Fatalf("unexpected models: %+v", got)
+	}
+}
+
+func TestDiscoverCompatModels_RegistersMergedModels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"kimi-k2"},{"id":"glm-4.6"},{"id":"embed-small"}]}`))
+	}))
+	defer upstream.Close()
+
+	compat := config.OpenAICompatibility{
+		Name:          "relay",
+		BaseURL:       upstream.URL + "/v1",
+		APIKeyEntries: []config.OpenAICompatibilityAPIKey{{APIKey: "sk-test"}},
+		Models:        []config.OpenAICompatibilityModel{{Name: "kimi-k2", Alias: "kimi"}},
+		ModelDiscovery: config.OpenAICompatibilityModelDiscovery{
+			Enabled: true,
+			Exclude: []string{"embed-*"},
+		},
+	}
+	service := &Service{
+		cfg:         &config.Config{OpenAICompatibility: []config.OpenAICompatibility{compat}},
+		coreManager: coreauth.NewManager(nil, nil, nil),
+		discovery:   newModelDiscovery(),
+	}
+	auth := &coreauth.Auth{
+		ID:         "relay-auth",
+		Provider:   "relay",
+		Attributes: map[string]string{"compat_name": "relay", "provider_key": "relay", "base_url": compat.BaseURL, "api_key": "sk-test"},
+	}
+	if _, err := service.coreManager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	t.Cleanup(func() { GlobalModelRegistry().UnregisterClient(auth.ID) })
+
+	service.discoverCompatModels(context.Background(), "relay")
+
+	discovered := service.discoveredModels("relay")
+	if len(discovered) != 2 {
+		t.Fatalf("discovered = %+v", discovered)
+	}
+	registry := GlobalModelRegistry()
+	for _, model := range []string{"kimi", "glm-4.6"} {
+		if !registry.ClientSupportsModel(auth.ID, model) {
+			t.Fatalf("model %q not registered", model)
+		}
+	}
+	for _, model := range []string{"kimi-k2", "embed-small"} {
+		if registry.ClientSupportsModel(auth.ID, model) {
+			t.Fatalf("model %q registered; configured aliases and excludes must win", model)
+		}
+	}
+}
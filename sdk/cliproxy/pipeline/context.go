@@ -62,3 +62,47 @@ func (h HookFunc) OnStreamChunk(ctx context.Context, execCtx *Context, chunk cli
 type RoundTripperProvider interface {
 	RoundTripperFor(auth *cliproxyauth.Auth) http.RoundTripper
 }
+
+// Adapt converts a Hook into a core auth ExecutionHook so it can be registered on
+// a cliproxyauth.Manager and invoked around every executor call.
+func Adapt(hook Hook) cliproxyauth.ExecutionHook {
+	if hook == nil {
+		return nil
+	}
+	return hookAdapter{hook: hook}
+}
+
+type hookAdapter struct {
+	hook Hook
+}
+
+// BeforeExecute implements cliproxyauth.ExecutionHook.
+func (a hookAdapter) BeforeExecute(ctx context.Context, state *cliproxyauth.ExecutionState) {
+	execCtx := contextFromState(state)
+	a.hook.BeforeExecute(ctx, execCtx)
+	state.Request = execCtx.Request
+	state.Options = execCtx.Options
+	state.HTTPClient = execCtx.HTTPClient
+	if execCtx.Auth != nil {
+		state.Auth = execCtx.Auth
+	}
+}
+
+// AfterExecute implements cliproxyauth.ExecutionHook.
+func (a hookAdapter) AfterExecute(ctx context.Context, state *cliproxyauth.ExecutionState, resp cliproxyexecutor.Response, err error) {
+	a.hook.AfterExecute(ctx, contextFromState(state), resp, err)
+}
+
+// OnStreamChunk implements cliproxyauth.ExecutionHook.
+func (a hookAdapter) OnStreamChunk(ctx context.Context, state *cliproxyauth.ExecutionState, chunk cliproxyexecutor.StreamChunk) {
+	a.hook.OnStreamChunk(ctx, contextFromState(state), chunk)
+}
+
+func contextFromState(state *cliproxyauth.ExecutionState) *Context {
+	return &Context{
+		Request:    state.Request,
+		Options:    state.Options,
+		Auth:       state.Auth,
+		HTTPClient: state.HTTPClient,
+	}
+}
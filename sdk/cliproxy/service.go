@@ -12,10 +12,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
-	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	internalusage "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/wsrelay"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -86,6 +88,9 @@ type Service struct {
 
 	// wsGateway manages websocket Gemini providers.
 	wsGateway *wsrelay.Manager
+
+	// discovery tracks models discovered from OpenAI-compatible upstreams.
+	discovery *modelDiscovery
 }
 
 // RegisterUsagePlugin registers a usage plugin on the global usage manager.
@@ -317,6 +322,68 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
 }
 
+func (s *Service) applySessionAffinityConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	affinity := cfg.Routing.SessionAffinity
+	s.coreManager.SetSessionAffinity(coreauth.SessionAffinityConfig{
+		Enabled:    affinity.Enabled,
+		Header:     affinity.Header,
+		TTL:        time.Duration(affinity.TTLSeconds) * time.Second,
+		MaxEntries: affinity.MaxEntries,
+	})
+}
+
+func (s *Service) applyUpstreamTimeoutConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	toTimeouts := func(t config.UpstreamTimeouts) coreauth.UpstreamTimeouts {
+		return coreauth.UpstreamTimeouts{
+			Connect:   time.Duration(t.ConnectSeconds) * time.Second,
+			FirstByte: time.Duration(t.FirstByteSeconds) * time.Second,
+			Idle:      time.Duration(t.IdleSeconds) * time.Second,
+		}
+	}
+	timeouts := coreauth.UpstreamTimeoutConfig{Default: toTimeouts(cfg.UpstreamTimeouts.UpstreamTimeouts)}
+	if len(cfg.UpstreamTimeouts.Providers) > 0 {
+		timeouts.Providers = make(map[string]coreauth.UpstreamTimeouts, len(cfg.UpstreamTimeouts.Providers))
+		for provider, values := range cfg.UpstreamTimeouts.Providers {
+			timeouts.Providers[provider] = toTimeouts(values)
+		}
+	}
+	s.coreManager.SetUpstreamTimeouts(timeouts)
+}
+
+func (s *Service) applyHealthCheckConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	s.coreManager.SetHealthCheck(coreauth.HealthCheckConfig{
+		Enabled:     cfg.HealthCheck.Enabled,
+		Interval:    time.Duration(cfg.HealthCheck.IntervalSeconds) * time.Second,
+		Timeout:     time.Duration(cfg.HealthCheck.TimeoutSeconds) * time.Second,
+		Concurrency: cfg.HealthCheck.Concurrency,
+		Providers:   cfg.HealthCheck.Providers,
+	})
+}
+
+func (s *Service) applyModelFallbackConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	chains := make(map[string][]coreauth.ModelFallback, len(cfg.Routing.Fallbacks))
+	for model, targets := range cfg.Routing.Fallbacks {
+		chain := make([]coreauth.ModelFallback, 0, len(targets))
+		for _, target := range targets {
+			chain = append(chain, coreauth.ModelFallback{Model: target.Model, Provider: target.Provider})
+		}
+		chains[model] = chain
+	}
+	s.coreManager.SetModelFallbacks(chains)
+}
+
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
 	if a == nil {
 		return "", "", false
@@ -354,7 +421,7 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 		if compatProviderKey == "" {
 			compatProviderKey = "openai-compatibility"
 		}
-		s.coreManager.RegisterExecutor(executor.NewOpenAICompatExecutor(compatProviderKey, s.cfg))
+		s.coreManager.RegisterExecutor(executor.NewOpenAICompatExecutor(compatProviderKey, s.cfg).SetDiscoveredModels(s.discoveredModels))
 		return
 	}
 	switch strings.ToLower(a.Provider) {
@@ -384,7 +451,7 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 		if providerKey == "" {
 			providerKey = "openai-compatibility"
 		}
-		s.coreManager.RegisterExecutor(executor.NewOpenAICompatExecutor(providerKey, s.cfg))
+		s.coreManager.RegisterExecutor(executor.NewOpenAICompatExecutor(providerKey, s.cfg).SetDiscoveredModels(s.discoveredModels))
 	}
 }
 
@@ -431,6 +498,15 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	s.applyRetryConfig(s.cfg)
+	s.applySessionAffinityConfig(s.cfg)
+	s.applyUpstreamTimeoutConfig(s.cfg)
+	s.applyModelFallbackConfig(s.cfg)
+
+	if s.coreManager != nil {
+		s.coreManager.AddExecutionHook(metrics.Hook())
+		metrics.SetAuthSource(s.coreManager)
+		policy.Default().SetPrefixResolver(s.coreManager.HasModelPrefix)
+	}
 
 	if s.coreManager != nil {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
@@ -458,12 +534,14 @@ func (s *Service) Run(ctx context.Context) error {
 
 	// handlers no longer depend on legacy clients; pass nil slice initially
 	s.server = api.NewServer(s.cfg, s.coreManager, s.accessManager, s.configPath, s.serverOptions...)
+	s.server.SetModelRegistrar(s.registerModelsForAuth)
 
 	if s.authManager == nil {
 		s.authManager = newDefaultAuthManager()
 	}
 
 	s.ensureWebsocketGateway()
+	metrics.SetSessionSource(s.wsGateway)
 	if s.server != nil && s.wsGateway != nil {
 		s.server.AttachWebsocketRoute(s.wsGateway.Path(), s.wsGateway.Handler())
 		s.server.SetWebsocketAuthChangeHandler(func(oldEnabled, newEnabled bool) {
@@ -527,6 +605,12 @@ func (s *Service) Run(ctx context.Context) error {
 			switch strategy {
 			case "fill-first", "fillfirst", "ff":
 				return "fill-first"
+			case "weighted", "wrr":
+				return "weighted"
+			case "least-in-flight", "leastinflight", "least-requests", "lif":
+				return "least-in-flight"
+			case "quota-aware", "quotaaware", "quota":
+				return "quota-aware"
 			default:
 				return "round-robin"
 			}
@@ -538,6 +622,12 @@ func (s *Service) Run(ctx context.Context) error {
 			switch nextStrategy {
 			case "fill-first":
 				selector = &coreauth.FillFirstSelector{}
+			case "weighted":
+				selector = &coreauth.WeightedSelector{}
+			case "least-in-flight":
+				selector = &coreauth.LeastInFlightSelector{}
+			case "quota-aware":
+				selector = &coreauth.QuotaAwareSelector{}
 			default:
 				selector = &coreauth.RoundRobinSelector{}
 			}
@@ -546,6 +636,10 @@ func (s *Service) Run(ctx context.Context) error {
 		}
 
 		s.applyRetryConfig(newCfg)
+		s.applySessionAffinityConfig(newCfg)
+		s.applyUpstreamTimeoutConfig(newCfg)
+		s.applyModelFallbackConfig(newCfg)
+		s.applyHealthCheckConfig(newCfg)
 		if s.server != nil {
 			s.server.UpdateClients(newCfg)
 		}
@@ -556,6 +650,7 @@ func (s *Service) Run(ctx context.Context) error {
 			s.coreManager.SetOAuthModelMappings(newCfg.OAuthModelMappings)
 		}
 		s.rebindExecutors()
+		s.applyModelDiscoveryConfig(newCfg)
 	}
 
 	watcherWrapper, err = s.watcherFactory(s.configPath, s.cfg.AuthDir, reloadCallback)
@@ -582,6 +677,8 @@ func (s *Service) Run(ctx context.Context) error {
 		s.coreManager.StartAutoRefresh(context.Background(), interval)
 		log.Infof("core auth auto-refresh started (interval=%s)", interval)
 	}
+	s.applyHealthCheckConfig(s.cfg)
+	s.applyModelDiscoveryConfig(s.cfg)
 
 	select {
 	case <-ctx.Done():
@@ -618,7 +715,9 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			s.coreManager.StopHealthCheck()
 		}
+		s.stopModelDiscovery()
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
 				log.Errorf("failed to stop file watcher: %v", err)
@@ -652,6 +751,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 
 		usage.StopDefault()
+		internalusage.ClosePersistentStore()
 	})
 	return shutdownErr
 }
@@ -827,6 +927,9 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 							DisplayName: modelID,
 						})
 					}
+					if compat.ModelDiscovery.Enabled {
+						ms = appendDiscoveredModels(ms, compat, s.discoveredModels(compat.Name))
+					}
 					// Register and return
 					if len(ms) > 0 {
 						if providerKey == "" {
@@ -18,7 +18,94 @@ type Record struct {
 	Source      string
 	RequestedAt time.Time
 	Failed      bool
-	Detail      Detail
+	// Cache is "hit" when the response cache served the request, "miss" when a cacheable
+	// request went upstream, and empty when the cache was not consulted.
+	Cache  string
+	Detail Detail
+}
+
+// Cache outcomes reported in Record.Cache.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+type cacheStatusKey struct{}
+
+// WithCacheStatus marks ctx so records published for the request carry the cache outcome.
+func WithCacheStatus(ctx context.Context, status string) context.Context {
+	return context.WithValue(ctx, cacheStatusKey{}, status)
+}
+
+// CacheStatusFromContext returns the cache outcome recorded by WithCacheStatus.
+func CacheStatusFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	status, _ := ctx.Value(cacheStatusKey{}).(string)
+	return status
+}
+
+// requestRecordsKey is the request-scoped store key (typically on a *gin.Context) under which
+// Publish collects the records of a request.
+const requestRecordsKey = "__usage_records__"
+
+// requestRecords collects the records published while serving one request.
+type requestRecords struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// TrackRequestRecords starts collecting the records published for the request behind store.
+// Publish appends to the collection when the request's context carries store as "gin".
+// Tracking an already tracked request keeps the existing collection.
+func TrackRequestRecords(store interface {
+	Get(string) (any, bool)
+	Set(string, any)
+}) {
+	if store == nil {
+		return
+	}
+	if _, ok := store.Get(requestRecordsKey); !ok {
+		store.Set(requestRecordsKey, &requestRecords{})
+	}
+}
+
+// RequestRecords returns the records collected since TrackRequestRecords.
+func RequestRecords(store interface{ Get(string) (any, bool) }) []Record {
+	if store == nil {
+		return nil
+	}
+	value, ok := store.Get(requestRecordsKey)
+	if !ok {
+		return nil
+	}
+	collected, ok := value.(*requestRecords)
+	if !ok || collected == nil {
+		return nil
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	return append([]Record(nil), collected.records...)
+}
+
+func collectRequestRecord(ctx context.Context, record Record) {
+	if ctx == nil {
+		return
+	}
+	store, ok := ctx.Value("gin").(interface{ Get(string) (any, bool) })
+	if !ok || store == nil {
+		return
+	}
+	value, ok := store.Get(requestRecordsKey)
+	if !ok {
+		return
+	}
+	if collected, ok := value.(*requestRecords); ok && collected != nil {
+		collected.mu.Lock()
+		collected.records = append(collected.records, record)
+		collected.mu.Unlock()
+	}
 }
 
 // Detail holds the token usage breakdown.
@@ -45,6 +132,7 @@ type Manager struct {
 	once     sync.Once
 	stopOnce sync.Once
 	cancel   context.CancelFunc
+	done     chan struct{}
 
 	mu     sync.Mutex
 	cond   *sync.Cond
@@ -73,11 +161,15 @@ func (m *Manager) Start(ctx context.Context) {
 		}
 		var workerCtx context.Context
 		workerCtx, m.cancel = context.WithCancel(ctx)
-		go m.run(workerCtx)
+		done := make(chan struct{})
+		m.mu.Lock()
+		m.done = done
+		m.mu.Unlock()
+		go m.run(workerCtx, done)
 	})
 }
 
-// Stop stops the dispatcher and drains the queue.
+// Stop stops the dispatcher and waits until the queued records have been delivered.
 func (m *Manager) Stop() {
 	if m == nil {
 		return
@@ -88,8 +180,12 @@ func (m *Manager) Stop() {
 		}
 		m.mu.Lock()
 		m.closed = true
+		done := m.done
 		m.mu.Unlock()
 		m.cond.Broadcast()
+		if done != nil {
+			<-done
+		}
 	})
 }
 
@@ -109,6 +205,10 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 	if m == nil {
 		return
 	}
+	if record.Cache == "" {
+		record.Cache = CacheStatusFromContext(ctx)
+	}
+	collectRequestRecord(ctx, record)
 	// ensure worker is running even if Start was not called explicitly
 	m.Start(context.Background())
 	m.mu.Lock()
@@ -121,7 +221,8 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 	m.cond.Signal()
 }
 
-func (m *Manager) run(ctx context.Context) {
+func (m *Manager) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
 	for {
 		m.mu.Lock()
 		for !m.closed && len(m.queue) == 0 {
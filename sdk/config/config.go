@@ -9,6 +9,7 @@ import internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 type SDKConfig = internalconfig.SDKConfig
 type AccessConfig = internalconfig.AccessConfig
 type AccessProvider = internalconfig.AccessProvider
+type APIKeyPolicy = internalconfig.APIKeyPolicy
 
 type Config = internalconfig.Config
 
@@ -20,6 +21,9 @@ type ModelNameMapping = internalconfig.ModelNameMapping
 type PayloadConfig = internalconfig.PayloadConfig
 type PayloadRule = internalconfig.PayloadRule
 type PayloadModelRule = internalconfig.PayloadModelRule
+type UpstreamTimeouts = internalconfig.UpstreamTimeouts
+type UpstreamTimeoutConfig = internalconfig.UpstreamTimeoutConfig
+type FallbackTarget = internalconfig.FallbackTarget
 
 type GeminiKey = internalconfig.GeminiKey
 type CodexKey = internalconfig.CodexKey
@@ -29,6 +33,7 @@ type VertexCompatModel = internalconfig.VertexCompatModel
 type OpenAICompatibility = internalconfig.OpenAICompatibility
 type OpenAICompatibilityAPIKey = internalconfig.OpenAICompatibilityAPIKey
 type OpenAICompatibilityModel = internalconfig.OpenAICompatibilityModel
+type OpenAICompatibilityModelDiscovery = internalconfig.OpenAICompatibilityModelDiscovery
 
 type TLS = internalconfig.TLSConfig
 
@@ -3,8 +3,27 @@ package translator
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// SpanStarter opens a trace span under ctx. It must return a non-recording span when tracing is off.
+type SpanStarter func(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span)
+
+var spanStarter atomic.Pointer[SpanStarter]
+
+// SetSpanStarter installs the function used to open translation spans. The proxy installs its
+// tracing gate here; without one, translations are not traced.
+func SetSpanStarter(start SpanStarter) {
+	if start == nil {
+		spanStarter.Store(nil)
+		return
+	}
+	spanStarter.Store(&start)
+}
+
 // Registry manages translation functions across schemas.
 type Registry struct {
 	mu        sync.RWMutex
@@ -52,6 +71,13 @@ func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byt
 	return rawJSON
 }
 
+// TranslateRequestContext behaves like TranslateRequest and records a trace span under ctx.
+func (r *Registry) TranslateRequestContext(ctx context.Context, from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	_, span := startSpan(ctx, "Registry.TranslateRequest", from, to, model)
+	defer span.End()
+	return r.TranslateRequest(from, to, model, rawJSON, stream)
+}
+
 // HasResponseTransformer indicates whether a response translator exists.
 func (r *Registry) HasResponseTransformer(from, to Format) bool {
 	r.mu.RLock()
@@ -80,6 +106,8 @@ func (r *Registry) TranslateStream(ctx context.Context, from, to Format, model s
 
 // TranslateNonStream applies the registered non-stream response translator.
 func (r *Registry) TranslateNonStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) string {
+	_, span := startSpan(ctx, "Registry.TranslateNonStream", from, to, model)
+	defer span.End()
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -104,6 +132,23 @@ func (r *Registry) TranslateTokenCount(ctx context.Context, from, to Format, cou
 	return string(rawJSON)
 }
 
+// startSpan opens a translation span. Streaming chunks are not traced individually to keep
+// span volume proportional to requests.
+func startSpan(ctx context.Context, name string, from, to Format, model string) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := spanStarter.Load()
+	if start == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return (*start)(ctx, name,
+		attribute.String("cliproxy.translator.from", string(from)),
+		attribute.String("cliproxy.translator.to", string(to)),
+		attribute.String("cliproxy.model", model),
+	)
+}
+
 var defaultRegistry = NewRegistry()
 
 // Default exposes the package-level registry for shared use.
@@ -121,6 +166,11 @@ func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
 }
 
+// TranslateRequestContext is a helper on the default registry that records a trace span.
+func TranslateRequestContext(ctx context.Context, from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	return defaultRegistry.TranslateRequestContext(ctx, from, to, model, rawJSON, stream)
+}
+
 // HasResponseTransformer inspects the default registry.
 func HasResponseTransformer(from, to Format) bool {
 	return defaultRegistry.HasResponseTransformer(from, to)